@@ -0,0 +1,277 @@
+package be_indexer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// maxAttributeSize protect from allocating huge memory when loading corrupted data
+const maxAttributeSize = 1 << 20
+
+const (
+	attrKindInt    = byte(0)
+	attrKindString = byte(1)
+)
+
+type (
+	// DocAttrs small set of document attributes(eg: campaign/advertiser), value must be integer or string,
+	// integers are normalized into int64 when stored
+	DocAttrs map[string]interface{}
+
+	// AttributeStore document attributes side-store of index, built together with index
+	// and read only after index built, so it's safe for concurrent reading
+	AttributeStore struct {
+		attrs map[DocID]DocAttrs
+	}
+)
+
+func NewAttributeStore() *AttributeStore {
+	return &AttributeStore{
+		attrs: map[DocID]DocAttrs{},
+	}
+}
+
+func normalizeAttrValue(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case string:
+		return value, nil
+	case int:
+		return int64(value), nil
+	case int8:
+		return int64(value), nil
+	case int16:
+		return int64(value), nil
+	case int32:
+		return int64(value), nil
+	case int64:
+		return value, nil
+	case uint8:
+		return int64(value), nil
+	case uint16:
+		return int64(value), nil
+	case uint32:
+		return int64(value), nil
+	case uint:
+		if uint64(value) <= math.MaxInt64 {
+			return int64(value), nil
+		}
+	case uint64:
+		if value <= math.MaxInt64 {
+			return int64(value), nil
+		}
+	case float64: // numbers decoded from json
+		if value == math.Trunc(value) && math.Abs(value) <= 1<<53 {
+			return int64(value), nil
+		}
+	}
+	return nil, fmt.Errorf("attribute value:%v type:%T not supported, need integer or string", v, v)
+}
+
+func normalizeDocAttrs(id DocID, attrs DocAttrs) (DocAttrs, error) {
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	normalized := make(DocAttrs, len(attrs))
+	for key, v := range attrs {
+		value, err := normalizeAttrValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("doc:%d attribute:%s invalid, err:%v", id, key, err)
+		}
+		normalized[key] = value
+	}
+	return normalized, nil
+}
+
+// Set replace attributes of document, empty attrs remove the document from store
+func (s *AttributeStore) Set(id DocID, attrs DocAttrs) error {
+	normalized, err := normalizeDocAttrs(id, attrs)
+	if err != nil {
+		return err
+	}
+	s.put(id, normalized)
+	return nil
+}
+
+func (s *AttributeStore) put(id DocID, attrs DocAttrs) {
+	if len(attrs) == 0 {
+		delete(s.attrs, id)
+		return
+	}
+	s.attrs[id] = attrs
+}
+
+// Get return attributes of document, nil if not exist; the result should not be modified
+func (s *AttributeStore) Get(id DocID) DocAttrs {
+	if s == nil {
+		return nil
+	}
+	return s.attrs[id]
+}
+
+// Value return attribute value(int64 or string) of document
+func (s *AttributeStore) Value(id DocID, key string) (interface{}, bool) {
+	v, ok := s.Get(id)[key]
+	return v, ok
+}
+
+func (s *AttributeStore) Int(id DocID, key string) (int64, bool) {
+	v, ok := s.Get(id)[key].(int64)
+	return v, ok
+}
+
+func (s *AttributeStore) String(id DocID, key string) (string, bool) {
+	v, ok := s.Get(id)[key].(string)
+	return v, ok
+}
+
+// Len count of documents has attributes
+func (s *AttributeStore) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.attrs)
+}
+
+// WriteTo serialize attributes(little endian): | docCnt | docID | attrCnt | keyLen | key | kind | value |...,
+// value is int64 or | strLen | str |; documents and keys are written in ascending order
+func (s *AttributeStore) WriteTo(w io.Writer) (int64, error) {
+	ids := make(DocIDList, 0, s.Len())
+	if s != nil {
+		for id := range s.attrs {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(ids)
+
+	buf := appendUint32(nil, uint32(len(ids)))
+	n, err := w.Write(buf)
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+	for _, id := range ids {
+		attrs := s.attrs[id]
+		keys := make([]string, 0, len(attrs))
+		for key := range attrs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf = appendUint64(buf[:0], uint64(id))
+		buf = appendUint32(buf, uint32(len(keys)))
+		for _, key := range keys {
+			buf = appendString(buf, key)
+			switch value := attrs[key].(type) {
+			case int64:
+				buf = appendUint64(append(buf, attrKindInt), uint64(value))
+			case string:
+				buf = appendString(append(buf, attrKindString), value)
+			}
+		}
+		n, err = w.Write(buf)
+		if total += int64(n); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom load attributes serialized by WriteTo, existing attributes are kept unless replaced
+func (s *AttributeStore) ReadFrom(r io.Reader) (int64, error) {
+	ar := &attrReader{r: r}
+	cnt := ar.uint32()
+	for i := uint32(0); i < cnt && ar.err == nil; i++ {
+		id := DocID(ar.uint64())
+		attrCnt := ar.uint32()
+		if ar.err == nil && (!ValidDocID(id) || attrCnt > maxAttributeSize) {
+			return ar.total, fmt.Errorf("bad serialized attributes, doc:%d count:%d", id, attrCnt)
+		}
+		attrs := make(DocAttrs, attrCnt)
+		for j := uint32(0); j < attrCnt && ar.err == nil; j++ {
+			key := ar.string()
+			switch kind := ar.byte(); kind {
+			case attrKindInt:
+				attrs[key] = int64(ar.uint64())
+			case attrKindString:
+				attrs[key] = ar.string()
+			default:
+				ar.fail(fmt.Errorf("bad serialized attributes, doc:%d key:%s kind:%d", id, key, kind))
+			}
+		}
+		if ar.err == nil {
+			s.put(id, attrs)
+		}
+	}
+	return ar.total, ar.err
+}
+
+// attrReader read fields of serialized attributes, the first error is kept and later reading skipped
+type attrReader struct {
+	r     io.Reader
+	buf   [8]byte
+	total int64
+	err   error
+}
+
+func (ar *attrReader) fail(err error) {
+	if ar.err == nil {
+		ar.err = err
+	}
+}
+
+func (ar *attrReader) read(b []byte) []byte {
+	if ar.err != nil {
+		return nil
+	}
+	n, err := io.ReadFull(ar.r, b)
+	ar.total += int64(n)
+	ar.fail(err)
+	return b
+}
+
+func (ar *attrReader) byte() byte {
+	if b := ar.read(ar.buf[:1]); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (ar *attrReader) uint32() uint32 {
+	if b := ar.read(ar.buf[:4]); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (ar *attrReader) uint64() uint64 {
+	if b := ar.read(ar.buf[:8]); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (ar *attrReader) string() string {
+	size := ar.uint32()
+	if size > maxAttributeSize {
+		ar.fail(fmt.Errorf("bad serialized attributes, string size:%d", size))
+	}
+	if ar.err != nil {
+		return ""
+	}
+	return string(ar.read(make([]byte, size)))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUint32(b, uint32(len(s))), s...)
+}
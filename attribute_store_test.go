@@ -0,0 +1,86 @@
+package be_indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestAttributeStore(t *testing.T) {
+	convey.Convey("test attributes normalized", t, func() {
+		store := NewAttributeStore()
+		convey.So(store.Set(1, DocAttrs{"campaign": int32(12), "advertiser": "adv1", "budget": uint8(3)}), convey.ShouldBeNil)
+
+		v, ok := store.Int(1, "campaign")
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(v, convey.ShouldEqual, 12)
+		s, ok := store.String(1, "advertiser")
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(s, convey.ShouldEqual, "adv1")
+		_, ok = store.String(1, "campaign")
+		convey.So(ok, convey.ShouldBeFalse)
+		_, ok = store.Value(2, "campaign")
+		convey.So(ok, convey.ShouldBeFalse)
+
+		convey.So(store.Set(2, DocAttrs{"ratio": 0.5}), convey.ShouldNotBeNil)
+		convey.So(store.Set(2, DocAttrs{"tags": []string{"a"}}), convey.ShouldNotBeNil)
+		convey.So(store.Len(), convey.ShouldEqual, 1)
+
+		convey.So(store.Set(1, nil), convey.ShouldBeNil)
+		convey.So(store.Len(), convey.ShouldEqual, 0)
+
+		var nilStore *AttributeStore
+		convey.So(nilStore.Get(1), convey.ShouldBeNil)
+	})
+
+	convey.Convey("test attributes serialization", t, func() {
+		store := NewAttributeStore()
+		convey.So(store.Set(1, DocAttrs{"campaign": -12, "advertiser": "adv1"}), convey.ShouldBeNil)
+		convey.So(store.Set(3, DocAttrs{"budget": uint32(3)}), convey.ShouldBeNil)
+
+		buf := &bytes.Buffer{}
+		n, err := store.WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, buf.Len())
+
+		data := buf.Bytes()
+		loaded := NewAttributeStore()
+		n, err = loaded.ReadFrom(bytes.NewReader(data))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, len(data))
+		convey.So(loaded.attrs, convey.ShouldResemble, store.attrs)
+
+		_, err = NewAttributeStore().ReadFrom(bytes.NewReader(data[:len(data)-1]))
+		convey.So(err, convey.ShouldNotBeNil)
+
+		var nilStore *AttributeStore
+		buf.Reset()
+		_, err = nilStore.WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		_, err = loaded.ReadFrom(buf)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(loaded.Len(), convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("test attributes supplied by document", t, func() {
+		doc := &Document{}
+		err := json.Unmarshal([]byte(`{"id":1,"cons":[{"exprs":{"age":[{"value":[1],"inc":true}]}}],"attrs":{"campaign":12,"advertiser":"adv1"}}`), doc)
+		convey.So(err, convey.ShouldBeNil)
+
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+			bad := NewDocument(2).AddConjunction(NewConjunction().In("age", []int64{2}))
+			bad.Attrs = DocAttrs{"ratio": 0.5}
+			convey.So(builder.AddDocument(bad), convey.ShouldNotBeNil)
+
+			index := builder.BuildIndex()
+			v, ok := index.(AttributeIndex).Attributes().Int(1, "campaign")
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(v, convey.ShouldEqual, 12)
+			convey.So(index.(AttributeIndex).Attributes().Get(1)["advertiser"], convey.ShouldEqual, "adv1")
+			convey.So(index.(AttributeIndex).Attributes().Len(), convey.ShouldEqual, 1)
+		}
+	})
+}
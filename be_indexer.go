@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
 )
 
 const (
@@ -23,29 +25,64 @@ type (
 		FieldConfig map[BEField]FieldOption
 	}
 
+	// BEIndex retrieving interface of a built index, implemented by indexes of this package and
+	// indexes built by their own builder(eg: roaringidx adapter); optional abilities are exposed by
+	// IterableIndex/AttributeIndex/PayloadIndex, check them by type assertion
 	BEIndex interface {
+		// Retrieve scan index data and retrieve satisfied document
+		Retrieve(queries Assignments, opt ...IndexOpt) (DocIDList, error)
+
+		// RetrieveWithCollector scan index data and retrieve satisfied document
+		RetrieveWithCollector(Assignments, ResultCollector, ...IndexOpt) error
+
+		// DumpEntries debug api
+		DumpEntries(sb *strings.Builder)
+
+		DumpIndexInfo(sb *strings.Builder)
+	}
+
+	// IterableIndex optional interface of BEIndex
+	IterableIndex interface {
+		// RetrieveIter return an iterator yield matched conjunctions lazily, see DocIterator
+		RetrieveIter(queries Assignments, opts ...IndexOpt) (DocIterator, error)
+	}
+
+	// AttributeIndex optional interface of BEIndex
+	AttributeIndex interface {
+		// Attributes document attributes side-store supplied by Document.Attrs
+		Attributes() *AttributeStore
+	}
+
+	// PayloadIndex optional interface of BEIndex
+	PayloadIndex interface {
+		// Payloads document payloads supplied by Document.Payload
+		Payloads() *PayloadStore
+	}
+
+	// buildableIndex index built by IndexerBuilder
+	buildableIndex interface {
+		BEIndex
+		IterableIndex
+		AttributeIndex
+		PayloadIndex
+
 		// addWildcardEID interface used by builder
 		addWildcardEID(id EntryID)
 
 		// set fields desc/settings
 		setFieldDesc(fieldsData map[BEField]*FieldDesc)
 
+		// setAttributeStore set document attributes side-store
+		setAttributeStore(store *AttributeStore)
+
+		// setPayloadStore set document payloads store
+		setPayloadStore(store *PayloadStore)
+
 		// newContainer indexer need return a valid Container for k size
 		newContainer(k int) *EntriesContainer
 
 		// compileIndexer prepare indexer and optimize index data
 		compileIndexer() error
-
-		// Retrieve scan index data and retrieve satisfied document
-		Retrieve(queries Assignments, opt ...IndexOpt) (DocIDList, error)
-
-		// RetrieveWithCollector scan index data and retrieve satisfied document
-		RetrieveWithCollector(Assignments, ResultCollector, ...IndexOpt) error
-
-		// DumpEntries debug api
-		DumpEntries(sb *strings.Builder)
-
-		DumpIndexInfo(sb *strings.Builder)
 	}
 
 	FieldDesc struct {
@@ -62,6 +99,13 @@ type (
 
 		// wildcardEntries hold all entry id that conjunction size is zero;
 		wildcardEntries Entries
+
+		attributes *AttributeStore
+
+		payloads *PayloadStore
+
+		// logger injected logger, global Logger used when nil
+		logger StructuredLogger
 	}
 )
 
@@ -69,6 +113,32 @@ func (bi *indexBase) setFieldDesc(fieldsData map[BEField]*FieldDesc) {
 	bi.fieldsData = fieldsData
 }
 
+func (bi *indexBase) setAttributeStore(store *AttributeStore) {
+	bi.attributes = store
+}
+
+func (bi *indexBase) Attributes() *AttributeStore {
+	return bi.attributes
+}
+
+func (bi *indexBase) setPayloadStore(store *PayloadStore) {
+	bi.payloads = store
+}
+
+func (bi *indexBase) Payloads() *PayloadStore {
+	return bi.payloads
+}
+
+// SetLogger inject logger for this index, it should be set before retrieving;
+// nil means fallback to global Logger
+func (bi *indexBase) SetLogger(logger StructuredLogger) {
+	bi.logger = logger
+}
+
+func (bi *indexBase) log() StructuredLogger {
+	return LoggerOrDefault(bi.logger)
+}
+
 // addWildcardEID append wildcard entry id to Z set
 func (bi *indexBase) addWildcardEID(id EntryID) {
 	bi.wildcardEntries = append(bi.wildcardEntries, id)
@@ -101,10 +171,33 @@ type (
 
 		collector ResultCollector
 
+		// stopper not nil when collector is a StoppableCollector
+		stopper StoppableCollector
+
 		assigns Assignments
+
+		// allowDocs/denyDocs restrict candidate documents, applied when cursors advancing
+		allowDocs *roaring64.Bitmap
+		denyDocs  *roaring64.Bitmap
+
+		trace *RetrieveTrace
 	}
 
 	IndexOpt func(ctx *retrieveContext)
+
+	// RetrieveOptions exported view of IndexOpt for BEIndex implementations outside this package
+	RetrieveOptions struct {
+		StepDetail bool
+
+		DumpEntries bool
+
+		Collector ResultCollector
+
+		AllowDocs *roaring64.Bitmap
+		DenyDocs  *roaring64.Bitmap
+
+		Trace *RetrieveTrace
+	}
 )
 
 func WithStepDetail() IndexOpt {
@@ -119,6 +212,20 @@ func WithDumpEntries() IndexOpt {
 	}
 }
 
+// WithAllowDocs only documents in allow bitmap can be retrieved, negative DocID stored as uint64(id)
+func WithAllowDocs(docs *roaring64.Bitmap) IndexOpt {
+	return func(ctx *retrieveContext) {
+		ctx.allowDocs = docs
+	}
+}
+
+// WithDenyDocs documents in deny bitmap will be skipped, eg: frequency-capped/budget-exhausted ads
+func WithDenyDocs(docs *roaring64.Bitmap) IndexOpt {
+	return func(ctx *retrieveContext) {
+		ctx.denyDocs = docs
+	}
+}
+
 // WithCollector specify a user defined collector
 func WithCollector(fn ResultCollector) IndexOpt {
 	return func(ctx *retrieveContext) {
@@ -126,6 +233,56 @@ func WithCollector(fn ResultCollector) IndexOpt {
 	}
 }
 
+func (ctx *retrieveContext) setCollector(collector ResultCollector) {
+	ctx.collector = collector
+	ctx.stopper, _ = collector.(StoppableCollector)
+}
+
+// done whether collector has enough results, retrieving can end up
+func (ctx *retrieveContext) done() bool {
+	return ctx.stopper != nil && ctx.stopper.Done()
+}
+
+// collect feed matched conjunction into collector
+func (ctx *retrieveContext) collect(conjID ConjID) {
+	ctx.collector.Add(conjID.DocID(), conjID)
+	if ctx.trace != nil {
+		ctx.trace.Matched = append(ctx.trace.Matched, conjID)
+	}
+}
+
+func (ctx *retrieveContext) skipTo(fc *FieldCursor, id EntryID) {
+	if ctx.trace != nil {
+		ctx.trace.SkipToCalls++
+	}
+	fc.SkipTo(id)
+}
+
+func (ctx *retrieveContext) docAllowed(id DocID) bool {
+	if ctx.allowDocs != nil && !ctx.allowDocs.Contains(uint64(id)) {
+		return false
+	}
+	return ctx.denyDocs == nil || !ctx.denyDocs.Contains(uint64(id))
+}
+
+// skipFilteredDocs advance cursors until current entry belongs to an allowed document,
+// entries of a filtered conjunction are skipped as if they were not indexed at all
+func (ctx *retrieveContext) skipFilteredDocs(cursors ...FieldCursor) {
+	if ctx.allowDocs == nil && ctx.denyDocs == nil {
+		return
+	}
+	for i := range cursors {
+		fc := &cursors[i]
+		for eid := fc.GetCurEntryID(); !eid.IsNULLEntry(); eid = fc.GetCurEntryID() {
+			conjID := eid.GetConjID()
+			if ctx.docAllowed(conjID.DocID()) {
+				break
+			}
+			ctx.skipTo(fc, NewEntryID(conjID, true)+1)
+		}
+	}
+}
+
 func newRetrieveCtx(ass Assignments, opts ...IndexOpt) retrieveContext {
 	ctx := retrieveContext{}
 	ctx.assigns = ass
@@ -135,6 +292,25 @@ func newRetrieveCtx(ass Assignments, opts ...IndexOpt) retrieveContext {
 	return ctx
 }
 
+// NewRetrieveOptions apply opts and return the settings, used by external BEIndex implementations
+func NewRetrieveOptions(opts ...IndexOpt) RetrieveOptions {
+	ctx := newRetrieveCtx(nil, opts...)
+	return RetrieveOptions{
+		StepDetail:  ctx.dumpStepInfo,
+		DumpEntries: ctx.dumpEntriesDetail,
+		Collector:   ctx.collector,
+		AllowDocs:   ctx.allowDocs,
+		DenyDocs:    ctx.denyDocs,
+		Trace:       ctx.trace,
+	}
+}
+
+// DocAllowed whether document pass WithAllowDocs/WithDenyDocs filter
+func (opt *RetrieveOptions) DocAllowed(id DocID) bool {
+	ctx := retrieveContext{allowDocs: opt.AllowDocs, denyDocs: opt.DenyDocs}
+	return ctx.docAllowed(id)
+}
+
 func PrintIndexInfo(index BEIndex) {
 	if index == nil {
 		fmt.Println("nil indexer")
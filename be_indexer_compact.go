@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/echoface/be_indexer/util"
 )
@@ -34,7 +35,7 @@ func (bi *CompactBEIndex) compileIndexer() error {
 	if bi.wildcardEntries.Len() > 0 {
 		sort.Sort(bi.wildcardEntries)
 	}
-	return bi.container.compileEntries()
+	return bi.container.compileEntries(bi.logger)
 }
 
 func (bi *CompactBEIndex) initCursors(ctx *retrieveContext) (fCursors FieldCursors, err error) {
@@ -44,6 +45,9 @@ func (bi *CompactBEIndex) initCursors(ctx *retrieveContext) (fCursors FieldCurso
 	if len(bi.wildcardEntries) > 0 {
 		pl := NewEntriesCursor(wildcardQKey, bi.wildcardEntries)
 		fCursors = append(fCursors, NewFieldCursor(pl))
+		if ctx.trace != nil {
+			ctx.trace.addPostingLists(CompactTraceK, EntriesCursors{pl})
+		}
 	}
 
 	var ok bool
@@ -63,6 +67,9 @@ func (bi *CompactBEIndex) initCursors(ctx *retrieveContext) (fCursors FieldCurso
 		if entriesList, err = holder.GetEntries(desc, values); err != nil {
 			return nil, err
 		}
+		if ctx.trace != nil {
+			ctx.trace.addPostingLists(CompactTraceK, entriesList)
+		}
 		if len(entriesList) > 0 {
 			fCursors = append(fCursors, NewFieldCursor(entriesList...))
 		}
@@ -90,16 +97,29 @@ func (bi *CompactBEIndex) RetrieveWithCollector(
 	ctx := newRetrieveCtx(queries, opts...)
 	util.PanicIf(ctx.collector != nil, "can't specify collector twice")
 
-	ctx.collector = collector
+	ctx.setCollector(collector)
+	var tracing groupTracing
+	if ctx.trace != nil {
+		ctx.trace.reset()
+		defer ctx.trace.finish(time.Now())
+		tracing = ctx.trace.beginGroup(CompactTraceK)
+	}
 	var fieldCursors FieldCursors
 	if fieldCursors, err = bi.initCursors(&ctx); err != nil {
 		return err
 	}
+	if ctx.trace != nil {
+		defer ctx.trace.endGroup(tracing, len(fieldCursors))
+	}
+	ctx.skipFilteredDocs(fieldCursors...)
+	if ctx.done() {
+		return nil
+	}
 
 	// sort.Sort(fieldCursors)
 	fieldCursors.Sort()
 	if ctx.dumpEntriesDetail {
-		Logger.Infof("RetrieveWithCollector initial entries:\n%s", fieldCursors.Dump())
+		bi.log().Info("initial entries", "entries", "\n"+fieldCursors.Dump())
 	}
 
 RETRIEVE:
@@ -115,55 +135,39 @@ RETRIEVE:
 		stepK := conjID.Size()
 		needMatchCnt := util.MaxInt(1, stepK)
 		if needMatchCnt > len(fieldCursors) {
-			LogInfoIf(ctx.dumpStepInfo, "end retrieve@stepK:%d, need match:%d but only:%d cursors", stepK, needMatchCnt, len(fieldCursors))
+			if ctx.dumpStepInfo {
+				bi.log().Info("cursors not enough, end retrieve", "k", stepK, "needMatch", needMatchCnt, "cursors", len(fieldCursors))
+			}
 			break RETRIEVE
 		}
 
-		// needMatchCnt <= plgsCount check whether eid fieldCursors[needMatchCnt-1].GetCurEntryID equal
-		endEID := fieldCursors[needMatchCnt-1].GetCurEntryID()
-
-		nextID := NewEntryID(endEID.GetConjID(), false)
-		// nextID := endEID
-
 		if ctx.dumpEntriesDetail {
-			Logger.Infof("step:%d round start, docs:%v entries:\n%s", stepK, collector.GetDocIDs(), fieldCursors.Dump())
+			bi.log().Info("round start", "k", stepK, "docs", collector.GetDocIDs(), "entries", "\n"+fieldCursors.Dump())
 		}
 		if ctx.dumpStepInfo {
-			LogInfo("step:%d process need match:%d cursors:%d, eid:[%s..%s]", stepK, needMatchCnt, len(fieldCursors), eid.DocString(), endEID.DocString())
+			// needMatchCnt <= plgsCount check whether eid fieldCursors[needMatchCnt-1].GetCurEntryID equal
+			endEID := fieldCursors[needMatchCnt-1].GetCurEntryID()
+			bi.log().Info("round process", "k", stepK, "needMatch", needMatchCnt, "cursors", len(fieldCursors),
+				"eid", eid.DocString(), "endEID", endEID.DocString())
 		}
 
-		if endEID.GetConjID() == conjID {
-
-			nextID = NewEntryID(endEID.GetConjID(), true) + 1
-
-			if eid.IsInclude() {
-
-				ctx.collector.Add(conjID.DocID(), conjID)
-
-			} else { //exclude
-
-				for i := needMatchCnt; i < len(fieldCursors); i++ {
-					if fieldCursors[i].GetCurEntryID() < nextID {
-						fieldCursors[i].SkipTo(nextID)
-					}
+		if conjID, matched := matchRound(&ctx, fieldCursors, needMatchCnt); matched {
+			ctx.collect(conjID)
+			if ctx.done() {
+				if ctx.dumpStepInfo {
+					bi.log().Info("collector done, end retrieve", "docID", conjID.DocID(), "conjID", conjID.String())
 				}
+				break RETRIEVE
 			}
 		}
 
-		for i := 0; i < needMatchCnt; i++ { // 推进游标
-			fieldCursors[i].SkipTo(nextID)
-		}
-
-		fieldCursors.Sort()
-		// sort.Sort(fieldCursors) // slow 12% compare to fieldCursors.Sort()
-
 		// remove those entries that have already reached end;
 		// the end-up cursor will in the end of slice after sorting
 		for len(fieldCursors) > 0 && fieldCursors[len(fieldCursors)-1].ReachEnd() {
 			fieldCursors = fieldCursors[:len(fieldCursors)-1]
 		}
 		if ctx.dumpStepInfo {
-			Logger.Infof("step:%d round end, result docs:%+v", stepK, collector.GetDocIDs())
+			bi.log().Info("round end", "k", stepK, "docs", collector.GetDocIDs())
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/echoface/be_indexer/util"
 )
@@ -30,12 +31,15 @@ func newFieldEntriesContainer() *EntriesContainer {
 	}
 }
 
-func (c *EntriesContainer) compileEntries() (err error) {
+// compileEntries inject logger into holders then compile them
+func (c *EntriesContainer) compileEntries(logger StructuredLogger) (err error) {
+	setHolderLogger(c.defaultHolder, logger)
 	if err = c.defaultHolder.CompileEntries(); err != nil {
 		return err
 	}
 
 	for _, holder := range c.fieldHolder {
+		setHolderLogger(holder, logger)
 		if err = holder.CompileEntries(); err != nil {
 			return err
 		}
@@ -102,6 +106,10 @@ func (c *EntriesContainer) DumpInfo(buf *strings.Builder) {
 }
 
 func NewKGroupsBEIndex() BEIndex {
+	return newKGroupsBEIndex()
+}
+
+func newKGroupsBEIndex() *KGroupsBEIndex {
 	index := &KGroupsBEIndex{
 		indexBase: indexBase{
 			fieldsData: make(map[BEField]*FieldDesc),
@@ -126,7 +134,7 @@ func (bi *KGroupsBEIndex) newContainer(k int) *EntriesContainer {
 
 func (bi *KGroupsBEIndex) compileIndexer() (err error) {
 	for _, sizeEntries := range bi.kSizeContainers {
-		if err = sizeEntries.compileEntries(); err != nil {
+		if err = sizeEntries.compileEntries(bi.logger); err != nil {
 			return err
 		}
 	}
@@ -150,6 +158,9 @@ func (bi *KGroupsBEIndex) initCursors(ctx *retrieveContext, k int) (fCursors Fie
 	if k == 0 && len(bi.wildcardEntries) > 0 {
 		pl := NewEntriesCursor(wildcardQKey, bi.wildcardEntries)
 		fCursors = append(fCursors, NewFieldCursor(pl))
+		if ctx.trace != nil {
+			ctx.trace.addPostingLists(k, EntriesCursors{pl})
+		}
 	}
 
 	kSizeContainer := bi.getKSizeEntries(k)
@@ -175,15 +186,18 @@ func (bi *KGroupsBEIndex) initCursors(ctx *retrieveContext, k int) (fCursors Fie
 		}
 
 		if entriesList, err = holder.GetEntries(desc, values); err != nil {
-			Logger.Errorf("fetch entries from holder fail:%s, field:%s\n", err.Error(), desc.Field)
+			bi.log().Error("fetch entries from holder fail", "field", desc.Field, "k", k, "err", err)
 			return nil, err
 		}
 
+		if ctx.trace != nil {
+			ctx.trace.addPostingLists(k, entriesList)
+		}
 		if len(entriesList) > 0 {
 			fCursors = append(fCursors, NewFieldCursor(entriesList...))
-			Logger.Debugf("<%s,%v> fetch %d posting list\n", desc.Field, values, len(entriesList))
+			bi.log().Debug("fetch posting list", "field", desc.Field, "k", k, "values", values, "count", len(entriesList))
 		} else {
-			Logger.Debugf("<%s,%v> nothing matched from entries holder\n", desc.Field, values)
+			bi.log().Debug("nothing matched from entries holder", "field", desc.Field, "k", k, "values", values)
 		}
 	}
 	return fCursors, nil
@@ -192,7 +206,9 @@ func (bi *KGroupsBEIndex) initCursors(ctx *retrieveContext, k int) (fCursors Fie
 // retrieveK retrieve matched result from k size index data
 func (bi *KGroupsBEIndex) retrieveK(ctx *retrieveContext, fieldCursors FieldCursors, needMatchCnt int) {
 	if len(fieldCursors) < needMatchCnt {
-		LogInfoIf(ctx.dumpStepInfo, "need match:%d but only:%d", needMatchCnt, len(fieldCursors))
+		if ctx.dumpStepInfo {
+			bi.log().Info("cursors not enough", "needMatch", needMatchCnt, "cursors", len(fieldCursors))
+		}
 		return
 	}
 	// sort.Sort(fieldCursors)
@@ -200,45 +216,23 @@ func (bi *KGroupsBEIndex) retrieveK(ctx *retrieveContext, fieldCursors FieldCurs
 
 	for !fieldCursors[needMatchCnt-1].GetCurEntryID().IsNULLEntry() {
 		if ctx.dumpStepInfo {
-			Logger.Infof("round need match:%d continue docs:%v", needMatchCnt, ctx.collector.GetDocIDs())
+			bi.log().Info("round start", "needMatch", needMatchCnt, "docs", ctx.collector.GetDocIDs())
 		}
 		if ctx.dumpEntriesDetail {
-			Logger.Infof("round need match:%d continue entries\n%s", needMatchCnt, fieldCursors.Dump())
+			bi.log().Info("round start", "needMatch", needMatchCnt, "entries", "\n"+fieldCursors.Dump())
 		}
 
-		eid := fieldCursors[0].GetCurEntryID()
-		endEID := fieldCursors[needMatchCnt-1].GetCurEntryID()
-
-		conjID := eid.GetConjID()
-		endConjID := endEID.GetConjID()
-
-		nextID := NewEntryID(endConjID, false) // 逻辑按照conjID执行，但是直接使用endEID可能跳过排除逻辑的EID
-
-		if conjID == endConjID {
-
-			// nextID = endEID + 1
-			nextID = NewEntryID(endEID.GetConjID(), true) + 1
-
-			if eid.IsInclude() {
-
-				ctx.collector.Add(conjID.DocID(), conjID)
-
-			} else { //exclude
-				for i := needMatchCnt; i < len(fieldCursors); i++ {
-					if fieldCursors[i].GetCurEntryID() < nextID {
-						fieldCursors[i].SkipTo(nextID)
-					}
+		if conjID, matched := matchRound(ctx, fieldCursors, needMatchCnt); matched {
+			ctx.collect(conjID)
+			if ctx.done() {
+				if ctx.dumpStepInfo {
+					bi.log().Info("collector done, end retrieve", "docID", conjID.DocID(), "conjID", conjID.String())
 				}
+				return
 			}
 		}
-		for i := 0; i < needMatchCnt; i++ { // 推进游标
-			fieldCursors[i].SkipTo(nextID)
-		}
-
-		fieldCursors.Sort()
-		// sort.Sort(fieldCursors)
 		if ctx.dumpStepInfo {
-			Logger.Infof("round end need match:%d, docs:%v", needMatchCnt, ctx.collector.GetDocIDs())
+			bi.log().Info("round end", "needMatch", needMatchCnt, "docs", ctx.collector.GetDocIDs())
 		}
 	}
 }
@@ -265,20 +259,35 @@ func (bi *KGroupsBEIndex) RetrieveWithCollector(
 	ctx := newRetrieveCtx(queries, opts...)
 	util.PanicIf(ctx.collector != nil, "can't specify collector twice")
 
-	ctx.collector = collector
+	ctx.setCollector(collector)
+	if ctx.trace != nil {
+		ctx.trace.reset()
+		defer ctx.trace.finish(time.Now())
+	}
 
 	var fCursors FieldCursors
-	for k := util.MinInt(queries.Size(), bi.maxK()); k >= 0; k-- {
+	var tracing groupTracing
+	for k := util.MinInt(queries.Size(), bi.maxK()); k >= 0 && !ctx.done(); k-- {
+		if ctx.trace != nil {
+			tracing = ctx.trace.beginGroup(k)
+		}
 		if fCursors, err = bi.initCursors(&ctx, k); err != nil {
 			return err
 		}
-		LogInfoIf(ctx.dumpStepInfo, "start@step:%d cursors:%d", k, len(fCursors))
+		if ctx.dumpStepInfo {
+			bi.log().Info("start retrieve k group", "k", k, "cursors", len(fCursors))
+		}
 		if ctx.dumpEntriesDetail {
-			Logger.Infof("RetrieveWithCollector k:%d initial entries:\n%s", k, fCursors.Dump())
+			bi.log().Info("initial entries", "k", k, "entries", "\n"+fCursors.Dump())
 		}
 
+		ctx.skipFilteredDocs(fCursors...)
+
 		needMatchCnt := util.MaxInt(k, 1)
 		bi.retrieveK(&ctx, fCursors, needMatchCnt)
+		if ctx.trace != nil {
+			ctx.trace.endGroup(tracing, len(fCursors))
+		}
 	}
 	return nil
 }
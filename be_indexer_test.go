@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/echoface/be_indexer/parser"
 
 	"github.com/echoface/be_indexer/codegen/cache"
@@ -770,3 +771,112 @@ func TestBEIndexer_Retrieve_Geohash(t *testing.T) {
 		convey.So(results.Len(), convey.ShouldEqual, 0)
 	})
 }
+
+func TestBEIndex_RetrieveWithDocFilter(t *testing.T) {
+	convey.Convey("test allow/deny docs applied when retrieving", t, func() {
+		docs, queries := BuildTestDocumentAndQueries(1000, 50, true)
+
+		allow, deny := roaring64.New(), roaring64.New()
+		for id := range docs {
+			if id%3 != 0 {
+				allow.Add(uint64(id))
+			}
+			if id%5 == 0 {
+				deny.Add(uint64(id))
+			}
+		}
+
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			for _, doc := range docs {
+				convey.So(builder.AddDocument(doc.ToDocument()), convey.ShouldBeNil)
+			}
+			index := builder.BuildIndex()
+
+			for _, q := range queries {
+				all, err := index.Retrieve(q.ToAssigns())
+				convey.So(err, convey.ShouldBeNil)
+
+				var expect DocIDList
+				for _, id := range all {
+					if allow.Contains(uint64(id)) && !deny.Contains(uint64(id)) {
+						expect = append(expect, id)
+					}
+				}
+				result, err := index.Retrieve(q.ToAssigns(), WithAllowDocs(allow), WithDenyDocs(deny))
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(result, convey.ShouldResemble, expect)
+
+				result, err = index.Retrieve(q.ToAssigns(), WithDenyDocs(roaring64.BitmapOf(uint64(len(docs)+1))))
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(len(result), convey.ShouldEqual, len(all))
+			}
+		}
+	})
+}
+
+type orderedConjCollector struct {
+	DocIDCollector
+	conjs []ConjID
+}
+
+func (c *orderedConjCollector) Add(id DocID, conj ConjID) {
+	c.DocIDCollector.Add(id, conj)
+	c.conjs = append(c.conjs, conj)
+}
+
+func TestBEIndex_RetrieveIter(t *testing.T) {
+	convey.Convey("test iterator yield the same matches as collector in cursor order", t, func() {
+		docs, queries := BuildTestDocumentAndQueries(1000, 50, true)
+
+		deny := roaring64.New()
+		for id := range docs {
+			if id%4 == 0 {
+				deny.Add(uint64(id))
+			}
+		}
+
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			for _, doc := range docs {
+				convey.So(builder.AddDocument(doc.ToDocument()), convey.ShouldBeNil)
+			}
+			index := builder.BuildIndex()
+			_, compacted := index.(*CompactBEIndex)
+
+			for _, q := range queries {
+				for _, opts := range [][]IndexOpt{nil, {WithDenyDocs(deny)}} {
+					collector := &orderedConjCollector{DocIDCollector: *NewDocIDCollector()}
+					convey.So(index.RetrieveWithCollector(q.ToAssigns(), collector, opts...), convey.ShouldBeNil)
+
+					iter, err := index.(IterableIndex).RetrieveIter(q.ToAssigns(), opts...)
+					convey.So(err, convey.ShouldBeNil)
+
+					var conjs []ConjID
+					for id, conj, ok := iter.Next(); ok; id, conj, ok = iter.Next() {
+						convey.So(id, convey.ShouldEqual, conj.DocID())
+						if n := len(conjs); n > 0 {
+							if compacted {
+								convey.So(conj, convey.ShouldBeGreaterThan, conjs[n-1])
+							} else {
+								convey.So(conj.Size(), convey.ShouldBeLessThanOrEqualTo, conjs[n-1].Size())
+							}
+						}
+						conjs = append(conjs, conj)
+					}
+					iter.Close()
+					convey.So(conjs, convey.ShouldResemble, collector.conjs)
+				}
+			}
+
+			// close early, no more results
+			iter, err := index.(IterableIndex).RetrieveIter(Assignments{})
+			convey.So(err, convey.ShouldBeNil)
+			iter.Close()
+			_, _, ok := iter.Next()
+			convey.So(ok, convey.ShouldBeFalse)
+
+			convey.So(func() {
+				_, _ = index.(IterableIndex).RetrieveIter(Assignments{}, WithCollector(NewDocIDCollector()))
+			}, convey.ShouldPanic)
+		}
+	})
+}
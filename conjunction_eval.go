@@ -0,0 +1,126 @@
+package be_indexer
+
+import (
+	"fmt"
+)
+
+type (
+	// EvalOptions settings for evaluating a single Conjunction/Document without building index,
+	// FieldConfig should be the same as what configured into IndexerBuilder, fields not configured
+	// use the default holder, so tokenizer/parser and holder semantics are exactly the same as Retrieve
+	EvalOptions struct {
+		FieldConfig map[BEField]FieldOption
+
+		// Logger injected into holders and used for reporting evaluation error, global Logger used when nil
+		Logger StructuredLogger
+	}
+)
+
+// evalConjID a placeholder conjunction id used to index expressions of a single conjunction
+var evalConjID = NewConjID(0, 0, 0)
+
+func (opt *EvalOptions) fieldDesc(field BEField) *FieldDesc {
+	desc := &FieldDesc{Field: field}
+	if opt != nil {
+		desc.FieldOption = opt.FieldConfig[field]
+	}
+	if len(desc.Container) == 0 {
+		desc.Container = HolderNameDefault
+	}
+	return desc
+}
+
+func (opt *EvalOptions) logger() StructuredLogger {
+	if opt == nil {
+		return LoggerOrDefault(nil)
+	}
+	return LoggerOrDefault(opt.Logger)
+}
+
+// Evaluate check whether the conjunction satisfied by assignments; the expressions of each field
+// are indexed into a fresh holder created from registered holder builder then queried with the
+// assigned values, so result agree with Retrieve; any holder error treated as not matched
+func (conj *Conjunction) Evaluate(assigns Assignments, opt *EvalOptions) bool {
+	matched, err := conj.evaluate(assigns, opt)
+	if err != nil {
+		opt.logger().Error("evaluate conjunction fail", "conj", conj.String(), "err", err)
+		return false
+	}
+	return matched
+}
+
+func (conj *Conjunction) evaluate(assigns Assignments, opt *EvalOptions) (matched bool, err error) {
+	defer func() {
+		if r := recover(); r != nil { // holders panic on unsupported expression
+			matched, err = false, fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	incEID, excEID := NewEntryID(evalConjID, true), NewEntryID(evalConjID, false)
+	for field, exprs := range conj.Expressions {
+		desc := opt.fieldDesc(field)
+		holder := NewEntriesHolder(desc.Container)
+		if holder == nil {
+			return false, fmt.Errorf("field:%s container:%s not found, plz register it", field, desc.Container)
+		}
+		if opt != nil {
+			setHolderLogger(holder, opt.Logger)
+		}
+
+		hasIncl := false
+		for _, expr := range exprs {
+			var data IndexingData
+			if data, err = holder.BuildFieldIndexingData(desc, expr); err != nil {
+				return false, fmt.Errorf("indexing field:%s fail:%v", field, err)
+			}
+			eid := NewEntryID(evalConjID, expr.Incl)
+			tx := FieldIndexingData{field: desc, holder: holder, EID: eid, Data: data}
+			if err = holder.CommitFieldIndexingData(tx); err != nil {
+				return false, err
+			}
+			hasIncl = hasIncl || expr.Incl
+		}
+		if err = holder.CompileEntries(); err != nil {
+			return false, err
+		}
+
+		values, assigned := assigns[field]
+		if !assigned { // nothing can be matched, include expression can't be satisfied
+			if hasIncl {
+				return false, nil
+			}
+			continue
+		}
+
+		var cursors EntriesCursors
+		if cursors, err = holder.GetEntries(desc, values); err != nil {
+			return false, err
+		}
+		inclHit := false
+		for i := range cursors {
+			// exclude entry id always less than include one of same conjunction
+			if cursors[i].SkipTo(excEID) == excEID {
+				return false, nil
+			}
+			inclHit = inclHit || cursors[i].SkipTo(incEID) == incEID
+		}
+		if hasIncl && !inclHit {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Match return indexes of conjunctions satisfied by assignments, see Conjunction.Evaluate
+func (doc *Document) Match(assigns Assignments, opts ...*EvalOptions) (idx []int) {
+	var opt *EvalOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	for i, conj := range doc.Cons {
+		if conj.Evaluate(assigns, opt) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
@@ -0,0 +1,226 @@
+package consistency
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/util"
+)
+
+const (
+	// referenceName key of Counterexample.Errors when reference engine failed
+	referenceName = "reference"
+)
+
+type (
+	Options struct {
+		Fields []FieldSchema
+
+		Docs    int // count of documents generated for a round
+		Queries int // count of assignments checked for a round
+		Seed    int64
+
+		MaxConjunctions    int
+		MaxExprsPerConj    int
+		MaxValuesPerExpr   int
+		MaxValuesPerAssign int
+
+		// Engines index implementations under checking, default: kgroups/compact/roaringidx
+		Engines []Engine
+	}
+
+	// Counterexample a minimized documents/assignments which engines return different results
+	Counterexample struct {
+		Docs    []*be_indexer.Document
+		Assigns be_indexer.Assignments
+
+		Expect  be_indexer.DocIDList            // result of brute force evaluation
+		Results map[string]be_indexer.DocIDList // result of engines diverged from expect
+		Errors  map[string]error                // engines(or "reference") failed building or retrieving
+	}
+
+	// Checker differential consistency checker, it generates random documents and assignments
+	// from field schema and compare results of all engines against brute force evaluation on raw expression values
+	Checker struct {
+		Options
+
+		gen       *generator
+		reference Engine
+	}
+)
+
+func NewChecker(opt Options) *Checker {
+	util.PanicIf(len(opt.Fields) == 0, "at least one field schema required")
+	if opt.Docs <= 0 {
+		opt.Docs = 100
+	}
+	if opt.Queries <= 0 {
+		opt.Queries = 100
+	}
+	if opt.MaxConjunctions <= 0 {
+		opt.MaxConjunctions = 3
+	}
+	if opt.MaxExprsPerConj <= 0 {
+		opt.MaxExprsPerConj = 3
+	}
+	if opt.MaxValuesPerExpr <= 0 {
+		opt.MaxValuesPerExpr = 3
+	}
+	if opt.MaxValuesPerAssign <= 0 {
+		opt.MaxValuesPerAssign = 2
+	}
+	if len(opt.Engines) == 0 {
+		opt.Engines = []Engine{NewKGroupsEngine(), NewCompactEngine(), NewIvtEngine()}
+	}
+	for i := range opt.Fields {
+		util.PanicIf(opt.Fields[i].Cardinality <= 0, "field:%s need positive cardinality", opt.Fields[i].Name)
+	}
+	c := &Checker{
+		Options:   opt,
+		reference: NewBruteForceEngine(),
+	}
+	c.gen = &generator{Options: &c.Options, rand: rand.New(rand.NewSource(opt.Seed))}
+	return c
+}
+
+// Run check a round of random documents and assignments,
+// return a minimized counterexample when any engine diverged, nil means all engines consistent
+func (c *Checker) Run() *Counterexample {
+	docs := make([]*be_indexer.Document, 0, c.Docs)
+	for id := 1; id <= c.Docs; id++ {
+		docs = append(docs, c.gen.document(be_indexer.DocID(id)))
+	}
+	queries := make([]be_indexer.Assignments, 0, c.Queries)
+	for i := 0; i < c.Queries; i++ {
+		queries = append(queries, c.gen.assignments())
+	}
+
+	if d := c.diverged(docs, queries); len(d) > 0 {
+		return c.minimize(docs, d[0])
+	}
+	return nil
+}
+
+// diverged return the assignments that engines retrieve different results
+func (c *Checker) diverged(docs []*be_indexer.Document, queries []be_indexer.Assignments) (res []be_indexer.Assignments) {
+	ce := c.check(docs, queries)
+	for i, ok := range ce {
+		if ok != nil {
+			res = append(res, queries[i])
+		}
+	}
+	return res
+}
+
+// check build all engines with docs and return counterexample for each query(nil if consistent)
+func (c *Checker) check(docs []*be_indexer.Document, queries []be_indexer.Assignments) []*Counterexample {
+	res := make([]*Counterexample, len(queries))
+	newCounterexample := func(i int) *Counterexample {
+		if res[i] == nil {
+			res[i] = &Counterexample{
+				Docs:    docs,
+				Assigns: queries[i],
+				Results: map[string]be_indexer.DocIDList{},
+				Errors:  map[string]error{},
+			}
+		}
+		return res[i]
+	}
+
+	expects := make([]be_indexer.DocIDList, len(queries))
+	if err := c.buildEngine(c.reference, docs); err != nil {
+		for i := range queries {
+			newCounterexample(i).Errors[referenceName] = err
+		}
+		return res
+	}
+	for i, assigns := range queries {
+		var err error
+		if expects[i], err = c.retrieveEngine(c.reference, assigns); err != nil {
+			newCounterexample(i).Errors[referenceName] = err
+		}
+	}
+
+	for _, engine := range c.Engines {
+		if err := c.buildEngine(engine, docs); err != nil {
+			for i := range queries {
+				newCounterexample(i).Errors[engine.Name()] = err
+			}
+			continue
+		}
+		for i, assigns := range queries {
+			result, err := c.retrieveEngine(engine, assigns)
+			if err != nil {
+				newCounterexample(i).Errors[engine.Name()] = err
+			} else if !equalDocIDs(result, expects[i]) {
+				newCounterexample(i).Results[engine.Name()] = result
+			}
+		}
+	}
+	for i := range res {
+		if res[i] != nil {
+			res[i].Expect = expects[i]
+		}
+	}
+	return res
+}
+
+// buildEngine convert panic into error, some engines panic on bad input
+func (c *Checker) buildEngine(engine Engine, docs []*be_indexer.Document) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return engine.Build(c.Fields, docs)
+}
+
+func (c *Checker) retrieveEngine(engine Engine, assigns be_indexer.Assignments) (ids be_indexer.DocIDList, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return engine.Retrieve(assigns)
+}
+
+func equalDocIDs(a, b be_indexer.DocIDList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (ce *Counterexample) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString("documents:\n")
+	for _, doc := range ce.Docs {
+		sb.WriteString(doc.String())
+		sb.WriteString("\n")
+	}
+	fields := make([]string, 0, len(ce.Assigns))
+	for field := range ce.Assigns {
+		fields = append(fields, string(field))
+	}
+	sort.Strings(fields)
+	sb.WriteString("assignments:")
+	for _, field := range fields {
+		sb.WriteString(fmt.Sprintf(" %s:%v", field, ce.Assigns[be_indexer.BEField(field)]))
+	}
+	sb.WriteString(fmt.Sprintf("\nexpect: %v\n", ce.Expect))
+	for name, result := range ce.Results {
+		sb.WriteString(fmt.Sprintf("%s: %v\n", name, result))
+	}
+	for name, err := range ce.Errors {
+		sb.WriteString(fmt.Sprintf("%s: error %v\n", name, err))
+	}
+	return sb.String()
+}
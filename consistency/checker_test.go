@@ -0,0 +1,65 @@
+package consistency
+
+import (
+	"testing"
+
+	"github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// droppingEngine a buggy engine which never return document with id 3
+type droppingEngine struct {
+	Engine
+}
+
+func (e *droppingEngine) Name() string {
+	return "dropping"
+}
+
+func (e *droppingEngine) Retrieve(assigns be_indexer.Assignments) (be_indexer.DocIDList, error) {
+	ids, err := e.Engine.Retrieve(assigns)
+	return ids.Sub(be_indexer.DocIDList{3}), err
+}
+
+var testSchema = []FieldSchema{
+	{Name: "age", Kind: FieldKindNumber, Cardinality: 20, EnableRange: true},
+	{Name: "city", Kind: FieldKindString, Cardinality: 8},
+	{Name: "tag", Kind: FieldKindNumber, Cardinality: 10},
+	{Name: "title", Kind: FieldKindString, Cardinality: 15, EnableACMatch: true},
+}
+
+func TestChecker_Run(t *testing.T) {
+	convey.Convey("test index implementations consistent", t, func() {
+		for seed := int64(1); seed <= 5; seed++ {
+			checker := NewChecker(Options{
+				Fields:  testSchema,
+				Docs:    200,
+				Queries: 200,
+				Seed:    seed,
+			})
+			ce := checker.Run()
+			if ce != nil {
+				t.Log(ce.String())
+			}
+			convey.So(ce, convey.ShouldBeNil)
+		}
+	})
+
+	convey.Convey("test counterexample minimized", t, func() {
+		checker := NewChecker(Options{
+			Fields:  testSchema,
+			Docs:    100,
+			Queries: 500,
+			Seed:    1,
+			Engines: []Engine{&droppingEngine{Engine: NewBruteForceEngine()}},
+		})
+		ce := checker.Run()
+		convey.So(ce, convey.ShouldNotBeNil)
+		convey.So(len(ce.Docs), convey.ShouldEqual, 1)
+		convey.So(ce.Docs[0].ID, convey.ShouldEqual, 3)
+		convey.So(len(ce.Docs[0].Cons), convey.ShouldEqual, 1)
+		convey.So(ce.Expect, convey.ShouldResemble, be_indexer.DocIDList{3})
+		convey.So(ce.Results["dropping"], convey.ShouldBeEmpty)
+		convey.So(ce.String(), convey.ShouldContainSubstring, "dropping")
+	})
+}
@@ -0,0 +1,167 @@
+package consistency
+
+import (
+	"sort"
+
+	"github.com/echoface/be_indexer"
+	_ "github.com/echoface/be_indexer/holder/ahoholder" // register ac_matcher holder
+	"github.com/echoface/be_indexer/parser"
+	"github.com/echoface/be_indexer/roaringidx"
+)
+
+type (
+	// Engine a boolean expression index implementation under checking
+	Engine interface {
+		Name() string
+
+		// Build index the documents, it will be called for each round of checking
+		Build(schema []FieldSchema, docs []*be_indexer.Document) error
+
+		// Retrieve return sorted document ids
+		Retrieve(assigns be_indexer.Assignments) (be_indexer.DocIDList, error)
+	}
+
+	beIndexEngine struct {
+		compact bool
+		index   be_indexer.BEIndex
+	}
+
+	ivtIndexEngine struct {
+		indexer *roaringidx.IvtBEIndexer
+	}
+
+	bruteForceEngine struct {
+		eval *evaluator
+		docs []*be_indexer.Document
+	}
+)
+
+// NewKGroupsEngine engine of KGroupsBEIndex
+func NewKGroupsEngine() Engine {
+	return &beIndexEngine{compact: false}
+}
+
+// NewCompactEngine engine of CompactBEIndex
+func NewCompactEngine() Engine {
+	return &beIndexEngine{compact: true}
+}
+
+// NewIvtEngine engine of roaringidx IvtBEIndexer
+func NewIvtEngine() Engine {
+	return &ivtIndexEngine{}
+}
+
+// NewBruteForceEngine engine evaluate every document against assignments on raw expression values,
+// it's the reference engine and independent of holders/containers under checking
+func NewBruteForceEngine() Engine {
+	return &bruteForceEngine{}
+}
+
+func (e *beIndexEngine) Name() string {
+	if e.compact {
+		return "compact"
+	}
+	return "kgroups"
+}
+
+// fieldOptions field config of be_indexer from schema
+func fieldOptions(schema []FieldSchema) map[be_indexer.BEField]be_indexer.FieldOption {
+	options := make(map[be_indexer.BEField]be_indexer.FieldOption, len(schema))
+	for _, field := range schema {
+		option := be_indexer.FieldOption{Container: be_indexer.HolderNameDefault}
+		if field.Kind == FieldKindNumber && field.EnableRange {
+			option.Container = be_indexer.HolderNameExtendRange
+		} else if field.Kind == FieldKindString && field.EnableACMatch {
+			option.Container = be_indexer.HolderNameACMatcher
+		}
+		options[field.Name] = option
+	}
+	return options
+}
+
+func (e *beIndexEngine) Build(schema []FieldSchema, docs []*be_indexer.Document) error {
+	builder := be_indexer.NewIndexerBuilder()
+	if e.compact {
+		builder = be_indexer.NewCompactIndexerBuilder()
+	}
+	for field, option := range fieldOptions(schema) {
+		builder.ConfigField(field, option)
+	}
+	if err := builder.AddDocument(docs...); err != nil {
+		return err
+	}
+	e.index = builder.BuildIndex()
+	return nil
+}
+
+func (e *beIndexEngine) Retrieve(assigns be_indexer.Assignments) (be_indexer.DocIDList, error) {
+	ids, err := e.index.Retrieve(assigns)
+	sort.Sort(ids)
+	return ids, err
+}
+
+func (e *ivtIndexEngine) Name() string {
+	return "roaringidx"
+}
+
+func (e *ivtIndexEngine) Build(schema []FieldSchema, docs []*be_indexer.Document) error {
+	builder := roaringidx.NewIndexerBuilder()
+	for _, field := range schema {
+		setting := roaringidx.FieldSetting{Container: roaringidx.ContainerNameDefault, Parser: parser.NewStrHashParser()}
+		if field.Kind == FieldKindNumber {
+			setting.Parser = parser.NewNumberParser()
+			if field.EnableRange {
+				setting.Container = roaringidx.ContainerNameBSI
+			}
+		} else if field.EnableACMatch {
+			setting = roaringidx.FieldSetting{Container: roaringidx.ContainerNameAcMatch}
+		}
+		if err := builder.ConfigureField(string(field.Name), setting); err != nil {
+			return err
+		}
+	}
+	if err := builder.AddDocuments(docs...); err != nil {
+		return err
+	}
+	var err error
+	e.indexer, err = builder.BuildIndexer()
+	return err
+}
+
+func (e *ivtIndexEngine) Retrieve(assigns be_indexer.Assignments) (be_indexer.DocIDList, error) {
+	ids, err := roaringidx.NewScanner(e.indexer).Retrieve(assigns)
+	if err != nil {
+		return nil, err
+	}
+	res := make(be_indexer.DocIDList, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, be_indexer.DocID(id))
+	}
+	sort.Sort(res)
+	return res, nil
+}
+
+func (e *bruteForceEngine) Name() string {
+	return "brute_force"
+}
+
+func (e *bruteForceEngine) Build(schema []FieldSchema, docs []*be_indexer.Document) error {
+	e.eval = newEvaluator(schema)
+	e.docs = docs
+	return nil
+}
+
+func (e *bruteForceEngine) Retrieve(assigns be_indexer.Assignments) (be_indexer.DocIDList, error) {
+	var res be_indexer.DocIDList
+	for _, doc := range e.docs {
+		matched, err := e.eval.matchDocument(doc, assigns)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			res = append(res, doc.ID)
+		}
+	}
+	sort.Sort(res)
+	return res, nil
+}
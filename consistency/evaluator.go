@@ -0,0 +1,136 @@
+package consistency
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/echoface/be_indexer/util"
+)
+
+// evaluator brute force evaluate documents on raw expression values, it shares nothing
+// with holders/containers under checking, so it's an independent reference of the semantics
+type evaluator struct {
+	fields map[be_indexer.BEField]*FieldSchema
+}
+
+func newEvaluator(schema []FieldSchema) *evaluator {
+	e := &evaluator{fields: make(map[be_indexer.BEField]*FieldSchema, len(schema))}
+	for i := range schema {
+		e.fields[schema[i].Name] = &schema[i]
+	}
+	return e
+}
+
+// matchDocument a document matched if any of its conjunctions matched
+func (e *evaluator) matchDocument(doc *be_indexer.Document, assigns be_indexer.Assignments) (bool, error) {
+	for _, conj := range doc.Cons {
+		matched, err := e.matchConjunction(conj, assigns)
+		if err != nil {
+			return false, fmt.Errorf("doc:%d %w", doc.ID, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchConjunction all fields of conjunction need be satisfied, for each field:
+// any include expression matched(if exist) and none of exclude expression matched
+func (e *evaluator) matchConjunction(conj *be_indexer.Conjunction, assigns be_indexer.Assignments) (bool, error) {
+	for field, exprs := range conj.Expressions {
+		values := assigns[field]
+		hasIncl, inclMatched := false, false
+		for _, expr := range exprs {
+			matched, err := e.matchExpression(field, expr, values)
+			if err != nil {
+				return false, err
+			}
+			if expr.Incl {
+				hasIncl = true
+				inclMatched = inclMatched || matched
+			} else if matched {
+				return false, nil
+			}
+		}
+		if hasIncl && !inclMatched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchExpression whether assigned values satisfy the expression:
+// EQ: any assigned value in the value set of expression(keyword contained in joined values for ac field)
+// range: GT(n) => (n, +inf), LT(n) => (-inf, n), Between(l, r) => [l, r)
+func (e *evaluator) matchExpression(field be_indexer.BEField, expr *be_indexer.BoolValues, values be_indexer.Values) (bool, error) {
+	if util.NilInterface(values) {
+		return false, nil
+	}
+	if expr.Operator == be_indexer.ValueOptEQ {
+		exprValues, err := parser.ValuesToStrings(expr.Value)
+		if err != nil {
+			return false, fmt.Errorf("field:%s expression %w", field, err)
+		}
+		assigned, err := parser.ValuesToStrings(values)
+		if err != nil {
+			return false, fmt.Errorf("field:%s assignment %w", field, err)
+		}
+		if schema, ok := e.fields[field]; ok && schema.EnableACMatch {
+			content := strings.Join(assigned, " ")
+			for _, keyword := range exprValues {
+				if strings.Contains(content, keyword) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		for _, v := range assigned {
+			for _, ev := range exprValues {
+				if v == ev {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+
+	assigned, err := parser.ParseIntegers(values, true)
+	if err != nil {
+		return false, fmt.Errorf("field:%s assignment %w", field, err)
+	}
+	var left, right int64
+	switch expr.Operator {
+	case be_indexer.ValueOptGT, be_indexer.ValueOptLT:
+		if left, err = parser.ParseIntegerNumber(expr.Value, true); err != nil {
+			return false, fmt.Errorf("field:%s expression %w", field, err)
+		}
+	case be_indexer.ValueOptBetween:
+		rg, err := parser.ParseIntegers(expr.Value, true)
+		if err != nil || len(rg) != 2 {
+			return false, fmt.Errorf("field:%s bad between expression:%v", field, expr.Value)
+		}
+		left, right = rg[0], rg[1]
+	default:
+		return false, fmt.Errorf("field:%s unsupported operator:%v", field, expr.Operator)
+	}
+	for _, v := range assigned {
+		switch expr.Operator {
+		case be_indexer.ValueOptGT:
+			if v > left {
+				return true, nil
+			}
+		case be_indexer.ValueOptLT:
+			if v < left {
+				return true, nil
+			}
+		case be_indexer.ValueOptBetween:
+			if v >= left && v < right {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
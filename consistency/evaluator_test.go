@@ -0,0 +1,41 @@
+package consistency
+
+import (
+	"testing"
+
+	"github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestEvaluator_MatchExpression(t *testing.T) {
+	convey.Convey("test evaluate raw expression values", t, func() {
+		e := newEvaluator(testSchema)
+		cases := []struct {
+			field  be_indexer.BEField
+			expr   be_indexer.BoolValues
+			values be_indexer.Values
+			expect bool
+		}{
+			{"city", be_indexer.NewBoolValue(be_indexer.ValueOptEQ, []string{"v1", "v2"}, true), []string{"v2"}, true},
+			{"city", be_indexer.NewBoolValue(be_indexer.ValueOptEQ, []string{"v1"}, true), []string{"v12"}, false},
+			{"title", be_indexer.NewBoolValue(be_indexer.ValueOptEQ, []string{"v1"}, true), []string{"v12"}, true},
+			{"title", be_indexer.NewBoolValue(be_indexer.ValueOptEQ, []string{"v3 v"}, true), []string{"v3", "v4"}, true},
+			{"title", be_indexer.NewBoolValue(be_indexer.ValueOptEQ, []string{"v5"}, true), []string{"v3", "v4"}, false},
+			{"age", be_indexer.NewBoolValue(be_indexer.ValueOptGT, 5, true), []int64{5}, false},
+			{"age", be_indexer.NewBoolValue(be_indexer.ValueOptLT, 5, true), []int64{4}, true},
+			{"age", be_indexer.NewBoolValue(be_indexer.ValueOptBetween, []int64{3, 5}, true), []int64{3}, true},
+			{"age", be_indexer.NewBoolValue(be_indexer.ValueOptBetween, []int64{3, 5}, true), []int64{5}, false},
+			{"age", be_indexer.NewBoolValue(be_indexer.ValueOptGT, 5, true), nil, false},
+		}
+		for _, c := range cases {
+			expr := c.expr
+			matched, err := e.matchExpression(c.field, &expr, c.values)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(matched, convey.ShouldEqual, c.expect)
+		}
+
+		expr := be_indexer.NewBoolValue(be_indexer.ValueOptBetween, []int64{3}, true)
+		_, err := e.matchExpression("age", &expr, []int64{3})
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,109 @@
+package consistency
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/echoface/be_indexer"
+)
+
+type FieldKind int
+
+const (
+	FieldKindNumber FieldKind = iota
+	FieldKindString
+)
+
+type (
+	FieldSchema struct {
+		Name be_indexer.BEField
+		Kind FieldKind
+
+		// Cardinality values are generated from [0, Cardinality), small cardinality make more hits
+		Cardinality int
+
+		// EnableRange generate GT/LT/Between expressions for number field, the field will be
+		// indexed by range holder(be_indexer) and bsi container(roaringidx)
+		EnableRange bool
+
+		// EnableACMatch expression values of string field are keywords matched against the content
+		// joined by assigned values, the field will be indexed by ac_matcher holder(be_indexer)
+		// and ac_matcher container(roaringidx)
+		EnableACMatch bool
+	}
+
+	generator struct {
+		*Options
+		rand *rand.Rand
+	}
+)
+
+func (g *generator) fieldValue(field *FieldSchema) interface{} {
+	v := g.rand.Intn(field.Cardinality)
+	if field.Kind == FieldKindNumber {
+		return int64(v)
+	}
+	return fmt.Sprintf("v%d", v)
+}
+
+func (g *generator) fieldValues(field *FieldSchema, maxCnt int) be_indexer.Values {
+	cnt := 1 + g.rand.Intn(maxCnt)
+	if field.Kind == FieldKindNumber {
+		values := make([]int64, 0, cnt)
+		for i := 0; i < cnt; i++ {
+			values = append(values, g.fieldValue(field).(int64))
+		}
+		return values
+	}
+	values := make([]string, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		values = append(values, g.fieldValue(field).(string))
+	}
+	return values
+}
+
+func (g *generator) expression(field *FieldSchema) *be_indexer.BoolValues {
+	incl := g.rand.Intn(3) > 0
+	if field.Kind == FieldKindNumber && field.EnableRange && g.rand.Intn(2) == 0 {
+		v := int64(g.rand.Intn(field.Cardinality))
+		switch g.rand.Intn(3) {
+		case 0:
+			expr := be_indexer.NewBoolValue(be_indexer.ValueOptGT, v, incl)
+			return &expr
+		case 1:
+			expr := be_indexer.NewBoolValue(be_indexer.ValueOptLT, v, incl)
+			return &expr
+		default:
+			right := v + 1 + int64(g.rand.Intn(field.Cardinality))
+			expr := be_indexer.NewBoolValue(be_indexer.ValueOptBetween, []int64{v, right}, incl)
+			return &expr
+		}
+	}
+	expr := be_indexer.NewBoolValue(be_indexer.ValueOptEQ, g.fieldValues(field, g.MaxValuesPerExpr), incl)
+	return &expr
+}
+
+func (g *generator) document(id be_indexer.DocID) *be_indexer.Document {
+	doc := be_indexer.NewDocument(id)
+	for i := 0; i < 1+g.rand.Intn(g.MaxConjunctions); i++ {
+		conj := be_indexer.NewConjunction()
+		for j := 0; j < g.rand.Intn(g.MaxExprsPerConj+1); j++ {
+			field := &g.Fields[g.rand.Intn(len(g.Fields))]
+			conj.Expressions[field.Name] = append(conj.Expressions[field.Name], g.expression(field))
+		}
+		doc.AddConjunction(conj)
+	}
+	return doc
+}
+
+func (g *generator) assignments() be_indexer.Assignments {
+	assigns := be_indexer.Assignments{}
+	for i := range g.Fields {
+		field := &g.Fields[i]
+		if g.rand.Intn(4) == 0 {
+			continue
+		}
+		assigns[field.Name] = g.fieldValues(field, g.MaxValuesPerAssign)
+	}
+	return assigns
+}
@@ -0,0 +1,190 @@
+package consistency
+
+import (
+	"github.com/echoface/be_indexer"
+)
+
+// minimize shrink the failing case greedily: documents, conjunctions, expressions,
+// expression values, assignment fields and assignment values, each reduction
+// is kept only if engines still diverged
+func (c *Checker) minimize(docs []*be_indexer.Document, assigns be_indexer.Assignments) *Counterexample {
+	failing := func(docs []*be_indexer.Document, assigns be_indexer.Assignments) *Counterexample {
+		return c.check(docs, []be_indexer.Assignments{assigns})[0]
+	}
+
+	docs = cloneDocuments(docs)
+	ce := failing(docs, assigns)
+	if ce == nil { // not reproducible, should not happen for deterministic engines
+		return nil
+	}
+
+	// documents which make difference is the most likely minimal set
+	if candidates := ce.differentDocs(docs); len(candidates) > 0 && len(candidates) < len(docs) {
+		if next := failing(candidates, assigns); next != nil {
+			docs, ce = candidates, next
+		}
+	}
+
+	for i := 0; i < len(docs) && len(docs) > 1; {
+		next := append(append([]*be_indexer.Document{}, docs[:i]...), docs[i+1:]...)
+		if r := failing(next, assigns); r != nil {
+			docs, ce = next, r
+			continue
+		}
+		i++
+	}
+
+	for _, doc := range docs {
+		for i := 0; i < len(doc.Cons) && len(doc.Cons) > 1; {
+			removed := doc.Cons[i]
+			doc.Cons = append(doc.Cons[:i:i], doc.Cons[i+1:]...)
+			if r := failing(docs, assigns); r != nil {
+				ce = r
+				continue
+			}
+			doc.Cons = append(doc.Cons[:i:i], append([]*be_indexer.Conjunction{removed}, doc.Cons[i:]...)...)
+			i++
+		}
+		for _, conj := range doc.Cons {
+			if r := c.minimizeConjunction(docs, conj, assigns, failing); r != nil {
+				ce = r
+			}
+		}
+	}
+
+	for field, values := range assigns {
+		next := cloneAssignments(assigns)
+		delete(next, field)
+		if r := failing(docs, next); r != nil {
+			assigns, ce = next, r
+			continue
+		}
+		for i := 0; i < valuesLen(values); {
+			reduced, ok := removeValue(values, i)
+			if !ok {
+				break
+			}
+			next = cloneAssignments(assigns)
+			next[field] = reduced
+			if r := failing(docs, next); r != nil {
+				assigns, values, ce = next, reduced, r
+				continue
+			}
+			i++
+		}
+	}
+	return ce
+}
+
+func (c *Checker) minimizeConjunction(docs []*be_indexer.Document, conj *be_indexer.Conjunction,
+	assigns be_indexer.Assignments, failing func([]*be_indexer.Document, be_indexer.Assignments) *Counterexample) (ce *Counterexample) {
+
+	for field := range conj.Expressions {
+		exprs := conj.Expressions[field]
+		for i := 0; i < len(exprs); {
+			next := append(exprs[:i:i], exprs[i+1:]...)
+			if len(next) == 0 {
+				delete(conj.Expressions, field)
+			} else {
+				conj.Expressions[field] = next
+			}
+			if r := failing(docs, assigns); r != nil {
+				exprs, ce = next, r
+				continue
+			}
+			conj.Expressions[field] = exprs
+			i++
+		}
+		for _, expr := range exprs {
+			if expr.Operator != be_indexer.ValueOptEQ {
+				continue
+			}
+			for i := 0; i < valuesLen(expr.Value); {
+				origin := expr.Value
+				reduced, ok := removeValue(origin, i)
+				if !ok {
+					break
+				}
+				expr.Value = reduced
+				if r := failing(docs, assigns); r != nil {
+					ce = r
+					continue
+				}
+				expr.Value = origin
+				i++
+			}
+		}
+	}
+	return ce
+}
+
+// differentDocs documents appear in any engine's result but not in expect or vice versa
+func (ce *Counterexample) differentDocs(docs []*be_indexer.Document) (res []*be_indexer.Document) {
+	diff := map[be_indexer.DocID]struct{}{}
+	for _, result := range ce.Results {
+		for _, id := range result.Sub(ce.Expect) {
+			diff[id] = struct{}{}
+		}
+		for _, id := range ce.Expect.Sub(result) {
+			diff[id] = struct{}{}
+		}
+	}
+	for _, doc := range docs {
+		if _, ok := diff[doc.ID]; ok {
+			res = append(res, doc)
+		}
+	}
+	return res
+}
+
+func cloneDocuments(docs []*be_indexer.Document) []*be_indexer.Document {
+	res := make([]*be_indexer.Document, 0, len(docs))
+	for _, doc := range docs {
+		clone := be_indexer.NewDocument(doc.ID)
+		for _, conj := range doc.Cons {
+			c := be_indexer.NewConjunction()
+			for field, exprs := range conj.Expressions {
+				for _, expr := range exprs {
+					value := *expr
+					c.Expressions[field] = append(c.Expressions[field], &value)
+				}
+			}
+			clone.AddConjunction(c)
+		}
+		res = append(res, clone)
+	}
+	return res
+}
+
+func cloneAssignments(assigns be_indexer.Assignments) be_indexer.Assignments {
+	res := make(be_indexer.Assignments, len(assigns))
+	for field, values := range assigns {
+		res[field] = values
+	}
+	return res
+}
+
+func valuesLen(values be_indexer.Values) int {
+	switch vs := values.(type) {
+	case []int64:
+		return len(vs)
+	case []string:
+		return len(vs)
+	}
+	return 0
+}
+
+// removeValue remove the i-th value, at least one value will be kept
+func removeValue(values be_indexer.Values, i int) (be_indexer.Values, bool) {
+	switch vs := values.(type) {
+	case []int64:
+		if len(vs) > 1 {
+			return append(vs[:i:i], vs[i+1:]...), true
+		}
+	case []string:
+		if len(vs) > 1 {
+			return append(vs[:i:i], vs[i+1:]...), true
+		}
+	}
+	return values, false
+}
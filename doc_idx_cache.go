@@ -12,6 +12,8 @@ type DocIdxCache struct {
 	Version       uint64
 	SchemaHash    uint64         // 字段配置哈希，用于校验
 	ConjIdxCaches []ConjIdxCache // 每个 Conjunction 的结果
+	Payload       []byte         // 文档 payload，从缓存恢复时一并写入索引
+	Attrs         DocAttrs       // 文档属性(已规范化)，从缓存恢复时一并写入索引
 }
 
 // ConjIdxCache Conjunction 级别缓存
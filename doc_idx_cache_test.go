@@ -207,3 +207,38 @@ func TestIncrementalIndexing_CacheClearedOnSchemaChange(t *testing.T) {
 		convey.So(cache.Size(), convey.ShouldEqual, 0)
 	})
 }
+
+func TestIncrementalIndexing_PayloadRestoredFromCache(t *testing.T) {
+	convey.Convey("test payload and attributes saved into and restored from document level cache", t, func() {
+		cache := NewMemoryDocCache()
+
+		builder := NewIndexerBuilder(WithDocLevelCache(cache))
+		builder.ConfigField("age", FieldOption{Container: HolderNameDefault})
+		doc := NewDocument(1)
+		doc.Version = 1
+		doc.Payload = []byte("creative:7")
+		doc.Attrs = DocAttrs{"advertiser": 7, "campaign": "c1"}
+		doc.AddConjunction(NewConjunction().In("age", []int{18}))
+		convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+
+		cached, ok := cache.Get(NewDocCacheKey(1, 1))
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(string(cached.Payload), convey.ShouldEqual, "creative:7")
+		convey.So(cached.Attrs, convey.ShouldResemble, DocAttrs{"advertiser": int64(7), "campaign": "c1"})
+
+		restoreBuilder := NewIndexerBuilder()
+		restoreBuilder.ConfigField("age", FieldOption{Container: HolderNameDefault})
+		convey.So(restoreBuilder.AddDocIndexingData(cached), convey.ShouldBeNil)
+		index := restoreBuilder.BuildIndex()
+
+		results, err := RetrieveWithPayloads(index, Assignments{"age": []int{18}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(results, convey.ShouldResemble, []DocPayload{{ID: 1, Payload: []byte("creative:7")}})
+		convey.So(index.(AttributeIndex).Attributes().Get(1), convey.ShouldResemble, DocAttrs{"advertiser": int64(7), "campaign": "c1"})
+
+		// restored attributes are owned by index
+		cached.Attrs["advertiser"] = int64(8)
+		advertiser, _ := index.(AttributeIndex).Attributes().Int(1, "advertiser")
+		convey.So(advertiser, convey.ShouldEqual, 7)
+	})
+}
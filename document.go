@@ -18,6 +18,12 @@ type (
 		ID      DocID          `json:"id"`      // 只支持2^43最大值个Doc
 		Version uint64         `json:"version"` // 【增量缓存】业务提供的文档版本号，0表示不使用缓存
 		Cons    []*Conjunction `json:"cons"`    // conjunction之间的关系是或，具体描述可以看论文的表述
+
+		// Attrs attributes stored into index side-store(see AttributeStore), not used for retrieving
+		Attrs DocAttrs `json:"attrs,omitempty"`
+
+		// Payload opaque data returned alongside document id, see RetrieveWithPayloads
+		Payload []byte `json:"payload,omitempty"`
 	}
 )
 
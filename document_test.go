@@ -1,8 +1,10 @@
 package be_indexer
 
 import (
+	"sort"
 	"testing"
 
+	"github.com/echoface/be_indexer/parser"
 	"github.com/smartystreets/goconvey/convey"
 )
 
@@ -96,3 +98,116 @@ func TestDocument_AddConjunctions(t *testing.T) {
 	})
 
 }
+
+func TestConjunction_Evaluate(t *testing.T) {
+	convey.Convey("test evaluate conjunction without index", t, func() {
+		conj := NewConjunction().
+			In("age", NewInt64Values(12, 15)).
+			NotIn("city", []string{"sh"}).
+			In("label", []string{"a"}).
+			In("label", []string{"b"})
+
+		convey.So(conj.Evaluate(Assignments{"age": 12, "label": "b"}, nil), convey.ShouldBeTrue)
+		convey.So(conj.Evaluate(Assignments{"age": 12, "label": "a", "city": "bj"}, nil), convey.ShouldBeTrue)
+		convey.So(conj.Evaluate(Assignments{"age": 12, "label": "a", "city": "sh"}, nil), convey.ShouldBeFalse)
+		convey.So(conj.Evaluate(Assignments{"age": 13, "label": "a"}, nil), convey.ShouldBeFalse)
+		convey.So(conj.Evaluate(Assignments{"age": 12}, nil), convey.ShouldBeFalse)
+
+		exclOnly := NewConjunction().NotIn("city", []string{"sh"})
+		convey.So(exclOnly.Evaluate(Assignments{}, nil), convey.ShouldBeTrue)
+		convey.So(exclOnly.Evaluate(Assignments{"city": []string{"bj", "sh"}}, nil), convey.ShouldBeFalse)
+
+		// default holder not support range operator, Retrieve fail to build too
+		convey.So(NewConjunction().GreaterThan("age", 10).Evaluate(Assignments{"age": 20}, nil), convey.ShouldBeFalse)
+
+		opt := &EvalOptions{FieldConfig: map[BEField]FieldOption{"age": {Container: "not_exist"}}}
+		convey.So(conj.Evaluate(Assignments{"age": 12, "label": "b"}, opt), convey.ShouldBeFalse)
+	})
+
+	convey.Convey("test document match agree with retrieve", t, func() {
+		builder := NewIndexerBuilder()
+		docs := []*Document{
+			NewDocument(1).AddConjunction(
+				NewConjunction().In("age", NewInt64Values(1, 2)).NotIn("city", []string{"sh"}),
+				NewConjunction().In("city", []string{"bj"}),
+			),
+			NewDocument(2).AddConjunction(NewConjunction().NotIn("age", NewInt64Values(3))),
+			NewDocument(3).AddConjunction(
+				NewConjunction().In("age", NewInt64Values(3)).In("city", []string{"sh", "gz"}),
+			),
+		}
+		convey.So(builder.AddDocument(docs...), convey.ShouldBeNil)
+		index := builder.BuildIndex()
+
+		queries := []Assignments{
+			{},
+			{"age": 1},
+			{"age": 1, "city": "sh"},
+			{"age": 3, "city": "gz"},
+			{"age": []int64{2, 3}, "city": []string{"bj", "sh"}},
+		}
+		for _, assigns := range queries {
+			result, err := index.Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+
+			var matched DocIDList
+			for _, doc := range docs {
+				if len(doc.Match(assigns)) > 0 {
+					matched = append(matched, doc.ID)
+				}
+			}
+			sort.Sort(result)
+			convey.So(matched, convey.ShouldResemble, result)
+		}
+		convey.So(docs[0].Match(Assignments{"age": 1, "city": "bj"}), convey.ShouldResemble, []int{0, 1})
+		convey.So(docs[0].Match(Assignments{"age": 1, "city": "sh"}), convey.ShouldBeEmpty)
+	})
+}
+
+func TestConjunction_EvaluateGeohash(t *testing.T) {
+	RegisterEntriesHolder("geohash_eval", func() EntriesHolder {
+		holder := NewDefaultEntriesHolder()
+		holder.RegisterFieldTokenizer("location", parser.NewGeoHashParser(nil))
+		return holder
+	})
+
+	convey.Convey("test evaluate geohash expression agree with retrieve", t, func() {
+		opt := &EvalOptions{FieldConfig: map[BEField]FieldOption{
+			"location": {Container: "geohash_eval"},
+		}}
+		docs := []*Document{
+			NewDocument(1).AddConjunction(NewConjunction().In("location", "31.21275902:121.53779984:1000")),
+			NewDocument(2).AddConjunction(NewConjunction().
+				NotIn("location", "31.21275902:121.53779984:1000").
+				In("label", []string{"a"})),
+			NewDocument(3).AddConjunction(
+				NewConjunction().In("location", "30.5:114.3:5000"),
+				NewConjunction().In("location", "31.21275902:121.53779984:300"),
+			),
+		}
+		points := []Values{
+			[2]float64{31.21275902, 121.53779984},
+			[2]float64{31.21775902, 121.53779984}, // ~500m north
+			[2]float64{31.23275902, 121.53779984}, // ~2km north
+			[2]float64{30.5, 114.3},
+		}
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			builder.ConfigField("location", opt.FieldConfig["location"])
+			convey.So(builder.AddDocument(docs...), convey.ShouldBeNil)
+			index := builder.BuildIndex()
+
+			for _, point := range points {
+				for _, assigns := range []Assignments{{"location": point}, {"location": point, "label": "a"}} {
+					result, err := index.Retrieve(assigns)
+					convey.So(err, convey.ShouldBeNil)
+					for _, doc := range docs {
+						convey.So(len(doc.Match(assigns, opt)) > 0, convey.ShouldEqual, result.Contain(doc.ID))
+					}
+				}
+			}
+		}
+		center := Assignments{"location": points[0]}
+		convey.So(docs[2].Match(center, opt), convey.ShouldResemble, []int{1})
+		convey.So(docs[0].Match(Assignments{"location": points[2]}, opt), convey.ShouldBeEmpty)
+	})
+}
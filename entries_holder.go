@@ -44,6 +44,12 @@ type (
 		CompileEntries() error
 	}
 
+	// LoggerSetter optional interface of EntriesHolder(and index), the index inject its logger
+	// (see WithLogger) into holders before compiling, nil means fallback to global Logger
+	LoggerSetter interface {
+		SetLogger(logger StructuredLogger)
+	}
+
 	// holder 自定义的索引数据，encode用于支持增量构建
 	IndexingData interface {
 		// Encode serialize TxData for caching
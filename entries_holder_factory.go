@@ -5,9 +5,13 @@ type (
 )
 
 const (
-	HolderNameDefault     = "default"
-	HolderNameACMatcher   = "ac_matcher"
-	HolderNameExtendRange = "ext_range"
+	HolderNameDefault       = "default"
+	HolderNameACMatcher     = "ac_matcher"
+	HolderNameExtendRange   = "ext_range"
+	HolderNameKeywordExpr   = "keyword_expr"
+	HolderNameFuzzyMatch    = "fuzzy_match"
+	HolderNameBoxRange      = "box_range"
+	HolderNameAdaptiveRange = "adaptive_range"
 )
 
 var holderFactory = make(map[string]HolderBuilder)
@@ -41,3 +45,10 @@ func RegisterEntriesHolder(name string, builder HolderBuilder) {
 	}
 	holderFactory[name] = builder
 }
+
+// setHolderLogger inject logger into holder if it implements LoggerSetter
+func setHolderLogger(holder EntriesHolder, logger StructuredLogger) {
+	if setter, ok := holder.(LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
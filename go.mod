@@ -3,18 +3,19 @@ module github.com/echoface/be_indexer
 go 1.18
 
 require (
-	github.com/RoaringBitmap/roaring v0.9.4
+	github.com/RoaringBitmap/roaring v1.9.4
 	github.com/anknown/ahocorasick v0.0.0-20190904063843-d75dbd5169c0
 	github.com/echoface/proximityhash v0.0.0-20230211105152-91366992edfe
 	github.com/iohub/ahocorasick v0.0.0-20190713143823-b7bfd8ad9e27
 	github.com/mmcloughlin/geohash v0.10.0
 	github.com/smartystreets/goconvey v1.7.2
+	golang.org/x/text v0.14.0
 	google.golang.org/protobuf v1.28.1
 )
 
 require (
 	github.com/anknown/darts v0.0.0-20151216065714-83ff685239e6 // indirect
-	github.com/bits-and-blooms/bitset v1.2.0 // indirect
+	github.com/bits-and-blooms/bitset v1.12.0 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
 	github.com/jtolds/gls v4.20.0+incompatible // indirect
 	github.com/mschoch/smat v0.2.0 // indirect
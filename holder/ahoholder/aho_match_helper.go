@@ -3,6 +3,11 @@ package ahoholder
 import (
 	"fmt"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
 )
 
 func ParseAcMatchDict(values interface{}) (r []string, e error) {
@@ -51,3 +56,87 @@ func BuildAcMatchContent(v interface{}, joinSep string) ([]rune, error) {
 	}
 	return data, nil
 }
+
+// BuildNormalizedAcMatchContent build query content like BuildAcMatchContent,
+// then apply the same normalization that used for keywords when indexing
+func BuildNormalizedAcMatchContent(v interface{}, option *ACHolderOption) ([]rune, error) {
+	data, err := BuildAcMatchContent(v, option.QuerySep)
+	if err != nil || !option.NeedNormalize() {
+		return data, err
+	}
+	return []rune(option.NormalizeText(string(data))), nil
+}
+
+// NormalizeAcMatchDict normalize keywords and remove the duplicated after normalization
+func NormalizeAcMatchDict(keys []string, option *ACHolderOption) []string {
+	if !option.NeedNormalize() {
+		return keys
+	}
+	seen := make(map[string]struct{}, len(keys))
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = option.NormalizeText(key)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		res = append(res, key)
+	}
+	return res
+}
+
+// NeedNormalize whether any normalization is enabled
+func (opt *ACHolderOption) NeedNormalize() bool {
+	return opt.CaseFolding || opt.NFKCNormalize || opt.WidthFolding
+}
+
+// NormalizeText apply normalization in order: width folding -> NFKC -> case folding
+func (opt *ACHolderOption) NormalizeText(s string) string {
+	if opt.WidthFolding {
+		s = width.Fold.String(s)
+	}
+	if opt.NFKCNormalize {
+		s = norm.NFKC.String(s)
+	}
+	if opt.CaseFolding { // Caser is stateful, can't be shared between goroutines
+		s = cases.Fold().String(s)
+	}
+	return s
+}
+
+var (
+	defaultWordRunes    = []*unicode.RangeTable{unicode.Letter, unicode.Digit}
+	defaultPerCharRunes = []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana}
+)
+
+// AcceptMatch check whether a matched word at content[pos:pos+size] is acceptable,
+// when WordBoundary enabled, both sides of the matched word must be a word boundary
+func (opt *ACHolderOption) AcceptMatch(content []rune, pos, size int) bool {
+	if !opt.WordBoundary || size <= 0 {
+		return true
+	}
+	end := pos + size
+	if pos > 0 && !opt.isBoundary(content[pos-1], content[pos]) {
+		return false
+	}
+	if end < len(content) && !opt.isBoundary(content[end], content[end-1]) {
+		return false
+	}
+	return true
+}
+
+// isBoundary whether the adjacent rune `r` split the matched word edge rune `edge` into a token
+func (opt *ACHolderOption) isBoundary(r, edge rune) bool {
+	perChar := opt.PerCharRunes
+	if perChar == nil {
+		perChar = defaultPerCharRunes
+	}
+	if unicode.IsOneOf(perChar, r) || unicode.IsOneOf(perChar, edge) {
+		return true
+	}
+	wordRunes := opt.WordRunes
+	if wordRunes == nil {
+		wordRunes = defaultWordRunes
+	}
+	return !unicode.IsOneOf(wordRunes, r) || !unicode.IsOneOf(wordRunes, edge)
+}
@@ -3,6 +3,7 @@ package ahoholder
 import (
 	"fmt"
 	"testing"
+	"unicode"
 
 	"github.com/smartystreets/goconvey/convey"
 )
@@ -21,3 +22,49 @@ func TestBuildAcMatchContent(t *testing.T) {
 		convey.So(err, convey.ShouldNotBeNil)
 	})
 }
+
+func TestACHolderOption_NormalizeText(t *testing.T) {
+	convey.Convey("test keyword/query normalization", t, func() {
+		opt := &ACHolderOption{QuerySep: " "}
+		convey.So(opt.NeedNormalize(), convey.ShouldBeFalse)
+		convey.So(opt.NormalizeText("Nike"), convey.ShouldEqual, "Nike")
+
+		opt.CaseFolding = true
+		convey.So(opt.NormalizeText("NiKe"), convey.ShouldEqual, "nike")
+
+		opt.WidthFolding = true
+		convey.So(opt.NormalizeText("ＮＩＫＥ１２３红包"), convey.ShouldEqual, "nike123红包")
+
+		opt.NFKCNormalize = true
+		convey.So(opt.NormalizeText("ﬁle①"), convey.ShouldEqual, "file1")
+
+		keys := NormalizeAcMatchDict([]string{"Nike", "NIKE", "ｎｉｋｅ", "红包"}, opt)
+		convey.So(keys, convey.ShouldResemble, []string{"nike", "红包"})
+
+		v, err := BuildNormalizedAcMatchContent([]string{"Ｎｉｋｅ", "Shoes"}, opt)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(string(v), convey.ShouldEqual, "nike shoes")
+	})
+}
+
+func TestACHolderOption_AcceptMatch(t *testing.T) {
+	convey.Convey("test word boundary match", t, func() {
+		opt := &ACHolderOption{QuerySep: " "}
+		content := []rune("a scarf for car-lovers")
+		convey.So(opt.AcceptMatch(content, 3, 3), convey.ShouldBeTrue)
+
+		opt.WordBoundary = true
+		convey.So(opt.AcceptMatch(content, 3, 3), convey.ShouldBeFalse)  // s[car]f
+		convey.So(opt.AcceptMatch(content, 12, 3), convey.ShouldBeTrue)  // [car]-lovers
+		convey.So(opt.AcceptMatch(content, 16, 6), convey.ShouldBeTrue)  // car-[lovers]
+		convey.So(opt.AcceptMatch(content, 16, 4), convey.ShouldBeFalse) // car-[love]rs
+
+		content = []rune("买car红包")
+		convey.So(opt.AcceptMatch(content, 1, 3), convey.ShouldBeTrue)
+		convey.So(opt.AcceptMatch(content, 4, 1), convey.ShouldBeTrue)
+
+		opt.WordRunes = []*unicode.RangeTable{unicode.Letter}
+		content = []rune("car2")
+		convey.So(opt.AcceptMatch(content, 0, 3), convey.ShouldBeTrue)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"unicode"
 
 	aho "github.com/anknown/ahocorasick"
 	. "github.com/echoface/be_indexer"
@@ -17,11 +18,32 @@ type (
 		// QuerySep 查询时，当存在多个值时，使用什么分隔符拼接多个查询字段来组成查询语句, 默认使用whitespace
 		// 这是因为在语义上'空'更符合逻辑表达的正确性, 但这也会导致输入的句子如果本身有空格的情况，可能导致拼接之后误匹配问题
 		QuerySep string
+
+		// CaseFolding 大小写不敏感匹配, 关键词与查询内容统一做Unicode case folding(不只是转小写),
+		// eg: "Nike" 可以匹配 "nike", "STRASSE" 可以匹配 "straße"
+		CaseFolding bool
+
+		// NFKCNormalize 对关键词与查询内容做Unicode NFKC规范化, eg: "ﬁ" => "fi", "①" => "1"
+		NFKCNormalize bool
+
+		// WidthFolding 全角/半角统一, 全角ASCII转换为半角, 半角片假名转换为全角, eg: "ＮＩＫＥ" => "NIKE"
+		WidthFolding bool
+
+		// WordBoundary 词边界模式, 只有匹配结果的两侧都处于词边界时才接受, eg: "car" 不会再匹配 "scarf"
+		// 对于英文等以空格/标点分词的语言可以避免大量的误匹配
+		WordBoundary bool
+
+		// WordRunes 构成单词的字符类, 词边界模式下匹配两侧的字符不属于这些字符类时视为边界; 默认: 字母与数字
+		WordRunes []*unicode.RangeTable
+
+		// PerCharRunes 按单字成词的字符类(如中日文), 这类字符两侧总视为边界; 默认: Han/Hiragana/Katakana
+		PerCharRunes []*unicode.RangeTable
 	}
 
 	ACEntriesHolder struct {
 		ACHolderOption
 		debug       bool
+		logger      StructuredLogger // injected by index, see LoggerSetter
 		totalTokens int
 		maxLen      int64 // max length of Entries
 		avgLen      int64 // avg length of Entries
@@ -70,11 +92,16 @@ func (h *ACEntriesHolder) EnableDebug(debug bool) {
 	h.debug = debug
 }
 
+// SetLogger implement LoggerSetter, debug message logged by it
+func (h *ACEntriesHolder) SetLogger(logger StructuredLogger) {
+	h.logger = logger
+}
+
 // DumpInfo
 // {name: %s, value_count:%d max_entries:%d avg_entries:%d}
 func (h *ACEntriesHolder) DumpInfo(buffer *strings.Builder) {
-	info := fmt.Sprintf("{name: %s, value_count:%d max_entries:%d avg_entries:%d}",
-		"ac_holder", len(h.values), h.maxLen, h.avgLen)
+	info := fmt.Sprintf("{name: %s, value_count:%d max_entries:%d avg_entries:%d case_folding:%t nfkc:%t width_folding:%t word_boundary:%t}",
+		"ac_holder", len(h.values), h.maxLen, h.avgLen, h.CaseFolding, h.NFKCNormalize, h.WidthFolding, h.WordBoundary)
 	buffer.WriteString(info)
 }
 
@@ -95,10 +122,8 @@ func (h *ACEntriesHolder) BuildFieldIndexingData(_ *FieldDesc, bv *BoolValues) (
 	if err != nil {
 		return nil, fmt.Errorf("ac holder need string(able) value, err:%v", err)
 	}
-	data := cache.StrListValues{
-		Values: keys,
-	}
-	return &AcHolderTxData{Keys: data}, nil
+	keys = NormalizeAcMatchDict(keys, &h.ACHolderOption)
+	return &AcHolderTxData{Keys: cache.StrListValues{Values: keys}}, nil
 }
 
 func (h *ACEntriesHolder) CommitFieldIndexingData(tx FieldIndexingData) error {
@@ -120,7 +145,7 @@ func (h *ACEntriesHolder) GetEntries(field *FieldDesc, assigns Values) (EntriesC
 	if len(h.values) == 0 {
 		return nil, nil
 	}
-	buf, err := BuildAcMatchContent(assigns, h.QuerySep)
+	buf, err := BuildNormalizedAcMatchContent(assigns, &h.ACHolderOption)
 	if err != nil {
 		return nil, err
 	}
@@ -130,9 +155,20 @@ func (h *ACEntriesHolder) GetEntries(field *FieldDesc, assigns Values) (EntriesC
 
 	var cursors EntriesCursors
 
+	matched := make(map[string]struct{})
 	terms := h.machine.MultiPatternSearch(buf, false)
 	for _, term := range terms {
+		if !h.AcceptMatch(buf, term.Pos, len(term.Word)) {
+			if h.debug {
+				LoggerOrDefault(h.logger).Debug("drop none word boundary match", "word", string(term.Word), "pos", term.Pos)
+			}
+			continue
+		}
 		key := string(term.Word)
+		if _, ok := matched[key]; ok {
+			continue
+		}
+		matched[key] = struct{}{}
 		if pl, ok := h.values[key]; ok && len(pl) > 0 {
 			cursor := NewEntriesCursor(NewQKey(field.Field, key), pl)
 			cursors = append(cursors, cursor)
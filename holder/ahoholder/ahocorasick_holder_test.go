@@ -62,3 +62,179 @@ func TestBEIndex_Retrieve6(t *testing.T) {
 		convey.So(err, convey.ShouldBeNil)
 	})
 }
+
+func TestBEIndex_RetrieveNormalized(t *testing.T) {
+	RegisterEntriesHolder("ac_normalized", func() EntriesHolder {
+		return NewACEntriesHolder(ACHolderOption{
+			QuerySep:      " ",
+			CaseFolding:   true,
+			NFKCNormalize: true,
+			WidthFolding:  true,
+		})
+	})
+
+	builder := NewIndexerBuilder()
+	builder.ConfigField("keyword", FieldOption{
+		Container: "ac_normalized",
+	})
+
+	doc := NewDocument(1)
+	doc.AddConjunction(NewConjunction().In("keyword", NewStrValues("Nike", "ＡＤＩＤＡＳ")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(2)
+	doc.AddConjunction(NewConjunction().In("keyword", NewStrValues("红包１２３")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(3)
+	doc.AddConjunction(NewConjunction().In("keyword", NewStrValues("STRASSE", "ΣΊΣΥΦΟΣ")))
+	_ = builder.AddDocument(doc)
+
+	convey.Convey("test ac matcher retrieve with normalization", t, func() {
+		indexer := builder.BuildIndex()
+
+		ids, err := indexer.Retrieve(Assignments{"keyword": "buy NIKE shoes"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1})
+
+		ids, err = indexer.Retrieve(Assignments{"keyword": NewStrValues("adidas", "领红包123")})
+		convey.So(err, convey.ShouldBeNil)
+		sort.Sort(ids)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1, 2})
+
+		ids, err = indexer.Retrieve(Assignments{"keyword": "ｎｉｋ"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(ids), convey.ShouldEqual, 0)
+
+		// case folding rather than lower-casing: "ß" => "ss", final sigma "ς" => "σ"
+		ids, err = indexer.Retrieve(Assignments{"keyword": "hauptstraße"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{3})
+
+		ids, err = indexer.Retrieve(Assignments{"keyword": "σίσυφος"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{3})
+	})
+}
+
+func TestBEIndex_RetrieveWordBoundary(t *testing.T) {
+	RegisterEntriesHolder("ac_word_boundary", func() EntriesHolder {
+		return NewACEntriesHolder(ACHolderOption{QuerySep: " ", WordBoundary: true})
+	})
+
+	builder := NewIndexerBuilder()
+	builder.ConfigField("keyword", FieldOption{
+		Container: "ac_word_boundary",
+	})
+
+	doc := NewDocument(1)
+	doc.AddConjunction(NewConjunction().In("keyword", NewStrValues("car")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(2)
+	doc.AddConjunction(NewConjunction().In("keyword", NewStrValues("红包")))
+	_ = builder.AddDocument(doc)
+
+	convey.Convey("test ac matcher retrieve with word boundary", t, func() {
+		indexer := builder.BuildIndex()
+
+		ids, err := indexer.Retrieve(Assignments{"keyword": "a warm scarf"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(ids), convey.ShouldEqual, 0)
+
+		ids, err = indexer.Retrieve(Assignments{"keyword": NewStrValues("scarf", "rent a car")})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1})
+
+		ids, err = indexer.Retrieve(Assignments{"keyword": "抢红包啦"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{2})
+	})
+}
+
+func TestConjunction_EvaluateACMatcher(t *testing.T) {
+	docs := []*Document{
+		NewDocument(1).AddConjunction(NewConjunction().In("keyword", NewStrValues("Nike", "ＡＤＩＤＡＳ"))),
+		NewDocument(2).AddConjunction(NewConjunction().
+			In("keyword", NewStrValues("car")).
+			NotIn("keyword", NewStrValues("scarf"))),
+		NewDocument(3).AddConjunction(
+			NewConjunction().In("keyword", NewStrValues("红包１２３")),
+			NewConjunction().NotIn("keyword", NewStrValues("ad")),
+		),
+	}
+	queries := []Assignments{{}}
+	for _, q := range []Values{
+		"buy NIKE shoes", "ｎｉｋｅ", "adidas", "a warm scarf", "rent a CAR", "rent a car",
+		"领红包123", "抢红包１２３啦", NewStrValues("scarf", "rent a car"), "bad", "an ad",
+	} {
+		queries = append(queries, Assignments{"keyword": q})
+	}
+
+	cases := []struct {
+		name   string
+		option ACHolderOption
+		probe  Assignments
+		expect []int // conjunctions of docs[0..2] matched by probe
+	}{
+		{"ac_eval_default", ACHolderOption{QuerySep: " "},
+			Assignments{"keyword": "ｎｉｋｅ scarf"}, []int{3}},
+		{"ac_eval_normalized", ACHolderOption{QuerySep: " ", CaseFolding: true, NFKCNormalize: true, WidthFolding: true},
+			Assignments{"keyword": "ｎｉｋｅ scarf"}, []int{1, 3}},
+		{"ac_eval_word_boundary", ACHolderOption{QuerySep: " ", WordBoundary: true},
+			Assignments{"keyword": "a warm scarf"}, []int{3}},
+		{"ac_eval_all", ACHolderOption{
+			QuerySep: " ", CaseFolding: true, NFKCNormalize: true, WidthFolding: true, WordBoundary: true,
+		}, Assignments{"keyword": "NIKE CAR"}, []int{1, 2, 3}},
+	}
+	for _, c := range cases {
+		option := c.option
+		RegisterEntriesHolder(c.name, func() EntriesHolder {
+			return NewACEntriesHolder(option)
+		})
+
+		convey.Convey("test evaluate ac matcher agree with retrieve:"+c.name, t, func() {
+			opt := &EvalOptions{FieldConfig: map[BEField]FieldOption{"keyword": {Container: c.name}}}
+			builder := NewIndexerBuilder()
+			builder.ConfigField("keyword", opt.FieldConfig["keyword"])
+			convey.So(builder.AddDocument(docs...), convey.ShouldBeNil)
+			index := builder.BuildIndex()
+
+			for _, assigns := range append(queries, c.probe) {
+				result, err := index.Retrieve(assigns)
+				convey.So(err, convey.ShouldBeNil)
+				for _, doc := range docs {
+					convey.So(len(doc.Match(assigns, opt)) > 0, convey.ShouldEqual, result.Contain(doc.ID))
+				}
+			}
+
+			matched := []int{}
+			for _, doc := range docs {
+				if len(doc.Match(c.probe, opt)) > 0 {
+					matched = append(matched, int(doc.ID))
+				}
+			}
+			convey.So(matched, convey.ShouldResemble, c.expect)
+		})
+	}
+}
+
+func TestBEIndex_ACResultCache(t *testing.T) {
+	convey.Convey("test result cache keep order of values joined by ac matcher", t, func() {
+		builder := NewIndexerBuilder()
+		builder.ConfigField("keyword", FieldOption{Container: HolderNameACMatcher})
+		doc := NewDocument(2).AddConjunction(NewConjunction().In("keyword", NewStrValues("running shoes")))
+		convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		index := builder.BuildIndex()
+		cache := NewResultCache(index)
+
+		for _, values := range [][]string{{"running", "shoes"}, {"shoes", "running"}} {
+			expect, err := index.Retrieve(Assignments{"keyword": values})
+			convey.So(err, convey.ShouldBeNil)
+			result, err := cache.Retrieve(Assignments{"keyword": values})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(result, convey.ShouldResemble, expect)
+		}
+		convey.So(cache.Stats().Misses, convey.ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,158 @@
+package boxholder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/echoface/be_indexer/parser"
+)
+
+type (
+	// Box a N-dimensional closed box, [Min[i], Max[i]] for dimension i
+	// eg: creative size: Box{Min: {300, 250}, Max: {728, 600}} => width in [300,728] and height in [250,600]
+	Box struct {
+		Min []float64 `json:"min"`
+		Max []float64 `json:"max"`
+	}
+
+	// Point a N-dimensional point used for query
+	Point []float64
+)
+
+func NewBox(min, max []float64) Box {
+	return Box{Min: min, Max: max}
+}
+
+func (b Box) Dims() int {
+	return len(b.Min)
+}
+
+func (b Box) Valid() error {
+	if len(b.Min) == 0 || len(b.Min) != len(b.Max) {
+		return fmt.Errorf("bad box dimension, min:%v max:%v", b.Min, b.Max)
+	}
+	for i := range b.Min {
+		if b.Min[i] > b.Max[i] {
+			return fmt.Errorf("bad box, min:%v > max:%v at dimension:%d", b.Min[i], b.Max[i], i)
+		}
+	}
+	return nil
+}
+
+func (b Box) ContainPoint(p Point) bool {
+	for i, v := range p {
+		if v < b.Min[i] || v > b.Max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// center used for sorting boxes when bulk loading
+func (b Box) center(dim int) float64 {
+	return (b.Min[dim] + b.Max[dim]) / 2
+}
+
+// extend make the box large enough to hold other
+func (b *Box) extend(other Box) {
+	if len(b.Min) == 0 {
+		b.Min = append([]float64(nil), other.Min...)
+		b.Max = append([]float64(nil), other.Max...)
+		return
+	}
+	for i := range b.Min {
+		if other.Min[i] < b.Min[i] {
+			b.Min[i] = other.Min[i]
+		}
+		if other.Max[i] > b.Max[i] {
+			b.Max[i] = other.Max[i]
+		}
+	}
+}
+
+// String compact format: [300:728,250:600], also used as posting list key
+func (b Box) String() string {
+	parts := make([]string, 0, len(b.Min))
+	for i := range b.Min {
+		parts = append(parts, strconv.FormatFloat(b.Min[i], 'g', -1, 64)+":"+
+			strconv.FormatFloat(b.Max[i], 'g', -1, 64))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// ParseBoxes parse expression value into boxes, support: Box, *Box, []Box, []*Box
+func ParseBoxes(v interface{}) ([]Box, error) {
+	var boxes []Box
+	switch tv := v.(type) {
+	case Box:
+		boxes = []Box{tv}
+	case *Box:
+		boxes = []Box{*tv}
+	case []Box:
+		boxes = tv
+	case []*Box:
+		for _, b := range tv {
+			boxes = append(boxes, *b)
+		}
+	default:
+		return nil, fmt.Errorf("box holder need Box/[]Box value, value:%+v", v)
+	}
+	for _, b := range boxes {
+		if err := b.Valid(); err != nil {
+			return nil, err
+		}
+	}
+	return boxes, nil
+}
+
+// ParsePoints parse query assign into points, support:
+// single point: []int64/[]float64/[]int/[]interface{} ; multi points: [][]int64/[][]float64/[]Point
+func ParsePoints(v interface{}) ([]Point, error) {
+	switch tv := v.(type) {
+	case Point:
+		return []Point{tv}, nil
+	case []Point:
+		return tv, nil
+	case []float64:
+		return []Point{tv}, nil
+	case [][]float64:
+		res := make([]Point, 0, len(tv))
+		for _, p := range tv {
+			res = append(res, p)
+		}
+		return res, nil
+	case [][]int64:
+		res := make([]Point, 0, len(tv))
+		for _, p := range tv {
+			pt, err := parsePoint(p)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, pt)
+		}
+		return res, nil
+	default:
+		pt, err := parsePoint(v)
+		if err != nil {
+			return nil, err
+		}
+		return []Point{pt}, nil
+	}
+}
+
+func parsePoint(v interface{}) (Point, error) {
+	values, err := parser.ValuesToStrings(v)
+	if err != nil {
+		return nil, fmt.Errorf("bad point value:%+v, err:%v", v, err)
+	}
+	pt := make(Point, 0, len(values))
+	for _, s := range values {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad point value:%+v, err:%v", v, err)
+		}
+		pt = append(pt, f)
+	}
+	return pt, nil
+}
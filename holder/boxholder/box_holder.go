@@ -0,0 +1,201 @@
+package boxholder
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/util"
+)
+
+type (
+	BoxHolderOption struct {
+		// Dims dimension of boxes and query points, zero means decided by the first indexed box
+		Dims int
+
+		// NodeCapacity max children count of R-tree node, default: 16
+		NodeCapacity int
+	}
+
+	// BoxEntriesHolder index N-dimensional boxes(range conditions on correlated dimensions)
+	// with a static R-tree, eg: creative size(width × height), geographic bounding box,
+	// (price, quality) window; it answers point query given as []int64/[]float64
+	BoxEntriesHolder struct {
+		BoxHolderOption
+
+		debug  bool
+		logger StructuredLogger // injected by index, see LoggerSetter
+		maxLen int64            // max length of Entries
+		avgLen int64            // avg length of Entries
+
+		boxes map[string]*boxEntries
+		root  *rtreeNode
+	}
+
+	BoxTxData struct {
+		Boxes []Box `json:"boxes"`
+	}
+)
+
+func init() {
+	RegisterEntriesHolder(HolderNameBoxRange, func() EntriesHolder {
+		return NewBoxEntriesHolder(BoxHolderOption{})
+	})
+}
+
+func NewBoxEntriesHolder(option BoxHolderOption) *BoxEntriesHolder {
+	if option.NodeCapacity <= 1 {
+		option.NodeCapacity = defaultNodeCapacity
+	}
+	return &BoxEntriesHolder{
+		BoxHolderOption: option,
+		boxes:           map[string]*boxEntries{},
+	}
+}
+
+func (txd *BoxTxData) Encode() ([]byte, error) {
+	return json.Marshal(txd)
+}
+
+func (h *BoxEntriesHolder) DecodeFieldIndexingData(data []byte) (IndexingData, error) {
+	var txd BoxTxData
+	err := json.Unmarshal(data, &txd)
+	return &txd, err
+}
+
+func (h *BoxEntriesHolder) EnableDebug(debug bool) {
+	h.debug = debug
+}
+
+// SetLogger implement LoggerSetter, debug message logged by it
+func (h *BoxEntriesHolder) SetLogger(logger StructuredLogger) {
+	h.logger = logger
+}
+
+func (h *BoxEntriesHolder) DumpInfo(buffer *strings.Builder) {
+	summary := map[string]interface{}{
+		"name":          HolderNameBoxRange,
+		"dims":          h.Dims,
+		"boxCnt":        len(h.boxes),
+		"treeDepth":     h.root.depth(),
+		"maxEntriesLen": h.maxLen,
+		"avgEntriesLen": h.avgLen,
+	}
+	buffer.WriteString(util.JSONPretty(summary))
+}
+
+func (h *BoxEntriesHolder) DumpEntries(buffer *strings.Builder) {
+	buffer.WriteString("BoxEntriesHolder entries:")
+	for key, be := range h.boxes {
+		buffer.WriteString("\n")
+		buffer.WriteString(key)
+		buffer.WriteString(":")
+		buffer.WriteString(strings.Join(be.entries.DocString(), ","))
+	}
+}
+
+func (h *BoxEntriesHolder) BuildFieldIndexingData(field *FieldDesc, bv *BoolValues) (IndexingData, error) {
+	util.PanicIf(bv.Operator != ValueOptEQ, "box_range container support EQ operator only")
+
+	boxes, err := ParseBoxes(bv.Value)
+	if err != nil {
+		return nil, fmt.Errorf("field:%s value:%+v parse fail, err:%v", field.Field, bv, err)
+	}
+	// validate all boxes before deciding dimension, a failed expression leave holder untouched
+	dims := h.Dims
+	unique := make([]Box, 0, len(boxes))
+	seen := make(map[string]struct{}, len(boxes))
+	for _, box := range boxes {
+		if dims == 0 {
+			dims = box.Dims()
+		}
+		if box.Dims() != dims {
+			return nil, fmt.Errorf("field:%s box:%s dimension not match, need:%d", field.Field, box.String(), dims)
+		}
+		if _, ok := seen[box.String()]; ok {
+			continue
+		}
+		seen[box.String()] = struct{}{}
+		unique = append(unique, box)
+	}
+	h.Dims = dims
+	return &BoxTxData{Boxes: unique}, nil
+}
+
+func (h *BoxEntriesHolder) CommitFieldIndexingData(tx FieldIndexingData) error {
+	if tx.Data == nil {
+		return nil
+	}
+	data, ok := tx.Data.(*BoxTxData)
+	if !ok {
+		return fmt.Errorf("invalid Tx.Data type")
+	}
+	for _, box := range data.Boxes {
+		key := box.String()
+		be, ok := h.boxes[key]
+		if !ok {
+			be = &boxEntries{box: box}
+			h.boxes[key] = be
+		}
+		// same box of a conjunction committed more than once(duplicated in tx or expressions)
+		if n := len(be.entries); n > 0 && be.entries[n-1] == tx.EID {
+			continue
+		}
+		be.entries = append(be.entries, tx.EID)
+	}
+	return nil
+}
+
+func (h *BoxEntriesHolder) CompileEntries() error {
+	var total int64
+	items := make([]*boxEntries, 0, len(h.boxes))
+	for _, be := range h.boxes {
+		if h.Dims == 0 {
+			h.Dims = be.box.Dims()
+		}
+		if be.box.Dims() != h.Dims {
+			return fmt.Errorf("box:%s dimension not match, need:%d", be.box.String(), h.Dims)
+		}
+		sort.Sort(be.entries)
+		if h.maxLen < int64(len(be.entries)) {
+			h.maxLen = int64(len(be.entries))
+		}
+		total += int64(len(be.entries))
+		items = append(items, be)
+	}
+	if len(items) > 0 {
+		h.avgLen = total / int64(len(items))
+	}
+	h.root = buildRTree(items, h.Dims, h.NodeCapacity)
+	return nil
+}
+
+func (h *BoxEntriesHolder) GetEntries(field *FieldDesc, assigns Values) (r EntriesCursors, e error) {
+	if h.root == nil {
+		return nil, nil
+	}
+	var points []Point
+	if points, e = ParsePoints(assigns); e != nil {
+		return nil, e
+	}
+	matched := map[*boxEntries]struct{}{}
+	for _, p := range points {
+		if len(p) != h.Dims {
+			return nil, fmt.Errorf("field:%s query point:%v dimension not match, need:%d", field.Field, p, h.Dims)
+		}
+		h.root.search(p, func(item *boxEntries) {
+			if _, ok := matched[item]; ok {
+				return
+			}
+			matched[item] = struct{}{}
+			if h.debug {
+				LoggerOrDefault(h.logger).Debug("box contain point",
+					"box", item.box.String(), "point", p, "entries", len(item.entries))
+			}
+			r = append(r, NewEntriesCursor(NewQKey(field.Field, item.box), item.entries))
+		})
+	}
+	return r, nil
+}
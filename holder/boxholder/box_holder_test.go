@@ -0,0 +1,171 @@
+package boxholder
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuildRTree_Search(t *testing.T) {
+	convey.Convey("test r-tree search result same as brute force", t, func() {
+		rd := rand.New(rand.NewSource(7))
+		var items []*boxEntries
+		for i := 0; i < 1000; i++ {
+			box := Box{Min: make([]float64, 3), Max: make([]float64, 3)}
+			for d := 0; d < 3; d++ {
+				box.Min[d] = float64(rd.Intn(1000))
+				box.Max[d] = box.Min[d] + float64(rd.Intn(200))
+			}
+			items = append(items, &boxEntries{box: box, entries: Entries{EntryID(i)}})
+		}
+		root := buildRTree(items, 3, 8)
+		convey.So(root.depth(), convey.ShouldBeGreaterThan, 1)
+
+		for i := 0; i < 500; i++ {
+			p := Point{float64(rd.Intn(1200)), float64(rd.Intn(1200)), float64(rd.Intn(1200))}
+			var expect, got []int
+			for _, item := range items {
+				if item.box.ContainPoint(p) {
+					expect = append(expect, int(item.entries[0]))
+				}
+			}
+			root.search(p, func(item *boxEntries) {
+				got = append(got, int(item.entries[0]))
+			})
+			sort.Ints(got)
+			sort.Ints(expect)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+	})
+}
+
+func TestBoxEntriesHolder_GetEntries(t *testing.T) {
+	convey.Convey("test box holder dimension check", t, func() {
+		holder := NewBoxEntriesHolder(BoxHolderOption{Dims: 2})
+		field := &FieldDesc{ID: 0, Field: "size"}
+		_, err := holder.BuildFieldIndexingData(field, &BoolValues{Value: NewBox([]float64{1}, []float64{2}), Incl: true})
+		convey.So(err, convey.ShouldNotBeNil)
+		_, err = holder.BuildFieldIndexingData(field, &BoolValues{Value: NewBox([]float64{3, 1}, []float64{2, 2}), Incl: true})
+		convey.So(err, convey.ShouldNotBeNil)
+
+		data, err := holder.BuildFieldIndexingData(field, &BoolValues{Value: NewBox([]float64{1, 1}, []float64{2, 2}), Incl: true})
+		convey.So(err, convey.ShouldBeNil)
+		tx := FieldIndexingData{EID: NewEntryID(NewConjID(1, 0, 1), true), Data: data}
+		convey.So(holder.CommitFieldIndexingData(tx), convey.ShouldBeNil)
+		convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+
+		cursors, err := holder.GetEntries(field, []int64{1, 2})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(cursors), convey.ShouldEqual, 1)
+		key := cursors[0].Key()
+		convey.So(key.String(), convey.ShouldEqual, "[size,[1:2,1:2]]")
+
+		_, err = holder.GetEntries(field, []int64{1, 2, 3})
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+func TestBoxEntriesHolder_BuildFieldIndexingData(t *testing.T) {
+	convey.Convey("test failed build leave dimension undecided", t, func() {
+		holder := NewBoxEntriesHolder(BoxHolderOption{})
+		field := &FieldDesc{ID: 0, Field: "size"}
+		_, err := holder.BuildFieldIndexingData(field, &BoolValues{Value: []Box{
+			NewBox([]float64{1, 1}, []float64{2, 2}),
+			NewBox([]float64{1}, []float64{2}),
+		}, Incl: true})
+		convey.So(err, convey.ShouldNotBeNil)
+		convey.So(holder.Dims, convey.ShouldEqual, 0)
+
+		_, err = holder.BuildFieldIndexingData(field, &BoolValues{Value: NewBox([]float64{1}, []float64{2}), Incl: true})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(holder.Dims, convey.ShouldEqual, 1)
+	})
+
+	convey.Convey("test duplicated boxes of conjunction indexed once", t, func() {
+		holder := NewBoxEntriesHolder(BoxHolderOption{})
+		field := &FieldDesc{ID: 0, Field: "size"}
+		box := NewBox([]float64{1, 1}, []float64{2, 2})
+		data, err := holder.BuildFieldIndexingData(field, &BoolValues{Value: []Box{box, box}, Incl: true})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(data.(*BoxTxData).Boxes), convey.ShouldEqual, 1)
+
+		eid := NewEntryID(NewConjID(1, 0, 1), true)
+		convey.So(holder.CommitFieldIndexingData(FieldIndexingData{EID: eid, Data: data}), convey.ShouldBeNil)
+		// same box in another expression of the conjunction
+		convey.So(holder.CommitFieldIndexingData(FieldIndexingData{EID: eid, Data: data}), convey.ShouldBeNil)
+		convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+		convey.So(holder.boxes[box.String()].entries, convey.ShouldResemble, Entries{eid})
+
+		builder := NewIndexerBuilder()
+		builder.ConfigField("size", FieldOption{Container: HolderNameBoxRange})
+		convey.So(builder.AddDocument(NewDocument(1).AddConjunction(
+			NewConjunction().In("size", []Box{box, box}).In("size", box))), convey.ShouldBeNil)
+		ids, err := builder.BuildIndex().Retrieve(Assignments{"size": []int64{1, 2}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1})
+	})
+}
+
+func TestBoxEntriesHolder_Retrieve(t *testing.T) {
+	builder := NewIndexerBuilder()
+	builder.ConfigField("size", FieldOption{
+		Container: HolderNameBoxRange,
+	})
+
+	doc := NewDocument(1)
+	doc.AddConjunction(NewConjunction().In("size", []Box{
+		NewBox([]float64{300, 250}, []float64{728, 600}),
+		NewBox([]float64{1000, 90}, []float64{1200, 120}),
+	}))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(2)
+	doc.AddConjunction(NewConjunction().
+		In("size", NewBox([]float64{320, 50}, []float64{320, 50})).
+		In("os", NewStrValues("android")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(3)
+	doc.AddConjunction(NewConjunction().NotIn("size", NewBox([]float64{0, 0}, []float64{400, 400})))
+	_ = builder.AddDocument(doc)
+
+	convey.Convey("test box holder retrieve", t, func() {
+		indexer := builder.BuildIndex()
+
+		ids, err := indexer.Retrieve(Assignments{"size": []int64{500, 500}})
+		convey.So(err, convey.ShouldBeNil)
+		sort.Sort(ids)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1, 3})
+
+		ids, err = indexer.Retrieve(Assignments{"size": []int64{320, 50}, "os": "android"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{2})
+
+		ids, err = indexer.Retrieve(Assignments{"size": [][]int64{{320, 300}, {1100, 100}}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1})
+	})
+}
+
+func TestBoxEntriesHolder_ResultCache(t *testing.T) {
+	convey.Convey("test result cache keep coordinates order of box point", t, func() {
+		builder := NewIndexerBuilder()
+		builder.ConfigField("size", FieldOption{Container: HolderNameBoxRange})
+		doc := NewDocument(1).AddConjunction(NewConjunction().In("size", NewBox([]float64{300, 250}, []float64{728, 600})))
+		convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		index := builder.BuildIndex()
+		cache := NewResultCache(index)
+
+		for _, point := range [][]int64{{300, 250}, {250, 300}} {
+			expect, err := index.Retrieve(Assignments{"size": point})
+			convey.So(err, convey.ShouldBeNil)
+			result, err := cache.Retrieve(Assignments{"size": point})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(result, convey.ShouldResemble, expect)
+		}
+		convey.So(cache.Stats().Misses, convey.ShouldEqual, 2)
+	})
+}
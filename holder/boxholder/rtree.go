@@ -0,0 +1,119 @@
+package boxholder
+
+import (
+	"math"
+	"sort"
+
+	. "github.com/echoface/be_indexer"
+)
+
+const (
+	// defaultNodeCapacity max children/items count of a R-tree node
+	defaultNodeCapacity = 16
+)
+
+type (
+	// boxEntries a distinct box and the posting list of entries indexed on it
+	boxEntries struct {
+		box     Box
+		entries Entries
+	}
+
+	// rtreeNode a static R-tree node, it's bulk loaded by Sort-Tile-Recursive(STR) algorithm
+	rtreeNode struct {
+		bound    Box
+		children []*rtreeNode
+		items    []*boxEntries // only leaf node has items
+	}
+)
+
+// buildRTree bulk load all boxes into a static R-tree
+func buildRTree(items []*boxEntries, dims, capacity int) *rtreeNode {
+	if len(items) == 0 {
+		return nil
+	}
+	var nodes []*rtreeNode
+	for _, group := range strTiles(len(items), dims, capacity, func(i int) Box { return items[i].box }) {
+		node := &rtreeNode{}
+		for _, idx := range group {
+			node.items = append(node.items, items[idx])
+			node.bound.extend(items[idx].box)
+		}
+		nodes = append(nodes, node)
+	}
+	for len(nodes) > 1 {
+		level := nodes
+		nodes = nil
+		for _, group := range strTiles(len(level), dims, capacity, func(i int) Box { return level[i].bound }) {
+			node := &rtreeNode{}
+			for _, idx := range group {
+				node.children = append(node.children, level[idx])
+				node.bound.extend(level[idx].bound)
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes[0]
+}
+
+// strTiles group n boxes into tiles with at most capacity boxes, boxes in a tile are spatially close
+func strTiles(n, dims, capacity int, boxOf func(i int) Box) [][]int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var tiles [][]int
+	var tile func(idx []int, dim int)
+	tile = func(idx []int, dim int) {
+		sort.Slice(idx, func(i, j int) bool {
+			return boxOf(idx[i]).center(dim) < boxOf(idx[j]).center(dim)
+		})
+		if dim == dims-1 {
+			for start := 0; start < len(idx); start += capacity {
+				end := start + capacity
+				if end > len(idx) {
+					end = len(idx)
+				}
+				tiles = append(tiles, idx[start:end])
+			}
+			return
+		}
+		pages := math.Ceil(float64(len(idx)) / float64(capacity))
+		slabs := int(math.Ceil(math.Pow(pages, 1/float64(dims-dim))))
+		slabSize := capacity * int(math.Ceil(pages/float64(slabs)))
+		for start := 0; start < len(idx); start += slabSize {
+			end := start + slabSize
+			if end > len(idx) {
+				end = len(idx)
+			}
+			tile(idx[start:end], dim+1)
+		}
+	}
+	tile(indexes, 0)
+	return tiles
+}
+
+// search collect all boxes contain the point
+func (n *rtreeNode) search(p Point, fn func(item *boxEntries)) {
+	if n == nil || !n.bound.ContainPoint(p) {
+		return
+	}
+	for _, item := range n.items {
+		if item.box.ContainPoint(p) {
+			fn(item)
+		}
+	}
+	for _, child := range n.children {
+		child.search(p, fn)
+	}
+}
+
+func (n *rtreeNode) depth() int {
+	if n == nil {
+		return 0
+	}
+	if len(n.children) == 0 {
+		return 1
+	}
+	return n.children[0].depth() + 1
+}
@@ -0,0 +1,203 @@
+package fuzzyholder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/codegen/cache"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/echoface/be_indexer/util"
+	"google.golang.org/protobuf/proto"
+)
+
+type (
+	FuzzyHolderOption struct {
+		// MaxDistance max Levenshtein distance between query value and indexed value, zero means
+		// exact match; the holder registered as HolderNameFuzzyMatch use 1
+		MaxDistance int
+
+		// Transposition count swapping two adjacent runes as one edit(Damerau/OSA distance),
+		// eg: "iphnoe" => "iphone" distance is 1 instead of 2
+		Transposition bool
+
+		// CaseFolding lower-case both indexed values and query values
+		CaseFolding bool
+	}
+
+	// FuzzyEntriesHolder typo-tolerant string holder, it returns posting lists for every
+	// indexed value within MaxDistance of the query value
+	FuzzyEntriesHolder struct {
+		FuzzyHolderOption
+
+		debug  bool
+		logger StructuredLogger // injected by index, see LoggerSetter
+		maxLen int64            // max length of Entries
+		avgLen int64            // avg length of Entries
+
+		values map[string]Entries
+		trie   *termTrie
+	}
+
+	FuzzyTxData struct {
+		Values cache.StrListValues
+	}
+
+	// FuzzyTerm the value of QKey for matched posting list, used for explain/debugging
+	FuzzyTerm struct {
+		Term     string // indexed value
+		Query    string // query value
+		Distance int
+	}
+)
+
+func init() {
+	RegisterEntriesHolder(HolderNameFuzzyMatch, func() EntriesHolder {
+		return NewFuzzyEntriesHolder(FuzzyHolderOption{MaxDistance: 1, Transposition: true})
+	})
+}
+
+func NewFuzzyEntriesHolder(option FuzzyHolderOption) *FuzzyEntriesHolder {
+	if option.MaxDistance < 0 {
+		option.MaxDistance = 0
+	}
+	return &FuzzyEntriesHolder{
+		FuzzyHolderOption: option,
+		values:            map[string]Entries{},
+		trie:              newTermTrie(),
+	}
+}
+
+func (ft FuzzyTerm) String() string {
+	return fmt.Sprintf("%s~%d(%s)", ft.Term, ft.Distance, ft.Query)
+}
+
+func (txd *FuzzyTxData) Encode() ([]byte, error) {
+	return proto.Marshal(&txd.Values)
+}
+
+func (h *FuzzyEntriesHolder) DecodeFieldIndexingData(data []byte) (IndexingData, error) {
+	txData := &FuzzyTxData{}
+	if len(data) == 0 {
+		return txData, nil
+	}
+	err := proto.Unmarshal(data, &txData.Values)
+	return txData, err
+}
+
+func (h *FuzzyEntriesHolder) EnableDebug(debug bool) {
+	h.debug = debug
+}
+
+// SetLogger implement LoggerSetter, debug message logged by it
+func (h *FuzzyEntriesHolder) SetLogger(logger StructuredLogger) {
+	h.logger = logger
+}
+
+func (h *FuzzyEntriesHolder) DumpInfo(buffer *strings.Builder) {
+	summary := map[string]interface{}{
+		"name":          HolderNameFuzzyMatch,
+		"termCnt":       len(h.values),
+		"maxEntriesLen": h.maxLen,
+		"avgEntriesLen": h.avgLen,
+		"maxDistance":   h.MaxDistance,
+		"transposition": h.Transposition,
+		"caseFolding":   h.CaseFolding,
+	}
+	buffer.WriteString(util.JSONPretty(summary))
+}
+
+func (h *FuzzyEntriesHolder) DumpEntries(buffer *strings.Builder) {
+	buffer.WriteString("FuzzyEntriesHolder entries:")
+	for term, entries := range h.values {
+		buffer.WriteString("\n")
+		buffer.WriteString(term)
+		buffer.WriteString(":")
+		buffer.WriteString(strings.Join(entries.DocString(), ","))
+	}
+}
+
+func (h *FuzzyEntriesHolder) normalize(values []string) []string {
+	if !h.CaseFolding {
+		return values
+	}
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		res = append(res, strings.ToLower(v))
+	}
+	return res
+}
+
+func (h *FuzzyEntriesHolder) BuildFieldIndexingData(field *FieldDesc, bv *BoolValues) (IndexingData, error) {
+	util.PanicIf(bv.Operator != ValueOptEQ, "fuzzy_match container support EQ operator only")
+
+	values, err := parser.ValuesToStrings(bv.Value)
+	if err != nil {
+		return nil, fmt.Errorf("field:%s value:%+v parse fail, err:%v", field.Field, bv, err)
+	}
+	values = util.DistinctString(h.normalize(values))
+	return &FuzzyTxData{Values: cache.StrListValues{Values: values}}, nil
+}
+
+func (h *FuzzyEntriesHolder) CommitFieldIndexingData(tx FieldIndexingData) error {
+	if tx.Data == nil {
+		return nil
+	}
+	data, ok := tx.Data.(*FuzzyTxData)
+	if !ok {
+		return fmt.Errorf("invalid Tx.Data type")
+	}
+	for _, v := range data.Values.GetValues() {
+		h.values[v] = append(h.values[v], tx.EID)
+	}
+	return nil
+}
+
+// CompileEntries sort the term dictionary and build the trie used by Levenshtein search
+func (h *FuzzyEntriesHolder) CompileEntries() error {
+	terms := make([]string, 0, len(h.values))
+	for term := range h.values {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	var total int64
+	h.trie = newTermTrie()
+	for _, term := range terms {
+		entries := h.values[term]
+		sort.Sort(entries)
+		if h.maxLen < int64(len(entries)) {
+			h.maxLen = int64(len(entries))
+		}
+		total += int64(len(entries))
+		h.trie.insert(term, entries)
+	}
+	if len(terms) > 0 {
+		h.avgLen = total / int64(len(terms))
+	}
+	return nil
+}
+
+func (h *FuzzyEntriesHolder) GetEntries(field *FieldDesc, assigns Values) (r EntriesCursors, e error) {
+	var queries []string
+	if queries, e = parser.ValuesToStrings(assigns); e != nil {
+		return nil, e
+	}
+	matched := map[*trieNode]FuzzyTerm{}
+	for _, query := range h.normalize(queries) {
+		for node, distance := range h.trie.search(query, h.MaxDistance, h.Transposition) {
+			if hit, ok := matched[node]; ok && hit.Distance <= distance {
+				continue
+			}
+			matched[node] = FuzzyTerm{Term: node.term, Query: query, Distance: distance}
+		}
+	}
+	for node, term := range matched {
+		if h.debug {
+			LoggerOrDefault(h.logger).Debug("fuzzy match", "term", term.String(), "entries", len(node.entries))
+		}
+		r = append(r, NewEntriesCursor(NewQKey(field.Field, term), node.entries))
+	}
+	return r, nil
+}
@@ -0,0 +1,86 @@
+package fuzzyholder
+
+import (
+	"sort"
+	"testing"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestFuzzyEntriesHolder_GetEntries(t *testing.T) {
+	convey.Convey("test fuzzy holder expose distance in QKey", t, func() {
+		holder := NewFuzzyEntriesHolder(FuzzyHolderOption{MaxDistance: 1, Transposition: true, CaseFolding: true})
+		field := &FieldDesc{ID: 0, Field: "query"}
+		data, err := holder.BuildFieldIndexingData(field, &BoolValues{Value: NewStrValues("iPhone", "ipad"), Incl: true})
+		convey.So(err, convey.ShouldBeNil)
+		tx := FieldIndexingData{EID: NewEntryID(NewConjID(1, 0, 1), true), Data: data}
+		convey.So(holder.CommitFieldIndexingData(tx), convey.ShouldBeNil)
+		convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+
+		cursors, err := holder.GetEntries(field, "IPHNOE")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(cursors), convey.ShouldEqual, 1)
+		key := cursors[0].Key()
+		convey.So(key.Value(), convey.ShouldResemble, FuzzyTerm{Term: "iphone", Query: "iphnoe", Distance: 1})
+		convey.So(key.String(), convey.ShouldEqual, "[query,iphone~1(iphnoe)]")
+
+		cursors, err = holder.GetEntries(field, NewStrValues("android", "samsung"))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(cursors), convey.ShouldEqual, 0)
+	})
+}
+
+func TestFuzzyEntriesHolder_ZeroDistance(t *testing.T) {
+	convey.Convey("test fuzzy holder with zero MaxDistance only match exactly", t, func() {
+		holder := NewFuzzyEntriesHolder(FuzzyHolderOption{})
+		field := &FieldDesc{ID: 0, Field: "query"}
+		data, err := holder.BuildFieldIndexingData(field, &BoolValues{Value: NewStrValues("iphone"), Incl: true})
+		convey.So(err, convey.ShouldBeNil)
+		tx := FieldIndexingData{EID: NewEntryID(NewConjID(1, 0, 1), true), Data: data}
+		convey.So(holder.CommitFieldIndexingData(tx), convey.ShouldBeNil)
+		convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+
+		cursors, err := holder.GetEntries(field, "iphnoe")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(cursors), convey.ShouldEqual, 0)
+
+		cursors, err = holder.GetEntries(field, "iphone")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(cursors), convey.ShouldEqual, 1)
+	})
+}
+
+func TestFuzzyEntriesHolder_Retrieve(t *testing.T) {
+	builder := NewIndexerBuilder()
+	builder.ConfigField("query", FieldOption{
+		Container: HolderNameFuzzyMatch,
+	})
+
+	doc := NewDocument(1)
+	doc.AddConjunction(NewConjunction().In("query", NewStrValues("iphone", "apple")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(2)
+	doc.AddConjunction(NewConjunction().
+		In("query", NewStrValues("samsung")).
+		In("os", NewStrValues("android")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(3)
+	doc.AddConjunction(NewConjunction().NotIn("query", NewStrValues("ipad")))
+	_ = builder.AddDocument(doc)
+
+	convey.Convey("test fuzzy holder retrieve", t, func() {
+		indexer := builder.BuildIndex()
+
+		ids, err := indexer.Retrieve(Assignments{"query": "iphnoe"})
+		convey.So(err, convey.ShouldBeNil)
+		sort.Sort(ids)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1, 3})
+
+		ids, err = indexer.Retrieve(Assignments{"query": NewStrValues("samsumg", "ipd"), "os": "android"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{2})
+	})
+}
@@ -0,0 +1,124 @@
+package fuzzyholder
+
+import (
+	"sort"
+
+	. "github.com/echoface/be_indexer"
+)
+
+type (
+	// termTrie a trie built from the sorted term dictionary; searching it with a
+	// Levenshtein DP row per node simulates a Levenshtein automaton and prunes
+	// every sub-tree that can't be within the max distance anymore
+	termTrie struct {
+		root *trieNode
+	}
+
+	trieNode struct {
+		char     rune
+		children []*trieNode // sorted by char
+
+		terminal bool
+		term     string
+		entries  Entries
+	}
+
+	// levenshteinSearch search state for one query
+	levenshteinSearch struct {
+		query         []rune
+		maxDistance   int
+		transposition bool
+
+		results map[*trieNode]int
+	}
+)
+
+func newTermTrie() *termTrie {
+	return &termTrie{root: &trieNode{}}
+}
+
+func (n *trieNode) child(c rune) *trieNode {
+	idx := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].char >= c
+	})
+	if idx < len(n.children) && n.children[idx].char == c {
+		return n.children[idx]
+	}
+	node := &trieNode{char: c}
+	n.children = append(n.children, nil)
+	copy(n.children[idx+1:], n.children[idx:])
+	n.children[idx] = node
+	return node
+}
+
+func (t *termTrie) insert(term string, entries Entries) {
+	node := t.root
+	for _, c := range term {
+		node = node.child(c)
+	}
+	node.terminal, node.term, node.entries = true, term, entries
+}
+
+// search return all terminal nodes within maxDistance and their distance
+func (t *termTrie) search(query string, maxDistance int, transposition bool) map[*trieNode]int {
+	s := &levenshteinSearch{
+		query:         []rune(query),
+		maxDistance:   maxDistance,
+		transposition: transposition,
+		results:       map[*trieNode]int{},
+	}
+	row := make([]int, len(s.query)+1)
+	for i := range row {
+		row[i] = i
+	}
+	if t.root.terminal && row[len(s.query)] <= maxDistance {
+		s.results[t.root] = row[len(s.query)]
+	}
+	for _, child := range t.root.children {
+		s.walk(child, row, nil, 0)
+	}
+	return s.results
+}
+
+func (s *levenshteinSearch) walk(node *trieNode, prev, prevPrev []int, prevChar rune) {
+	n := len(s.query)
+	cur := make([]int, n+1)
+	cur[0] = prev[0] + 1
+	rowMin := cur[0]
+	for j := 1; j <= n; j++ {
+		cost := 1
+		if s.query[j-1] == node.char {
+			cost = 0
+		}
+		cur[j] = minInt(cur[j-1]+1, prev[j]+1, prev[j-1]+cost)
+		if s.transposition && prevPrev != nil && j > 1 &&
+			s.query[j-1] == prevChar && s.query[j-2] == node.char {
+			cur[j] = minInt(cur[j], prevPrev[j-2]+1)
+		}
+		rowMin = minInt(rowMin, cur[j])
+	}
+	if node.terminal && cur[n] <= s.maxDistance {
+		s.results[node] = cur[n]
+	}
+
+	// for transposition, the next row can still reach a smaller value from this prev row
+	prevMin := s.maxDistance + 1
+	if s.transposition {
+		prevMin = minInt(prev[0], prev[1:]...) + 1
+	}
+	if rowMin > s.maxDistance && prevMin > s.maxDistance {
+		return
+	}
+	for _, child := range node.children {
+		s.walk(child, cur, prev, node.char)
+	}
+}
+
+func minInt(v int, others ...int) int {
+	for _, o := range others {
+		if o < v {
+			v = o
+		}
+	}
+	return v
+}
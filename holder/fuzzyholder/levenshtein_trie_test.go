@@ -0,0 +1,86 @@
+package fuzzyholder
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// bruteDistance optimal string alignment distance, used as reference
+func bruteDistance(a, b string, transposition bool) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if transposition && i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func TestTermTrie_Search(t *testing.T) {
+	convey.Convey("test levenshtein search on trie", t, func() {
+		trie := newTermTrie()
+		for _, term := range []string{"iphone", "iphone13", "ipad", "phone", "苹果手机"} {
+			trie.insert(term, nil)
+		}
+		find := func(query string, dist int, trans bool) map[string]int {
+			res := map[string]int{}
+			for node, d := range trie.search(query, dist, trans) {
+				res[node.term] = d
+			}
+			return res
+		}
+		convey.So(find("iphnoe", 1, false), convey.ShouldBeEmpty)
+		convey.So(find("iphnoe", 1, true), convey.ShouldResemble, map[string]int{"iphone": 1})
+		convey.So(find("iphone", 1, true), convey.ShouldResemble, map[string]int{"iphone": 0, "phone": 1})
+		convey.So(find("苹果手记", 1, false), convey.ShouldResemble, map[string]int{"苹果手机": 1})
+	})
+
+	convey.Convey("test levenshtein search agree with brute force", t, func() {
+		r := rand.New(rand.NewSource(7))
+		randWord := func() string {
+			w := make([]rune, r.Intn(7))
+			for i := range w {
+				w[i] = rune('a' + r.Intn(4))
+			}
+			return string(w)
+		}
+		terms := map[string]struct{}{}
+		trie := newTermTrie()
+		for i := 0; i < 300; i++ {
+			term := randWord()
+			terms[term] = struct{}{}
+			trie.insert(term, nil)
+		}
+		for i := 0; i < 200; i++ {
+			query, dist, trans := randWord(), r.Intn(3), r.Intn(2) == 0
+			expect := map[string]int{}
+			for term := range terms {
+				if d := bruteDistance(query, term, trans); d <= dist {
+					expect[term] = d
+				}
+			}
+			actual := map[string]int{}
+			for node, d := range trie.search(query, dist, trans) {
+				actual[node.term] = d
+			}
+			convey.So(actual, convey.ShouldResemble, expect)
+		}
+	})
+}
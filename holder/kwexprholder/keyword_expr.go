@@ -0,0 +1,224 @@
+package kwexprholder
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type (
+	// KeywordTerm a single word or a quoted phrase, tokenized into words
+	KeywordTerm []string
+
+	// KeywordExpr boolean keyword expression parsed from a string like: `running shoes ~nike -kids -"used shoes"`
+	//   - `word` or `+word`: required, must appear in text
+	//   - `~word`: optional, at least one optional term must appear if any optional term exist
+	//   - `-word`: negated, must not appear in text
+	//   - `"a b"`: phrase, words must appear consecutively; can be prefixed with +/~/-
+	KeywordExpr struct {
+		Required []KeywordTerm
+		Optional []KeywordTerm
+		Negated  []KeywordTerm
+	}
+
+	// Tokenizer split text into words, it's used for both expression and query text
+	Tokenizer func(text string) []string
+
+	// TextTokens tokenized query text with token positions, used to evaluate KeywordExpr
+	TextTokens struct {
+		tokens    []string
+		positions map[string][]int
+	}
+)
+
+const (
+	// tokenGap a sentinel token inserted between multi query values, avoid phrase matching across values
+	tokenGap = ""
+)
+
+// DefaultTokenizer lower-case the text, split words by none letter/digit runes,
+// and treat each CJK(Han/Hiragana/Katakana) rune as a single word
+func DefaultTokenizer(text string) []string {
+	var tokens []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// ParseKeywordExpr parse expression string into KeywordExpr
+func ParseKeywordExpr(expr string, tokenizer Tokenizer) (*KeywordExpr, error) {
+	segments, err := splitExprSegments(expr)
+	if err != nil {
+		return nil, err
+	}
+	result := &KeywordExpr{}
+	for _, seg := range segments {
+		prefix, content := byte('+'), seg
+		if content[0] == '+' || content[0] == '-' || content[0] == '~' {
+			prefix, content = content[0], content[1:]
+		}
+		content = strings.Trim(content, `"`)
+		term := KeywordTerm(tokenizer(content))
+		if len(term) == 0 {
+			return nil, fmt.Errorf("expression:%s has empty term:%s", expr, seg)
+		}
+		switch prefix {
+		case '-':
+			result.Negated = append(result.Negated, term)
+		case '~':
+			result.Optional = append(result.Optional, term)
+		default:
+			result.Required = append(result.Required, term)
+		}
+	}
+	if len(result.Required)+len(result.Optional)+len(result.Negated) == 0 {
+		return nil, fmt.Errorf("empty keyword expression:%s", expr)
+	}
+	return result, nil
+}
+
+// splitExprSegments split expression by whitespace, a quoted phrase will be kept as one segment
+func splitExprSegments(expr string) (segments []string, err error) {
+	var sb strings.Builder
+	quoted := false
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			sb.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if sb.Len() > 0 {
+				segments = append(segments, sb.String())
+				sb.Reset()
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unclosed quote in expression:%s", expr)
+	}
+	if sb.Len() > 0 {
+		segments = append(segments, sb.String())
+	}
+	return segments, nil
+}
+
+func (t KeywordTerm) String() string {
+	if len(t) == 1 {
+		return t[0]
+	}
+	return `"` + strings.Join(t, " ") + `"`
+}
+
+// String canonical format of the expression, two expression with same canonical string are equivalent
+func (e *KeywordExpr) String() string {
+	parts := make([]string, 0, len(e.Required)+len(e.Optional)+len(e.Negated))
+	for _, t := range e.Required {
+		parts = append(parts, "+"+t.String())
+	}
+	for _, t := range e.Optional {
+		parts = append(parts, "~"+t.String())
+	}
+	for _, t := range e.Negated {
+		parts = append(parts, "-"+t.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// AnchorTokens tokens that must appear in text when expression satisfied;
+// nil means the expression has negated terms only, it should always be evaluated
+func (e *KeywordExpr) AnchorTokens() []string {
+	if len(e.Required) > 0 {
+		return []string{e.Required[0][0]}
+	}
+	anchors := make([]string, 0, len(e.Optional))
+	for _, t := range e.Optional {
+		anchors = append(anchors, t[0])
+	}
+	return anchors
+}
+
+// Evaluate check whether the expression fully satisfied by text tokens
+func (e *KeywordExpr) Evaluate(text *TextTokens) bool {
+	for _, t := range e.Required {
+		if !text.Contain(t) {
+			return false
+		}
+	}
+	for _, t := range e.Negated {
+		if text.Contain(t) {
+			return false
+		}
+	}
+	if len(e.Optional) == 0 {
+		return true
+	}
+	for _, t := range e.Optional {
+		if text.Contain(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTextTokens tokenize each text and concat them with a gap token
+func NewTextTokens(texts []string, tokenizer Tokenizer) *TextTokens {
+	tt := &TextTokens{positions: map[string][]int{}}
+	for idx, text := range texts {
+		if idx > 0 {
+			tt.tokens = append(tt.tokens, tokenGap)
+		}
+		tt.tokens = append(tt.tokens, tokenizer(text)...)
+	}
+	for pos, token := range tt.tokens {
+		if token == tokenGap {
+			continue
+		}
+		tt.positions[token] = append(tt.positions[token], pos)
+	}
+	return tt
+}
+
+// Tokens return distinct tokens of text
+func (tt *TextTokens) Tokens() []string {
+	res := make([]string, 0, len(tt.positions))
+	for token := range tt.positions {
+		res = append(res, token)
+	}
+	return res
+}
+
+// Contain whether the term(word or phrase) appears in text
+func (tt *TextTokens) Contain(term KeywordTerm) bool {
+PosLoop:
+	for _, pos := range tt.positions[term[0]] {
+		if pos+len(term) > len(tt.tokens) {
+			return false
+		}
+		for i := 1; i < len(term); i++ {
+			if tt.tokens[pos+i] != term[i] {
+				continue PosLoop
+			}
+		}
+		return true
+	}
+	return false
+}
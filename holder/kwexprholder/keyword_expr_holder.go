@@ -0,0 +1,209 @@
+package kwexprholder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/codegen/cache"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/echoface/be_indexer/util"
+	"google.golang.org/protobuf/proto"
+)
+
+type (
+	KeywordExprHolderOption struct {
+		// Tokenizer used for tokenizing both keyword expression and query text, default: DefaultTokenizer
+		Tokenizer Tokenizer
+	}
+
+	// KeywordExprHolder hold boolean keyword expressions like `running shoes -kids`,
+	// GetEntries tokenize the query text and return EntriesCursors only for expressions
+	// fully satisfied by the text; each expression acts as a single value in conjunction counting
+	KeywordExprHolder struct {
+		KeywordExprHolderOption
+		debug  bool
+		logger StructuredLogger // injected by index, see LoggerSetter
+		maxLen int64            // max length of Entries
+		avgLen int64            // avg length of Entries
+
+		exprs map[string]*exprEntries // canonical expression => entries
+
+		anchors map[string][]*exprEntries // anchor token => candidate expressions
+		negOnly []*exprEntries            // expressions without positive terms, always be evaluated
+	}
+
+	exprEntries struct {
+		expr    *KeywordExpr
+		entries Entries
+	}
+
+	KeywordExprTxData struct {
+		Exprs cache.StrListValues
+	}
+)
+
+func init() {
+	RegisterEntriesHolder(HolderNameKeywordExpr, func() EntriesHolder {
+		return NewKeywordExprHolder(KeywordExprHolderOption{})
+	})
+}
+
+func NewKeywordExprHolder(option KeywordExprHolderOption) *KeywordExprHolder {
+	if option.Tokenizer == nil {
+		option.Tokenizer = DefaultTokenizer
+	}
+	return &KeywordExprHolder{
+		KeywordExprHolderOption: option,
+		exprs:                   map[string]*exprEntries{},
+		anchors:                 map[string][]*exprEntries{},
+	}
+}
+
+func (txd *KeywordExprTxData) Encode() ([]byte, error) {
+	return proto.Marshal(&txd.Exprs)
+}
+
+func (h *KeywordExprHolder) DecodeFieldIndexingData(data []byte) (IndexingData, error) {
+	txData := &KeywordExprTxData{}
+	if len(data) == 0 {
+		return txData, nil
+	}
+	err := proto.Unmarshal(data, &txData.Exprs)
+	return txData, err
+}
+
+func (h *KeywordExprHolder) EnableDebug(debug bool) {
+	h.debug = debug
+}
+
+// SetLogger implement LoggerSetter, debug message logged by it
+func (h *KeywordExprHolder) SetLogger(logger StructuredLogger) {
+	h.logger = logger
+}
+
+func (h *KeywordExprHolder) DumpInfo(buffer *strings.Builder) {
+	summary := map[string]interface{}{
+		"name":          HolderNameKeywordExpr,
+		"exprCnt":       len(h.exprs),
+		"anchorCnt":     len(h.anchors),
+		"negOnlyCnt":    len(h.negOnly),
+		"maxEntriesLen": h.maxLen,
+		"avgEntriesLen": h.avgLen,
+	}
+	buffer.WriteString(util.JSONPretty(summary))
+}
+
+func (h *KeywordExprHolder) DumpEntries(buffer *strings.Builder) {
+	buffer.WriteString("KeywordExprHolder entries:")
+	for key, ee := range h.exprs {
+		buffer.WriteString("\n")
+		buffer.WriteString(key)
+		buffer.WriteString(":")
+		buffer.WriteString(strings.Join(ee.entries.DocString(), ","))
+	}
+}
+
+func (h *KeywordExprHolder) BuildFieldIndexingData(field *FieldDesc, bv *BoolValues) (IndexingData, error) {
+	util.PanicIf(bv.Operator != ValueOptEQ, "keyword_expr container support EQ operator only")
+
+	values, err := parser.ValuesToStrings(bv.Value)
+	if err != nil {
+		return nil, fmt.Errorf("field:%s keyword expression need string value, err:%v", field.Field, err)
+	}
+	exprs := make([]string, 0, len(values))
+	for _, v := range values {
+		expr, err := ParseKeywordExpr(v, h.Tokenizer)
+		if err != nil {
+			return nil, fmt.Errorf("field:%s parse keyword expression fail, err:%v", field.Field, err)
+		}
+		exprs = append(exprs, expr.String())
+	}
+	return &KeywordExprTxData{Exprs: cache.StrListValues{Values: util.DistinctString(exprs)}}, nil
+}
+
+func (h *KeywordExprHolder) CommitFieldIndexingData(tx FieldIndexingData) error {
+	if tx.Data == nil {
+		return nil
+	}
+	data, ok := tx.Data.(*KeywordExprTxData)
+	if !ok {
+		return fmt.Errorf("invalid Tx.Data type")
+	}
+	for _, v := range data.Exprs.GetValues() {
+		ee, ok := h.exprs[v]
+		if !ok {
+			// canonical string is parsable and stable with the same tokenizer
+			expr, err := ParseKeywordExpr(v, h.Tokenizer)
+			if err != nil {
+				return err
+			}
+			ee = &exprEntries{expr: expr}
+			h.exprs[v] = ee
+		}
+		ee.entries = append(ee.entries, tx.EID)
+	}
+	return nil
+}
+
+func (h *KeywordExprHolder) CompileEntries() error {
+	var total int64
+	h.negOnly = h.negOnly[:0]
+	h.anchors = map[string][]*exprEntries{}
+	for _, ee := range h.exprs {
+		sort.Sort(ee.entries)
+		if h.maxLen < int64(len(ee.entries)) {
+			h.maxLen = int64(len(ee.entries))
+		}
+		total += int64(len(ee.entries))
+
+		anchors := ee.expr.AnchorTokens()
+		if len(anchors) == 0 {
+			h.negOnly = append(h.negOnly, ee)
+			continue
+		}
+		for _, token := range util.DistinctString(anchors) {
+			h.anchors[token] = append(h.anchors[token], ee)
+		}
+	}
+	if len(h.exprs) > 0 {
+		h.avgLen = total / int64(len(h.exprs))
+	}
+	return nil
+}
+
+func (h *KeywordExprHolder) GetEntries(field *FieldDesc, assigns Values) (r EntriesCursors, e error) {
+	if len(h.exprs) == 0 {
+		return nil, nil
+	}
+	var texts []string
+	if texts, e = parser.ValuesToStrings(assigns); e != nil {
+		return nil, e
+	}
+	text := NewTextTokens(texts, h.Tokenizer)
+
+	evaluated := make(map[*exprEntries]struct{})
+	evaluate := func(ee *exprEntries) {
+		if _, ok := evaluated[ee]; ok {
+			return
+		}
+		evaluated[ee] = struct{}{}
+		if !ee.expr.Evaluate(text) {
+			return
+		}
+		if h.debug {
+			LoggerOrDefault(h.logger).Debug("keyword expression satisfied", "expr", ee.expr.String())
+		}
+		r = append(r, NewEntriesCursor(NewQKey(field.Field, ee.expr.String()), ee.entries))
+	}
+	for _, token := range text.Tokens() {
+		for _, ee := range h.anchors[token] {
+			evaluate(ee)
+		}
+	}
+	for _, ee := range h.negOnly {
+		evaluate(ee)
+	}
+	return r, nil
+}
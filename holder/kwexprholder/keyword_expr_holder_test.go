@@ -0,0 +1,72 @@
+package kwexprholder
+
+import (
+	"sort"
+	"testing"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestKeywordExprHolder_Retrieve(t *testing.T) {
+	builder := NewIndexerBuilder()
+	builder.ConfigField("query", FieldOption{
+		Container: HolderNameKeywordExpr,
+	})
+
+	doc := NewDocument(1)
+	doc.AddConjunction(NewConjunction().
+		In("query", NewStrValues("running shoes -kids")).
+		In("os", NewStrValues("ios")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(2)
+	doc.AddConjunction(NewConjunction().
+		In("query", NewStrValues(`"trail running"`, "~nike ~adidas")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(3)
+	doc.AddConjunction(NewConjunction().
+		NotIn("query", NewStrValues("-shoes")).
+		In("os", NewStrValues("android")))
+	_ = builder.AddDocument(doc)
+
+	doc = NewDocument(4)
+	doc.AddConjunction(NewConjunction().
+		NotIn("query", NewStrValues("红包")))
+	_ = builder.AddDocument(doc)
+
+	convey.Convey("test keyword expression holder retrieve", t, func() {
+		indexer := builder.BuildIndex()
+
+		ids, err := indexer.Retrieve(Assignments{
+			"query": "cheap Running Shoes",
+			"os":    "ios",
+		})
+		convey.So(err, convey.ShouldBeNil)
+		sort.Sort(ids)
+		convey.So(ids, convey.ShouldResemble, DocIDList{1, 4})
+
+		ids, err = indexer.Retrieve(Assignments{
+			"query": "running shoes for kids",
+			"os":    "ios",
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{4})
+
+		ids, err = indexer.Retrieve(Assignments{
+			"query": NewStrValues("nike trail running", "抢红包"),
+			"os":    "android",
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, DocIDList{2})
+
+		ids, err = indexer.Retrieve(Assignments{
+			"query": "running shoes",
+			"os":    "android",
+		})
+		convey.So(err, convey.ShouldBeNil)
+		sort.Sort(ids)
+		convey.So(ids, convey.ShouldResemble, DocIDList{3, 4})
+	})
+}
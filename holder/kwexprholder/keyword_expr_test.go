@@ -0,0 +1,57 @@
+package kwexprholder
+
+import (
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseKeywordExpr(t *testing.T) {
+	convey.Convey("test parse keyword expression", t, func() {
+		expr, err := ParseKeywordExpr(`Running shoes ~nike -kids -"used shoes"`, DefaultTokenizer)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(expr.Required, convey.ShouldResemble, []KeywordTerm{{"running"}, {"shoes"}})
+		convey.So(expr.Optional, convey.ShouldResemble, []KeywordTerm{{"nike"}})
+		convey.So(expr.Negated, convey.ShouldResemble, []KeywordTerm{{"kids"}, {"used", "shoes"}})
+		convey.So(expr.String(), convey.ShouldEqual, `+running +shoes ~nike -kids -"used shoes"`)
+
+		again, err := ParseKeywordExpr(expr.String(), DefaultTokenizer)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(again, convey.ShouldResemble, expr)
+
+		expr, err = ParseKeywordExpr("领红包", DefaultTokenizer)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(expr.Required, convey.ShouldResemble, []KeywordTerm{{"领", "红", "包"}})
+
+		_, err = ParseKeywordExpr(`"running shoes`, DefaultTokenizer)
+		convey.So(err, convey.ShouldNotBeNil)
+		_, err = ParseKeywordExpr(" ", DefaultTokenizer)
+		convey.So(err, convey.ShouldNotBeNil)
+		_, err = ParseKeywordExpr("shoes -!!", DefaultTokenizer)
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+func TestKeywordExpr_Evaluate(t *testing.T) {
+	convey.Convey("test evaluate keyword expression", t, func() {
+		expr, _ := ParseKeywordExpr(`running shoes -kids -"used shoes"`, DefaultTokenizer)
+
+		text := NewTextTokens([]string{"Best running shoes for men"}, DefaultTokenizer)
+		convey.So(expr.Evaluate(text), convey.ShouldBeTrue)
+
+		text = NewTextTokens([]string{"running shoes for kids"}, DefaultTokenizer)
+		convey.So(expr.Evaluate(text), convey.ShouldBeFalse)
+
+		text = NewTextTokens([]string{"used shoes, good for running"}, DefaultTokenizer)
+		convey.So(expr.Evaluate(text), convey.ShouldBeFalse)
+
+		// phrase should not match across query values
+		expr, _ = ParseKeywordExpr(`"running shoes"`, DefaultTokenizer)
+		text = NewTextTokens([]string{"running", "shoes"}, DefaultTokenizer)
+		convey.So(expr.Evaluate(text), convey.ShouldBeFalse)
+
+		expr, _ = ParseKeywordExpr(`~nike ~adidas`, DefaultTokenizer)
+		convey.So(expr.Evaluate(NewTextTokens([]string{"adidas shoes"}, DefaultTokenizer)), convey.ShouldBeTrue)
+		convey.So(expr.Evaluate(NewTextTokens([]string{"puma shoes"}, DefaultTokenizer)), convey.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,302 @@
+package rangeholder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/echoface/be_indexer/util"
+)
+
+type RangeLayout string
+
+const (
+	// RangeLayoutValueExplosion all ranges exploded into point values, query by hash lookup
+	RangeLayoutValueExplosion RangeLayout = "value_explosion"
+	// RangeLayoutIntervalSplit ranges split into disjoint intervals, see RangeHolder
+	RangeLayoutIntervalSplit RangeLayout = "interval_split"
+	// RangeLayoutSegmentTree ranges indexed by segment tree, see OptimizedRangeHolder
+	RangeLayoutSegmentTree RangeLayout = "segment_tree"
+)
+
+// splitCostRatio interval split layout has cheaper query than segment tree,
+// so it's preferred while its postings size not exceed ratio times of segment tree's
+const splitCostRatio = 4
+
+type (
+	AdaptiveRangeOption struct {
+		RangeHolderOption
+
+		// MaxExplodeValues max count of posting entries when exploding all ranges into values
+		MaxExplodeValues float64
+
+		// MaxSplitRanges max count of ranges for interval split layout, it's O(N²) building
+		MaxSplitRanges int
+	}
+
+	// AdaptiveRangeStats statistics collected from committed data, used for choosing layout
+	AdaptiveRangeStats struct {
+		PointCnt       int     `json:"point_cnt"`
+		RangeCnt       int     `json:"range_cnt"`
+		ExplodedValues float64 `json:"exploded_values"` // posting entries count of value explosion
+		SplitEntries   int     `json:"split_entries"`   // posting entries count of interval split
+		TreeEntries    int     `json:"tree_entries"`    // estimated posting entries count of segment tree
+	}
+
+	// AdaptiveRangeHolder collect statistics when committing data and choose the
+	// best layout from value explosion/interval split/segment tree when compiling
+	AdaptiveRangeHolder struct {
+		AdaptiveRangeOption
+
+		debug  bool
+		logger StructuredLogger // injected by index, see LoggerSetter
+		stats  AdaptiveRangeStats
+		layout RangeLayout
+
+		points []pendingRange
+		ranges []pendingRange
+
+		holder EntriesHolder // holder of chosen layout, available after compiled
+	}
+)
+
+func init() {
+	RegisterEntriesHolder(HolderNameAdaptiveRange, func() EntriesHolder {
+		return NewAdaptiveRangeHolder(NewAdaptiveRangeOption())
+	})
+}
+
+func NewAdaptiveRangeOption() AdaptiveRangeOption {
+	return AdaptiveRangeOption{
+		RangeHolderOption: *NewRangeHolderOption(),
+		MaxExplodeValues:  65536,
+		MaxSplitRanges:    4096,
+	}
+}
+
+func NewAdaptiveRangeHolder(option AdaptiveRangeOption) *AdaptiveRangeHolder {
+	return &AdaptiveRangeHolder{
+		AdaptiveRangeOption: option,
+	}
+}
+
+// Layout return the layout chosen when compiling
+func (h *AdaptiveRangeHolder) Layout() RangeLayout {
+	return h.layout
+}
+
+func (h *AdaptiveRangeHolder) EnableDebug(debug bool) {
+	h.debug = debug
+	if h.holder != nil {
+		h.holder.EnableDebug(debug)
+	}
+}
+
+// SetLogger implement LoggerSetter, debug message logged by it
+func (h *AdaptiveRangeHolder) SetLogger(logger StructuredLogger) {
+	h.logger = logger
+	if setter, ok := h.holder.(LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
+func (h *AdaptiveRangeHolder) DumpInfo(buffer *strings.Builder) {
+	summary := map[string]interface{}{
+		"name":   "AdaptiveRangeHolder",
+		"layout": h.layout,
+		"stats":  h.stats,
+	}
+	buffer.WriteString(util.JSONPretty(summary))
+	if h.holder != nil {
+		buffer.WriteString("\nlayout holder:")
+		h.holder.DumpInfo(buffer)
+	}
+}
+
+func (h *AdaptiveRangeHolder) DumpEntries(buffer *strings.Builder) {
+	buffer.WriteString(fmt.Sprintf("AdaptiveRangeHolder layout:%s\n", h.layout))
+	if h.holder != nil {
+		h.holder.DumpEntries(buffer)
+	}
+}
+
+func (h *AdaptiveRangeHolder) DecodeFieldIndexingData(data []byte) (IndexingData, error) {
+	var txd RangeTxData
+	err := json.Unmarshal(data, &txd)
+	return &txd, err
+}
+
+// BuildFieldIndexingData keep range as it is, converting is decided when compiling
+func (h *AdaptiveRangeHolder) BuildFieldIndexingData(field *FieldDesc, values *BoolValues) (r IndexingData, e error) {
+	switch values.Operator {
+	case ValueOptEQ:
+		var ids []int64
+		if ids, e = parser.ParseIntegers(values.Value, h.EnableFloat2Int); e != nil {
+			return r, fmt.Errorf("field:%s value:%+v parse fail, err:%v", field.Field, values, e)
+		}
+		return &RangeTxData{EqValues: ids, Operator: ValueOptEQ}, nil
+	case ValueOptLT, ValueOptGT, ValueOptBetween:
+		rg, err := ParseRange(values.Operator, values.Value, h.EnableFloat2Int)
+		if err != nil {
+			return r, err
+		}
+		return &RangeTxData{Operator: ValueOptBetween, RgValue: rg}, nil
+	default:
+		break
+	}
+	return nil, fmt.Errorf("unsupport Operator:%d", values.Operator)
+}
+
+func (h *AdaptiveRangeHolder) CommitFieldIndexingData(tx FieldIndexingData) error {
+	if tx.Data == nil {
+		return nil
+	}
+	data, ok := tx.Data.(*RangeTxData)
+	if !ok {
+		return fmt.Errorf("invalid Tx.Data type")
+	}
+	switch data.Operator {
+	case ValueOptEQ:
+		values := util.DistinctInteger(data.EqValues)
+		for _, id := range values {
+			h.points = append(h.points, pointRange(id, tx.EID))
+		}
+		h.stats.ExplodedValues += float64(len(values))
+	case ValueOptGT, ValueOptLT, ValueOptBetween:
+		rg := data.RgValue
+		h.ranges = append(h.ranges, pendingRange{left: rg.left, right: rg.right, eid: tx.EID})
+		h.stats.ExplodedValues += rg.Size()
+	default:
+		return fmt.Errorf("unsupport Operator:%d", data.Operator)
+	}
+	return nil
+}
+
+func (h *AdaptiveRangeHolder) CompileEntries() error {
+	h.stats.PointCnt, h.stats.RangeCnt = len(h.points), len(h.ranges)
+	h.stats.SplitEntries, h.stats.TreeEntries = h.estimatePostings()
+	h.layout = h.chooseLayout()
+	if h.debug {
+		LoggerOrDefault(h.logger).Info("adaptive range holder choose layout", "layout", h.layout, "stats", h.stats)
+	}
+
+	var err error
+	switch h.layout {
+	case RangeLayoutValueExplosion:
+		h.holder, err = h.buildValueExplosion()
+	case RangeLayoutIntervalSplit:
+		h.holder, err = h.buildIntervalSplit()
+	default:
+		h.holder, err = h.buildSegmentTree()
+	}
+	if err != nil {
+		return err
+	}
+	h.holder.EnableDebug(h.debug)
+	if setter, ok := h.holder.(LoggerSetter); ok {
+		setter.SetLogger(h.logger)
+	}
+	h.points, h.ranges = nil, nil
+	return h.holder.CompileEntries()
+}
+
+func (h *AdaptiveRangeHolder) chooseLayout() RangeLayout {
+	if h.stats.ExplodedValues <= h.MaxExplodeValues {
+		return RangeLayoutValueExplosion
+	}
+	if h.stats.RangeCnt <= h.MaxSplitRanges &&
+		h.stats.SplitEntries <= splitCostRatio*h.stats.TreeEntries {
+		return RangeLayoutIntervalSplit
+	}
+	return RangeLayoutSegmentTree
+}
+
+// estimatePostings calculate the posting entries count of interval split layout by sweeping
+// boundaries, and estimate segment tree's: each range is covered by at most 2*log(N) nodes
+func (h *AdaptiveRangeHolder) estimatePostings() (split, tree int) {
+	if len(h.ranges) == 0 {
+		return 0, 0
+	}
+	deltas := make(map[int64]int, len(h.ranges)*2)
+	for _, rg := range h.ranges {
+		deltas[rg.left]++
+		deltas[rg.right]--
+	}
+	boundaries := make([]int64, 0, len(deltas))
+	for v := range deltas {
+		boundaries = append(boundaries, v)
+	}
+	sort.Slice(boundaries, func(i, j int) bool {
+		return boundaries[i] < boundaries[j]
+	})
+	depth := 0
+	for _, v := range boundaries[:len(boundaries)-1] {
+		depth += deltas[v]
+		split += depth
+	}
+	nodesPerRange := 2 * int(math.Ceil(math.Log2(float64(len(boundaries)))))
+	return split, len(h.ranges) * util.MaxInt(nodesPerRange, 1)
+}
+
+func (h *AdaptiveRangeHolder) buildValueExplosion() (EntriesHolder, error) {
+	holder := NewNumberExtendRangeHolder(WithRangeHolderOption(&h.RangeHolderOption))
+	for _, pr := range h.points {
+		tx := FieldIndexingData{EID: pr.eid, Data: &RangeTxData{Operator: ValueOptEQ, EqValues: []int64{pr.left}}}
+		if err := holder.CommitFieldIndexingData(tx); err != nil {
+			return nil, err
+		}
+	}
+	for _, pr := range h.ranges {
+		values := NewRange(pr.left, pr.right).ToSlice()
+		tx := FieldIndexingData{EID: pr.eid, Data: &RangeTxData{Operator: ValueOptEQ, EqValues: values}}
+		if err := holder.CommitFieldIndexingData(tx); err != nil {
+			return nil, err
+		}
+	}
+	return holder, nil
+}
+
+func (h *AdaptiveRangeHolder) buildIntervalSplit() (EntriesHolder, error) {
+	holder := NewNumberExtendRangeHolder(WithRangeHolderOption(&h.RangeHolderOption))
+	for _, pr := range h.points {
+		tx := FieldIndexingData{EID: pr.eid, Data: &RangeTxData{Operator: ValueOptEQ, EqValues: []int64{pr.left}}}
+		if err := holder.CommitFieldIndexingData(tx); err != nil {
+			return nil, err
+		}
+	}
+	for _, pr := range h.ranges {
+		tx := FieldIndexingData{EID: pr.eid, Data: &RangeTxData{Operator: ValueOptBetween, RgValue: NewRange(pr.left, pr.right)}}
+		if err := holder.CommitFieldIndexingData(tx); err != nil {
+			return nil, err
+		}
+	}
+	return holder, nil
+}
+
+func (h *AdaptiveRangeHolder) buildSegmentTree() (EntriesHolder, error) {
+	holder := NewOptimizedRangeHolder(WithRangeHolderOption(&h.RangeHolderOption))
+	for _, pr := range h.points {
+		tx := FieldIndexingData{EID: pr.eid, Data: &OptimizedRangeTxData{Operator: ValueOptEQ, EqValues: []int64{pr.left}}}
+		if err := holder.CommitFieldIndexingData(tx); err != nil {
+			return nil, err
+		}
+	}
+	for _, pr := range h.ranges {
+		tx := FieldIndexingData{EID: pr.eid, Data: &OptimizedRangeTxData{Operator: ValueOptBetween, Range: NewRange(pr.left, pr.right)}}
+		if err := holder.CommitFieldIndexingData(tx); err != nil {
+			return nil, err
+		}
+	}
+	return holder, nil
+}
+
+func (h *AdaptiveRangeHolder) GetEntries(field *FieldDesc, assigns Values) (EntriesCursors, error) {
+	if h.holder == nil {
+		return nil, fmt.Errorf("holder not compiled")
+	}
+	return h.holder.GetEntries(field, assigns)
+}
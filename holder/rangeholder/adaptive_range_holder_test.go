@@ -0,0 +1,98 @@
+package rangeholder
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdaptiveRangeHolder_Layout(t *testing.T) {
+	field := &FieldDesc{ID: 0, Field: "age"}
+	randValues := func(r *rand.Rand, withInf bool) *BoolValues {
+		switch r.Intn(4) {
+		case 0:
+			return &BoolValues{Operator: ValueOptEQ, Value: []int64{r.Int63n(1000), r.Int63n(1000)}}
+		case 1:
+			l := r.Int63n(1000)
+			return &BoolValues{Operator: ValueOptBetween, Value: []int64{l, l + 1 + r.Int63n(50)}}
+		case 2:
+			if withInf {
+				return &BoolValues{Operator: ValueOptGT, Value: r.Int63n(1000)}
+			}
+		}
+		if withInf {
+			return &BoolValues{Operator: ValueOptLT, Value: r.Int63n(1000)}
+		}
+		return &BoolValues{Operator: ValueOptEQ, Value: []int64{r.Int63n(1000)}}
+	}
+
+	cases := []struct {
+		name    string
+		withInf bool
+		option  func(opt *AdaptiveRangeOption)
+		layout  RangeLayout
+	}{
+		{"small domain", false, func(opt *AdaptiveRangeOption) {}, RangeLayoutValueExplosion},
+		{"low overlapped range", false, func(opt *AdaptiveRangeOption) { opt.MaxExplodeValues = 100 }, RangeLayoutIntervalSplit},
+		{"high overlapped range", true, func(opt *AdaptiveRangeOption) {}, RangeLayoutSegmentTree},
+		{"too many ranges", false, func(opt *AdaptiveRangeOption) {
+			opt.MaxExplodeValues, opt.MaxSplitRanges = 100, 10
+		}, RangeLayoutSegmentTree},
+	}
+	for _, c := range cases {
+		convey.Convey("test adaptive range holder layout:"+c.name, t, func() {
+			option := NewAdaptiveRangeOption()
+			c.option(&option)
+			holder := NewAdaptiveRangeHolder(option)
+
+			r := rand.New(rand.NewSource(32))
+			values := map[EntryID]*BoolValues{}
+			for i := 1; i <= 300; i++ {
+				eid := NewEntryID(NewConjID(DocID(i), 0, 1), true)
+				values[eid] = randValues(r, c.withInf)
+				data, err := holder.BuildFieldIndexingData(field, values[eid])
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(holder.CommitFieldIndexingData(FieldIndexingData{EID: eid, Data: data}), convey.ShouldBeNil)
+			}
+			convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+			convey.So(holder.Layout(), convey.ShouldEqual, c.layout)
+
+			buffer := &strings.Builder{}
+			holder.DumpInfo(buffer)
+			convey.So(buffer.String(), convey.ShouldContainSubstring, string(c.layout))
+
+			for v := int64(-10); v < 1100; v += 7 {
+				cursors, err := holder.GetEntries(field, v)
+				convey.So(err, convey.ShouldBeNil)
+				got := Entries{}
+				for _, cursor := range cursors {
+					for eid := cursor.GetCurEntryID(); !eid.IsNULLEntry(); eid = cursor.SkipTo(eid + 1) {
+						got = append(got, eid)
+					}
+				}
+				got = distinctSortedEntries(got)
+
+				expect := Entries{}
+				for eid, bv := range values {
+					rg, err := ParseRange(bv.Operator, bv.Value, true)
+					if bv.Operator == ValueOptEQ {
+						for _, id := range bv.Value.([]int64) {
+							if id == v {
+								expect = append(expect, eid)
+								break
+							}
+						}
+					} else if err == nil && rg.ContainValue(v) {
+						expect = append(expect, eid)
+					}
+				}
+				sort.Sort(expect)
+				convey.So(got, convey.ShouldResemble, expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package rangeholder
+
+import (
+	"fmt"
+	"sort"
+
+	. "github.com/echoface/be_indexer"
+)
+
+type (
+	IntervalMatchMode int
+
+	// IntervalQuery a query assignment describe a interval [Left, Right) instead of a point value,
+	// use it when only a bucket of value is known, eg: user age bucket [25,35), a time window
+	//   - IntervalOverlap: match range conditions overlap with the interval
+	//   - IntervalContained: match range conditions whose values are all contained in the interval
+	// NOTE: the mode applies to the value set of the expression no matter it's include or exclude
+	IntervalQuery struct {
+		Left  int64             `json:"left"`
+		Right int64             `json:"right"`
+		Mode  IntervalMatchMode `json:"mode,omitempty"`
+	}
+)
+
+const (
+	IntervalOverlap   IntervalMatchMode = 0
+	IntervalContained IntervalMatchMode = 1
+)
+
+// NewOverlapQuery query documents whose range conditions overlap with [left, right)
+func NewOverlapQuery(left, right int64) IntervalQuery {
+	return IntervalQuery{Left: left, Right: right, Mode: IntervalOverlap}
+}
+
+// NewContainedQuery query documents whose range conditions are contained in [left, right)
+func NewContainedQuery(left, right int64) IntervalQuery {
+	return IntervalQuery{Left: left, Right: right, Mode: IntervalContained}
+}
+
+func (q IntervalQuery) String() string {
+	if q.Mode == IntervalContained {
+		return fmt.Sprintf("contained[%d,%d)", q.Left, q.Right)
+	}
+	return fmt.Sprintf("overlap[%d,%d)", q.Left, q.Right)
+}
+
+// ParseIntervalQueries return interval queries if assigns is interval query assignment
+func ParseIntervalQueries(assigns Values) (res []IntervalQuery, ok bool, err error) {
+	switch v := assigns.(type) {
+	case IntervalQuery:
+		res = []IntervalQuery{v}
+	case *IntervalQuery:
+		res = []IntervalQuery{*v}
+	case []IntervalQuery:
+		res = v
+	case []*IntervalQuery:
+		for _, q := range v {
+			res = append(res, *q)
+		}
+	default:
+		return nil, false, nil
+	}
+	for _, q := range res {
+		if q.Left >= q.Right {
+			return nil, true, fmt.Errorf("bad interval query:%s", q.String())
+		}
+	}
+	return res, true, nil
+}
+
+// distinctSortedEntries sort entries and remove duplicated entry id
+func distinctSortedEntries(entries Entries) Entries {
+	sort.Sort(entries)
+	if len(entries) <= 1 {
+		return entries
+	}
+	idx := 1
+	for i := 1; i < len(entries); i++ {
+		if entries[i] != entries[idx-1] {
+			entries[idx] = entries[i]
+			idx++
+		}
+	}
+	return entries[:idx]
+}
+
+// containedCounter count how many value units(point value or range segment) of each entry
+// hit by an interval query; entry is contained if all its units hit
+type containedCounter map[EntryID]int
+
+func (c containedCounter) add(entries Entries, units int) {
+	for _, eid := range entries {
+		c[eid] += units
+	}
+}
+
+func (c containedCounter) containedEntries(total map[EntryID]int) Entries {
+	res := make(Entries, 0, len(c))
+	for eid, cnt := range c {
+		if cnt == total[eid] {
+			res = append(res, eid)
+		}
+	}
+	sort.Sort(res)
+	return res
+}
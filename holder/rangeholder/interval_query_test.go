@@ -0,0 +1,148 @@
+package rangeholder
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+type testRangeCond struct {
+	rgs []Range // value set of the condition, [left, right)
+}
+
+func (c *testRangeCond) overlap(q IntervalQuery) bool {
+	for _, rg := range c.rgs {
+		if rg.left < q.Right && q.Left < rg.right {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *testRangeCond) contained(q IntervalQuery) bool {
+	for _, rg := range c.rgs {
+		if rg.left < q.Left || rg.right > q.Right {
+			return false
+		}
+	}
+	return true
+}
+
+func randRangeConj(r *rand.Rand) (*Conjunction, *testRangeCond) {
+	conj, cond := NewConjunction(), &testRangeCond{}
+	switch r.Intn(4) {
+	case 0:
+		values := []int64{r.Int63n(100), r.Int63n(100)}
+		conj.In("age", values)
+		for _, v := range values {
+			cond.rgs = append(cond.rgs, Range{v, v + 1})
+		}
+	case 1:
+		l := r.Int63n(100)
+		h := l + 1 + r.Int63n(30)
+		conj.Between("age", l, h)
+		cond.rgs = append(cond.rgs, Range{l, h})
+	case 2:
+		v := r.Int63n(100)
+		conj.GreaterThan("age", v)
+		cond.rgs = append(cond.rgs, Range{v + 1, math.MaxInt64})
+	default:
+		v := r.Int63n(100)
+		conj.LessThan("age", v)
+		cond.rgs = append(cond.rgs, Range{math.MinInt64, v})
+	}
+	return conj, cond
+}
+
+func TestIntervalQuery_Retrieve(t *testing.T) {
+	option := &RangeHolderOption{
+		EnableFloat2Int:    true,
+		RangeCvtValuesSize: 4, // make most Between condition go range index
+		RangeMax:           math.MaxInt64,
+		RangeMin:           math.MinInt64,
+	}
+	RegisterEntriesHolder("test_ext_range", func() EntriesHolder {
+		return NewNumberExtendRangeHolder(WithRangeHolderOption(option))
+	})
+	RegisterEntriesHolder("test_optimized_range", func() EntriesHolder {
+		return NewOptimizedRangeHolder(WithRangeHolderOption(option))
+	})
+
+	r := rand.New(rand.NewSource(30))
+	conds := map[DocID]*testRangeCond{}
+	builders := []*IndexerBuilder{NewIndexerBuilder(), NewIndexerBuilder()}
+	builders[0].ConfigField("age", FieldOption{Container: "test_ext_range"})
+	builders[1].ConfigField("age", FieldOption{Container: "test_optimized_range"})
+	for id := DocID(1); id <= 200; id++ {
+		conj, cond := randRangeConj(r)
+		conds[id] = cond
+		for _, builder := range builders {
+			doc := NewDocument(id)
+			doc.AddConjunction(conj)
+			_ = builder.AddDocument(doc)
+		}
+	}
+
+	convey.Convey("test interval query agree with brute force", t, func() {
+		for _, builder := range builders {
+			indexer := builder.BuildIndex()
+			for i := 0; i < 100; i++ {
+				left := r.Int63n(120) - 10
+				q := IntervalQuery{Left: left, Right: left + 1 + r.Int63n(40), Mode: IntervalMatchMode(r.Intn(2))}
+
+				var expect DocIDList
+				for id, cond := range conds {
+					if (q.Mode == IntervalContained && cond.contained(q)) || (q.Mode == IntervalOverlap && cond.overlap(q)) {
+						expect = append(expect, id)
+					}
+				}
+				sort.Sort(expect)
+
+				ids, err := indexer.Retrieve(Assignments{"age": q})
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(ids, convey.ShouldResemble, expect)
+
+				// point query equal to overlap query [v, v+1)
+				point := NewOverlapQuery(left, left+1)
+				expect = expect[:0]
+				for id, cond := range conds {
+					if cond.overlap(point) {
+						expect = append(expect, id)
+					}
+				}
+				sort.Sort(expect)
+				ids, err = indexer.Retrieve(Assignments{"age": left})
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(ids, convey.ShouldResemble, expect)
+			}
+		}
+	})
+
+	convey.Convey("test bad interval query", t, func() {
+		indexer := builders[0].BuildIndex()
+		_, err := indexer.Retrieve(Assignments{"age": NewOverlapQuery(10, 10)})
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+func TestOptimizedRangeHolder_ContainedQuery(t *testing.T) {
+	convey.Convey("contained query on elementary intervals", t, func() {
+		holder := NewOptimizedRangeHolder()
+		holder.pendingRanges = []pendingRange{
+			{left: 18, right: 65, eid: EntryID(1)},
+			{left: 25, right: 35, eid: EntryID(2)},
+			{left: 60, right: 100, eid: EntryID(3)},
+		}
+		convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+
+		field := &FieldDesc{Field: "age"}
+		result, err := holder.GetEntries(field, NewContainedQuery(20, 40))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(result), convey.ShouldEqual, 1)
+		convey.So(result[0].GetCurEntryID(), convey.ShouldEqual, EntryID(2))
+	})
+}
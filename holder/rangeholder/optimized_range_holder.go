@@ -3,6 +3,7 @@ package rangeholder
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
@@ -116,7 +117,8 @@ type SegmentTreeNode struct {
 type OptimizedRangeHolder struct {
 	RangeHolderOption
 
-	debug bool
+	debug  bool
+	logger StructuredLogger // injected by index, see LoggerSetter
 
 	// 坐标压缩器
 	compressor *CoordinateCompressor
@@ -132,12 +134,18 @@ type OptimizedRangeHolder struct {
 
 	// 统计
 	stats HolderStats
+
+	// 每个 entry 覆盖的基本区间数量, 用于区间包含查询
+	eidUnits map[EntryID]int
 }
 
 // pendingRange 待处理的范围
 type pendingRange struct {
 	left, right int64
 	eid         EntryID
+
+	// point 单值 MaxInt64 的闭区间 [left, left], 只覆盖坐标 left 所在的基本区间
+	point bool
 }
 
 // HolderStats 统计信息
@@ -180,6 +188,11 @@ func (h *OptimizedRangeHolder) EnableDebug(debug bool) {
 	h.debug = debug
 }
 
+// SetLogger implement LoggerSetter, debug message logged by it
+func (h *OptimizedRangeHolder) SetLogger(logger StructuredLogger) {
+	h.logger = logger
+}
+
 // DumpInfo 输出统计信息
 func (h *OptimizedRangeHolder) DumpInfo(buffer *strings.Builder) {
 	summary := map[string]interface{}{
@@ -260,10 +273,6 @@ func (h *OptimizedRangeHolder) BuildFieldIndexingData(field *FieldDesc, values *
 			}, nil
 		}
 
-		// 收集坐标用于压缩
-		h.compressor.AddValue(rg.left)
-		h.compressor.AddValue(rg.right)
-
 		return &OptimizedRangeTxData{
 			Operator: ValueOptBetween,
 			Range:    rg,
@@ -284,15 +293,9 @@ func (h *OptimizedRangeHolder) CommitFieldIndexingData(tx FieldIndexingData) err
 
 	switch data.Operator {
 	case ValueOptEQ:
-		// 小范围直接记录，不经过线段树
 		values := util.DistinctInteger(data.EqValues)
 		for _, id := range values {
-			h.pendingRanges = append(h.pendingRanges, pendingRange{
-				left:  id,
-				right: id,
-				eid:   tx.EID,
-			})
-			h.compressor.AddValue(id)
+			h.pendingRanges = append(h.pendingRanges, pointRange(id, tx.EID))
 		}
 
 	case ValueOptBetween:
@@ -310,25 +313,33 @@ func (h *OptimizedRangeHolder) CommitFieldIndexingData(tx FieldIndexingData) err
 
 // CompileEntries 编译索引
 func (h *OptimizedRangeHolder) CompileEntries() error {
-	// 步骤1：构建坐标压缩
+	// 步骤1：构建坐标压缩; 坐标在编译阶段统一收集, 确保从缓存恢复的数据同样生效
+	for _, pr := range h.pendingRanges {
+		h.compressor.AddValue(pr.left)
+		h.compressor.AddValue(pr.right)
+	}
 	h.compressor.Build()
 	h.stats.CompressedSize = h.compressor.Size()
 
-	LogInfoIf(h.debug, "坐标压缩完成: %d -> %d (%.1fx 压缩)",
-		len(h.pendingRanges)*2, // 原始坐标数（估计）
-		h.stats.CompressedSize,
-		float64(len(h.pendingRanges)*2)/float64(h.stats.CompressedSize))
+	if h.debug {
+		LoggerOrDefault(h.logger).Info("坐标压缩完成",
+			"coordinates", len(h.pendingRanges)*2, // 原始坐标数（估计）
+			"compressed", h.stats.CompressedSize,
+			"ratio", float64(len(h.pendingRanges)*2)/float64(h.stats.CompressedSize))
+	}
 
 	// 步骤2：构建线段树
 	if h.stats.CompressedSize > 0 {
 		h.root = h.buildTree(0, h.stats.CompressedSize-1)
 	}
 
-	// 步骤3：插入所有范围
+	// 步骤3：插入所有范围, 空范围不覆盖任何基本区间
+	h.eidUnits = make(map[EntryID]int)
 	for _, pr := range h.pendingRanges {
-		l, r := h.compressor.FindRange(pr.left, pr.right)
+		l, r := h.coveredIdx(pr)
 		if l <= r {
 			h.insert(h.root, l, r, pr.eid)
+			h.eidUnits[pr.eid] += r - l + 1
 		}
 	}
 
@@ -387,6 +398,13 @@ func (h *OptimizedRangeHolder) GetEntries(field *FieldDesc, assigns Values) (Ent
 		return nil, fmt.Errorf("holder not compiled")
 	}
 
+	if queries, ok, err := ParseIntervalQueries(assigns); ok {
+		if err != nil {
+			return nil, err
+		}
+		return h.getIntervalEntries(field, queries), nil
+	}
+
 	// 解析查询值
 	ids, err := parser.ParseIntegers(assigns, h.EnableFloat2Int)
 	if err != nil {
@@ -401,14 +419,9 @@ func (h *OptimizedRangeHolder) GetEntries(field *FieldDesc, assigns Values) (Ent
 	resultMap := make(map[EntryID]struct{})
 
 	for _, id := range ids {
-		// 坐标压缩
-		idx, ok := h.compressor.GetIdx(id)
+		idx, ok := h.pointIdx(id)
 		if !ok {
-			// 值不存在，查找覆盖该值的范围
-			idx = h.compressor.FindIdx(id)
-			if idx >= h.stats.CompressedSize {
-				continue
-			}
+			continue
 		}
 
 		// 查询线段树
@@ -457,6 +470,107 @@ func (h *OptimizedRangeHolder) collectEntries(node *SegmentTreeNode, idx int, re
 	}
 }
 
+// pointRange 单值 id 视为区间 [id, id+1), 与范围统一使用基本区间表示;
+// 单值不再只占一个坐标点, 避免坐标之间的查询值落到下一个坐标所在的单值/范围上.
+// MaxInt64 的右边界无法表示, 使用闭区间 [MaxInt64, MaxInt64], 即最后一个坐标所在的基本区间
+func pointRange(id int64, eid EntryID) pendingRange {
+	if id == math.MaxInt64 {
+		return pendingRange{left: id, right: id, eid: eid, point: true}
+	}
+	return pendingRange{left: id, right: id + 1, eid: eid}
+}
+
+// coveredIdx 范围覆盖的基本区间 [l, r]: [left, right) 覆盖 idx(left) ... idx(right)-1;
+// 单值 MaxInt64 显式覆盖其坐标所在的基本区间, 与 pointIdx 对应
+func (h *OptimizedRangeHolder) coveredIdx(pr pendingRange) (l, r int) {
+	if pr.point {
+		idx, _ := h.compressor.GetIdx(pr.left)
+		return idx, idx
+	}
+	return h.compressor.FindIdx(pr.left), h.compressor.FindIdx(pr.right) - 1
+}
+
+// pointIdx 查询值 v 所在的基本区间, 最后一个坐标只作为区间右边界, 不包含任何值; 除非它是 MaxInt64
+func (h *OptimizedRangeHolder) pointIdx(v int64) (int, bool) {
+	idx := h.elementaryIdx(v)
+	if idx < 0 {
+		return 0, false
+	}
+	if idx >= h.stats.CompressedSize-1 && h.compressor.values[idx] != math.MaxInt64 {
+		return 0, false
+	}
+	return idx, true
+}
+
+// elementaryIdx 值 v 所在的基本区间 [values[idx], values[idx+1]) 的索引, -1 表示小于所有坐标
+func (h *OptimizedRangeHolder) elementaryIdx(v int64) int {
+	values := h.compressor.values
+	return sort.Search(len(values), func(i int) bool {
+		return values[i] > v
+	}) - 1
+}
+
+// getIntervalEntries 区间查询, 每个区间查询生成一个 cursor
+func (h *OptimizedRangeHolder) getIntervalEntries(field *FieldDesc, queries []IntervalQuery) (r EntriesCursors) {
+	maxIdx := h.stats.CompressedSize - 2 // 最后一个坐标只作为区间右边界
+	for _, q := range queries {
+		var result Entries
+		if q.Mode == IntervalContained {
+			// 完全落在 [Left, Right) 内的基本区间
+			lo := h.compressor.FindIdx(q.Left)
+			hi := util.MinInt(h.elementaryIdx(q.Right)-1, maxIdx)
+			if lo > hi {
+				continue
+			}
+			counter := containedCounter{}
+			h.countRangeEntries(h.root, lo, hi, counter)
+			result = counter.containedEntries(h.eidUnits)
+		} else {
+			// 与 [Left, Right) 相交的基本区间
+			lo := util.MaxInt(h.elementaryIdx(q.Left), 0)
+			hi := util.MinInt(h.elementaryIdx(q.Right-1), maxIdx)
+			if lo > hi {
+				continue
+			}
+			resultMap := make(map[EntryID]struct{})
+			h.collectRangeEntries(h.root, lo, hi, resultMap)
+			result = make(Entries, 0, len(resultMap))
+			for eid := range resultMap {
+				result = append(result, eid)
+			}
+			sort.Sort(result)
+		}
+		if len(result) > 0 {
+			r = append(r, NewEntriesCursor(NewQKey(field.Field, q), result))
+		}
+	}
+	return r
+}
+
+// collectRangeEntries 收集与基本区间 [l, r] 相交的所有 entries
+func (h *OptimizedRangeHolder) collectRangeEntries(node *SegmentTreeNode, l, r int, result map[EntryID]struct{}) {
+	if node == nil || node.r < l || node.l > r {
+		return
+	}
+	for _, eid := range node.entries {
+		result[eid] = struct{}{}
+	}
+	h.collectRangeEntries(node.left, l, r, result)
+	h.collectRangeEntries(node.right, l, r, result)
+}
+
+// countRangeEntries 统计每个 entry 在基本区间 [l, r] 内覆盖的基本区间数量
+func (h *OptimizedRangeHolder) countRangeEntries(node *SegmentTreeNode, l, r int, counter containedCounter) {
+	if node == nil || node.r < l || node.l > r {
+		return
+	}
+	if len(node.entries) > 0 {
+		counter.add(node.entries, util.MinInt(node.r, r)-util.MaxInt(node.l, l)+1)
+	}
+	h.countRangeEntries(node.left, l, r, counter)
+	h.countRangeEntries(node.right, l, r, counter)
+}
+
 // calculateMemoryStats 计算内存节省
 func (h *OptimizedRangeHolder) calculateMemoryStats() {
 	// 原实现：每个唯一坐标一个 map entry
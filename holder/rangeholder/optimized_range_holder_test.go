@@ -1,6 +1,7 @@
 package rangeholder
 
 import (
+	"math"
 	"testing"
 
 	. "github.com/echoface/be_indexer"
@@ -57,6 +58,149 @@ func TestOptimizedRangeHolder_Basic(t *testing.T) {
 	})
 }
 
+func optimizedHolderEntries(holder *OptimizedRangeHolder, v Values) (got []int) {
+	cursors, err := holder.GetEntries(&FieldDesc{Field: "age"}, v)
+	convey.So(err, convey.ShouldBeNil)
+	for _, cursor := range cursors {
+		for eid := cursor.GetCurEntryID(); !eid.IsNULLEntry(); eid = cursor.SkipTo(eid + 1) {
+			got = append(got, int(eid))
+		}
+	}
+	return got
+}
+
+func TestOptimizedRangeHolder_PointSemantics(t *testing.T) {
+	convey.Convey("eq values and range boundaries", t, func() {
+		field := &FieldDesc{Field: "age"}
+		values := []*BoolValues{
+			{Operator: ValueOptEQ, Value: []int64{5, 7}},
+			{Operator: ValueOptBetween, Value: []int64{10, 100}},
+			{Operator: ValueOptBetween, Value: []int64{50, 60}},
+			{Operator: ValueOptGT, Value: 200},
+			{Operator: ValueOptLT, Value: -100},
+			{Operator: ValueOptEQ, Value: []float64{8.8}},
+		}
+		build := func(restore bool) *OptimizedRangeHolder {
+			newHolder := func() *OptimizedRangeHolder {
+				return NewOptimizedRangeHolder(func(option *RangeHolderOption) {
+					option.RangeCvtValuesSize = 4
+				})
+			}
+			holder, target := newHolder(), newHolder()
+			if !restore {
+				target = holder
+			}
+			for i, bv := range values {
+				data, err := holder.BuildFieldIndexingData(field, bv)
+				convey.So(err, convey.ShouldBeNil)
+				if restore { // data restored from cache is committed without building
+					encoded, err := data.Encode()
+					convey.So(err, convey.ShouldBeNil)
+					data, err = target.DecodeFieldIndexingData(encoded)
+					convey.So(err, convey.ShouldBeNil)
+				}
+				tx := FieldIndexingData{EID: EntryID(i + 1), Data: data}
+				convey.So(target.CommitFieldIndexingData(tx), convey.ShouldBeNil)
+			}
+			convey.So(target.CompileEntries(), convey.ShouldBeNil)
+			return target
+		}
+		expects := map[int64][]int{
+			// coordinates: eq values, left bound inclusive, right bound exclusive
+			5: {1}, 7: {1}, 10: {2}, 50: {2, 3}, 60: {2}, 100: nil, 201: {4}, -101: {5}, -100: nil, 8: {6},
+			// values between coordinates never match eq values or ranges after them
+			4: nil, 6: nil, 9: nil, 11: {2}, 59: {2, 3}, 61: {2}, 150: nil, 200: nil, 1000: {4},
+			math.MinInt64: {5}, math.MaxInt64 - 1: {4},
+		}
+		for _, restore := range []bool{false, true} {
+			holder := build(restore)
+			for v, expect := range expects {
+				convey.So(optimizedHolderEntries(holder, v), convey.ShouldResemble, expect)
+			}
+		}
+	})
+}
+
+func TestOptimizedRangeHolder_MaxInt64(t *testing.T) {
+	convey.Convey("eq value MaxInt64 doesn't overflow", t, func() {
+		field := &FieldDesc{Field: "age"}
+		values := []*BoolValues{
+			{Operator: ValueOptEQ, Value: []int64{math.MaxInt64, 5}},
+			{Operator: ValueOptGT, Value: 200},
+			{Operator: ValueOptEQ, Value: []int64{math.MaxInt64 - 1}},
+		}
+		holder := NewOptimizedRangeHolder()
+		for i, bv := range values {
+			data, err := holder.BuildFieldIndexingData(field, bv)
+			convey.So(err, convey.ShouldBeNil)
+			tx := FieldIndexingData{EID: EntryID(i + 1), Data: data}
+			convey.So(holder.CommitFieldIndexingData(tx), convey.ShouldBeNil)
+		}
+		convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+
+		// range GT 200 is [201, MaxInt64), MaxInt64 itself excluded
+		convey.So(optimizedHolderEntries(holder, math.MaxInt64), convey.ShouldResemble, []int{1})
+		convey.So(optimizedHolderEntries(holder, math.MaxInt64-1), convey.ShouldResemble, []int{2, 3})
+		convey.So(optimizedHolderEntries(holder, 5), convey.ShouldResemble, []int{1})
+
+		// MaxInt64 is never in [Left, Right), so document with it never contained
+		overlap := NewOverlapQuery(math.MaxInt64-1, math.MaxInt64)
+		convey.So(optimizedHolderEntries(holder, overlap), convey.ShouldResemble, []int{2, 3})
+		contained := NewContainedQuery(0, math.MaxInt64)
+		convey.So(optimizedHolderEntries(holder, contained), convey.ShouldResemble, []int{2, 3})
+	})
+}
+
+func TestOptimizedRangeHolder_EQBoundary(t *testing.T) {
+	convey.Convey("eq value at int64 boundary is an explicit point", t, func() {
+		field := &FieldDesc{Field: "age"}
+		cases := []struct {
+			values []*BoolValues
+			expect map[int64][]int
+		}{
+			{ // single value holder, compressed coordinates only contains the boundary
+				values: []*BoolValues{{Operator: ValueOptEQ, Value: []int64{math.MaxInt64}}},
+				expect: map[int64][]int{math.MaxInt64: {1}, math.MaxInt64 - 1: nil, 0: nil},
+			},
+			{
+				values: []*BoolValues{{Operator: ValueOptEQ, Value: []int64{math.MinInt64}}},
+				expect: map[int64][]int{math.MinInt64: {1}, math.MinInt64 + 1: nil, 0: nil},
+			},
+			{
+				values: []*BoolValues{
+					{Operator: ValueOptEQ, Value: []int64{math.MinInt64, math.MaxInt64}},
+					{Operator: ValueOptEQ, Value: []int64{math.MaxInt64}},
+					{Operator: ValueOptBetween, Value: []int64{math.MinInt64, 0}},
+					{Operator: ValueOptEQ, Value: []int64{math.MinInt64 + 1}},
+				},
+				expect: map[int64][]int{
+					math.MaxInt64: {1, 2}, math.MaxInt64 - 1: nil,
+					math.MinInt64: {1, 3}, math.MinInt64 + 1: {3, 4}, math.MinInt64 + 2: {3}, 0: nil,
+				},
+			},
+		}
+		for _, c := range cases {
+			holder := NewOptimizedRangeHolder()
+			for i, bv := range c.values {
+				data, err := holder.BuildFieldIndexingData(field, bv)
+				convey.So(err, convey.ShouldBeNil)
+				tx := FieldIndexingData{EID: EntryID(i + 1), Data: data}
+				convey.So(holder.CommitFieldIndexingData(tx), convey.ShouldBeNil)
+			}
+			convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+			for v, expect := range c.expect {
+				convey.So(optimizedHolderEntries(holder, v), convey.ShouldResemble, expect)
+			}
+		}
+
+		// empty range covers nothing, it's not a point
+		holder := NewOptimizedRangeHolder()
+		holder.pendingRanges = []pendingRange{{left: 5, right: 5, eid: EntryID(1)}, {left: 5, right: 6, eid: EntryID(2)}}
+		convey.So(holder.CompileEntries(), convey.ShouldBeNil)
+		convey.So(optimizedHolderEntries(holder, 5), convey.ShouldResemble, []int{2})
+	})
+}
+
 func TestCoordinateCompressor(t *testing.T) {
 	convey.Convey("Test CoordinateCompressor", t, func() {
 		cc := NewCoordinateCompressor()
@@ -19,11 +19,15 @@ type (
 		RangeHolderOption
 
 		debug  bool
-		maxLen int // max length of Entries
-		avgLen int // avg length of Entries
+		logger StructuredLogger // injected by index, see LoggerSetter
+		maxLen int              // max length of Entries
+		avgLen int              // avg length of Entries
 
 		rangeIdx  *RangeIdx         // range expression container
 		plEntries map[int64]Entries // in/not in value expression container
+
+		sortedKeys []int64         // sorted keys of plEntries, for interval query
+		eidUnits   map[EntryID]int // count of point values and range segments for each entry
 	}
 
 	RangeHolderOption struct {
@@ -91,6 +95,11 @@ func (h *RangeHolder) EnableDebug(debug bool) {
 	h.debug = debug
 }
 
+// SetLogger implement LoggerSetter, debug message logged by it
+func (h *RangeHolder) SetLogger(logger StructuredLogger) {
+	h.logger = logger
+}
+
 func (h *RangeHolder) DumpInfo(buffer *strings.Builder) {
 	summarys := map[string]interface{}{
 		"name":               "RangeHolder",
@@ -119,23 +128,89 @@ func (h *RangeHolder) CompileEntries() error {
 	var total int
 	valueCnt := len(h.plEntries)
 
-	for _, entries := range h.plEntries {
-		sort.Sort(entries)
+	h.eidUnits = map[EntryID]int{}
+	h.sortedKeys = make([]int64, 0, valueCnt)
+	for v, entries := range h.plEntries {
+		entries = distinctSortedEntries(entries)
+		h.plEntries[v] = entries
+		h.sortedKeys = append(h.sortedKeys, v)
 
 		if h.maxLen < len(entries) {
 			h.maxLen = len(entries)
 		}
 		total += len(entries)
+		containedCounter(h.eidUnits).add(entries, 1)
 	}
 	if valueCnt > 0 {
 		h.avgLen = total / valueCnt
 	}
+	sort.Slice(h.sortedKeys, func(i, j int) bool {
+		return h.sortedKeys[i] < h.sortedKeys[j]
+	})
 
 	h.rangeIdx.Compile()
+	for _, pl := range h.rangeIdx.rgEntries {
+		containedCounter(h.eidUnits).add(pl.entries, 1)
+	}
 	return nil
 }
 
+// keysInRange return the point values in [left, right)
+func (h *RangeHolder) keysInRange(left, right int64) []int64 {
+	l := sort.Search(len(h.sortedKeys), func(i int) bool { return h.sortedKeys[i] >= left })
+	r := sort.Search(len(h.sortedKeys), func(i int) bool { return h.sortedKeys[i] >= right })
+	return h.sortedKeys[l:r]
+}
+
+func (h *RangeHolder) getIntervalEntries(field *FieldDesc, queries []IntervalQuery) (r EntriesCursors) {
+	pointResults := map[int64]IntervalQuery{}
+	rangeResults := map[*RangeEntries]IntervalQuery{}
+	for _, q := range queries {
+		if q.Mode == IntervalContained {
+			counter := containedCounter{}
+			for _, v := range h.keysInRange(q.Left, q.Right) {
+				counter.add(h.plEntries[v], 1)
+			}
+			for _, pl := range h.rangeIdx.Overlapped(q.Left, q.Right) {
+				if pl.left >= q.Left && pl.right <= q.Right {
+					counter.add(pl.entries, 1)
+				}
+			}
+			if entries := counter.containedEntries(h.eidUnits); len(entries) > 0 {
+				r = append(r, NewEntriesCursor(NewQKey(field.Field, q), entries))
+			}
+			if h.debug {
+				LoggerOrDefault(h.logger).Info("interval find", "field", field.Field, "query", q.String(), "entries", len(counter))
+			}
+			continue
+		}
+		for _, v := range h.keysInRange(q.Left, q.Right) {
+			pointResults[v] = q
+		}
+		for _, pl := range h.rangeIdx.Overlapped(q.Left, q.Right) {
+			if len(pl.entries) > 0 {
+				rangeResults[pl] = q
+			}
+		}
+	}
+	for v, q := range pointResults {
+		if entries := h.plEntries[v]; len(entries) > 0 {
+			r = append(r, NewEntriesCursor(NewQKey(field.Field, q), entries))
+		}
+	}
+	for rgPl, q := range rangeResults {
+		r = append(r, NewEntriesCursor(NewQKey(field.Field, q), rgPl.entries))
+	}
+	return r
+}
+
 func (h *RangeHolder) GetEntries(field *FieldDesc, assigns Values) (r EntriesCursors, e error) {
+	if queries, ok, err := ParseIntervalQueries(assigns); ok {
+		if err != nil {
+			return nil, err
+		}
+		return h.getIntervalEntries(field, queries), nil
+	}
 	var ids []int64
 	if ids, e = parser.ParseIntegers(assigns, h.EnableFloat2Int); e != nil {
 		return nil, e
@@ -148,11 +223,15 @@ func (h *RangeHolder) GetEntries(field *FieldDesc, assigns Values) (r EntriesCur
 		if entries, hit := h.plEntries[id]; hit && len(entries) > 0 {
 			cursor := NewEntriesCursor(NewQKey(field.Field, id), entries)
 			r = append(r, cursor)
-			LogInfoIf(h.debug, "kvs find:<%s:%d>, entries len:%d", field.Field, id, len(entries))
+			if h.debug {
+				LoggerOrDefault(h.logger).Info("kvs find", "field", field.Field, "id", id, "entries", len(entries))
+			}
 		}
 		if pl := h.rangeIdx.Retrieve(id); pl != nil && len(pl.entries) > 0 {
 			rangeResults[pl] = id
-			LogInfoIf(h.debug, "range find:<%s:%d>, entries len:%d", field.Field, id, len(pl.entries))
+			if h.debug {
+				LoggerOrDefault(h.logger).Info("range find", "field", field.Field, "id", id, "entries", len(pl.entries))
+			}
 		}
 	}
 	for rgPl, id := range rangeResults {
@@ -302,6 +381,28 @@ func NewRange(l, r int64) *Range {
 	return &Range{l, r}
 }
 
+// MarshalJSON encode range as [left, right], range is part of the indexing data cached
+func (rg Range) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int64{rg.left, rg.right})
+}
+
+func (rg *Range) UnmarshalJSON(data []byte) error {
+	var bounds [2]int64
+	if err := json.Unmarshal(data, &bounds); err != nil {
+		return err
+	}
+	rg.left, rg.right = bounds[0], bounds[1]
+	return nil
+}
+
+func (rg *Range) Left() int64 {
+	return rg.left
+}
+
+func (rg *Range) Right() int64 {
+	return rg.right
+}
+
 func (rg *Range) Size() float64 {
 	return float64(rg.right) - float64(rg.left)
 }
@@ -386,12 +487,13 @@ func (rix *RangeIdx) Compile() {
 
 		if cnt := pl.entries.Len(); cnt > 0 {
 
+			pl.entries = distinctSortedEntries(pl.entries)
+			cnt = pl.entries.Len()
+
 			sumCnt += cnt
 			if rix.maxLen < cnt {
 				rix.maxLen = cnt
 			}
-
-			sort.Sort(pl.entries)
 		}
 		rix.rgEntries = append(rix.rgEntries, pl)
 	}
@@ -435,6 +537,17 @@ func (rix *RangeIdx) Retrieve(value int64) *RangeEntries {
 	return rix.rgEntries[index]
 }
 
+// Overlapped return all compiled range entries overlap with [left, right)
+func (rix *RangeIdx) Overlapped(left, right int64) (res RangePlList) {
+	idx := sort.Search(len(rix.rgEntries), func(i int) bool {
+		return rix.rgEntries[i].right > left
+	})
+	for ; idx < len(rix.rgEntries) && rix.rgEntries[idx].left < right; idx++ {
+		res = append(res, rix.rgEntries[idx])
+	}
+	return res
+}
+
 func (rix *RangeIdx) scale(left, right int64) (l, r int64) {
 	return util.MaxInt64(left, rix.valueMin), util.MinInt64(right, rix.valueMax)
 }
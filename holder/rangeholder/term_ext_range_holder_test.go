@@ -138,3 +138,33 @@ func TestBEIndex_WithExtendRange(t *testing.T) {
 		}
 	})
 }
+
+func TestConjunction_EvaluateExtendRange(t *testing.T) {
+	convey.Convey("test evaluate range expression agree with retrieve", t, func() {
+		opt := &EvalOptions{FieldConfig: map[BEField]FieldOption{
+			"age": {Container: HolderNameExtendRange},
+		}}
+		builder := NewIndexerBuilder()
+		builder.ConfigField("age", opt.FieldConfig["age"])
+
+		docs := []*Document{
+			NewDocument(1).AddConjunction(NewConjunction().GreaterThan("age", 10)),
+			NewDocument(2).AddConjunction(NewConjunction().Between("age", 5, 20).In("tag", "x")),
+			NewDocument(3).AddConjunction(NewConjunction().LessThan("age", 5), NewConjunction().In("age", []int64{50})),
+		}
+		convey.So(builder.AddDocument(docs...), convey.ShouldBeNil)
+		index := builder.BuildIndex()
+
+		for _, age := range []int64{-1, 4, 5, 10, 11, 19, 20, 50} {
+			for _, assigns := range []Assignments{{"age": age}, {"age": age, "tag": "x"}} {
+				result, err := index.Retrieve(assigns)
+				convey.So(err, convey.ShouldBeNil)
+				for _, doc := range docs {
+					convey.So(len(doc.Match(assigns, opt)) > 0, convey.ShouldEqual, result.Contain(doc.ID))
+				}
+			}
+		}
+		convey.So(docs[1].Cons[0].Evaluate(Assignments{"age": 20, "tag": "x"}, opt), convey.ShouldBeFalse)
+		convey.So(docs[1].Cons[0].Evaluate(Assignments{"age": 5, "tag": "x"}, opt), convey.ShouldBeTrue)
+	})
+}
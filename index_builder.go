@@ -12,7 +12,11 @@ type (
 	IndexerBuilder struct {
 		BuilderOption
 
-		indexer BEIndex
+		indexer buildableIndex
+
+		attributes *AttributeStore
+
+		payloads *PayloadStore
 
 		fieldsData map[BEField]*FieldDesc
 
@@ -37,6 +41,7 @@ type (
 		indexerType     IndexerType
 		badConjBehavior BadConjBehavior // 是否允许一个doc中部分Conjunction解析失败
 		docLevelCache   DocLevelCache   // 【增量缓存】文档级缓存
+		logger          StructuredLogger
 	}
 
 	BuilderOpt func(builder *IndexerBuilder)
@@ -76,6 +81,13 @@ func WithDocLevelCache(cache DocLevelCache) BuilderOpt {
 	}
 }
 
+// WithLogger inject logger for builder and the index built by it, global Logger used if not specified
+func WithLogger(logger StructuredLogger) BuilderOpt {
+	return func(builder *IndexerBuilder) {
+		builder.logger = logger
+	}
+}
+
 func WithIndexerType(t IndexerType) BuilderOpt {
 	return func(builder *IndexerBuilder) {
 		builder.indexerType = t
@@ -84,7 +96,7 @@ func WithIndexerType(t IndexerType) BuilderOpt {
 
 func NewIndexerBuilder(opts ...BuilderOpt) *IndexerBuilder {
 	builder := &IndexerBuilder{
-		indexer:     NewKGroupsBEIndex(),
+		indexer:     newKGroupsBEIndex(),
 		fieldsData:  map[BEField]*FieldDesc{},
 		idAllocator: parser.NewIDAllocatorImpl(),
 	}
@@ -105,14 +117,23 @@ func (b *IndexerBuilder) Reset() {
 }
 
 func (b *IndexerBuilder) initIndexer() {
+	b.attributes = NewAttributeStore()
+	b.payloads = NewPayloadStore()
 	switch b.indexerType {
 	case IndexerTypeDefault:
-		b.indexer = NewKGroupsBEIndex()
+		b.indexer = newKGroupsBEIndex()
 	case IndexerTypeCompact:
 		b.indexer = NewCompactedBEIndex()
 	default:
 		util.PanicIf(true, "type:%d not supported", b.indexerType)
 	}
+	if setter, ok := b.indexer.(LoggerSetter); ok {
+		setter.SetLogger(b.logger)
+	}
+}
+
+func (b *IndexerBuilder) log() StructuredLogger {
+	return LoggerOrDefault(b.logger)
 }
 
 func (b *IndexerBuilder) ConfigField(field BEField, settings FieldOption) {
@@ -127,15 +148,27 @@ func (b *IndexerBuilder) AddDocument(docs ...*Document) error {
 		if err := b.validDocument(doc); err != nil {
 			return err
 		}
-		if err := b.buildDocEntries(doc); err != nil {
+		attrs, err := normalizeDocAttrs(doc.ID, doc.Attrs)
+		if err != nil {
+			return err
+		}
+		if err = b.buildDocEntries(doc); err != nil {
 			return err
 		}
+		b.attributes.put(doc.ID, attrs)
+		b.payloads.Set(doc.ID, doc.Payload)
 	}
 	return nil
 }
 
 // 从文档级索引缓存（中间结果）恢复
 func (b *IndexerBuilder) AddDocIndexingData(cached *DocIdxCache) error {
+	attrs, err := normalizeDocAttrs(cached.DocID, cached.Attrs)
+	if err != nil {
+		return err
+	}
+	b.attributes.put(cached.DocID, attrs)
+	b.payloads.Set(cached.DocID, cached.Payload)
 	for _, conjResult := range cached.ConjIdxCaches {
 		// 恢复成 ConjIndexingData
 		cd, err := b.toConjIndexingData(cached.DocID, &conjResult)
@@ -190,6 +223,8 @@ func (b *IndexerBuilder) toConjIndexingData(docID DocID, conjResult *ConjIdxCach
 
 func (b *IndexerBuilder) BuildIndex() BEIndex {
 	b.indexer.setFieldDesc(b.fieldsData)
+	b.indexer.setAttributeStore(b.attributes)
+	b.indexer.setPayloadStore(b.payloads)
 
 	err := b.indexer.compileIndexer()
 	util.PanicIfErr(err, "fail compile indexer data, err:%+v", err)
@@ -203,7 +238,7 @@ func (b *IndexerBuilder) configureField(field BEField, option FieldOption) (*Fie
 	}
 	if len(option.Container) == 0 {
 		option.Container = HolderNameDefault
-		Logger.Infof("not configure container for field:%s, use default", field)
+		b.log().Debug("container not configured, use default", "field", field)
 	}
 
 	fieldID := uint64(len(b.fieldsData))
@@ -218,10 +253,10 @@ func (b *IndexerBuilder) configureField(field BEField, option FieldOption) (*Fie
 	b.updateSchemaHash()
 	if b.docLevelCache != nil {
 		b.docLevelCache.Clear()
-		Logger.Infof("schema changed, doc level cache cleared")
+		b.log().Info("schema changed, doc level cache cleared", "field", field)
 	}
 
-	Logger.Infof("configure field:%s, fieldID:%d\n", field, desc.ID)
+	b.log().Debug("configure field", "field", field, "fieldID", desc.ID, "container", option.Container)
 	return desc, nil
 }
 
@@ -281,7 +316,7 @@ func (b *IndexerBuilder) buildDocEntries(doc *Document) error {
 	if b.docLevelCache != nil && doc.Version > 0 {
 		cacheKey := NewDocCacheKey(doc.ID, doc.Version)
 		if cached, ok := b.docLevelCache.Get(cacheKey); ok && cached.SchemaHash == b.schemaHash {
-			Logger.Debugf("doc cache hit: docID=%d, version=%d", doc.ID, doc.Version)
+			b.log().Debug("doc cache hit", "docID", doc.ID, "version", doc.Version)
 			return b.AddDocIndexingData(cached)
 		}
 	}
@@ -289,10 +324,14 @@ func (b *IndexerBuilder) buildDocEntries(doc *Document) error {
 	// 缓存未命中，构建并捕获结果
 	var cacheEntry *DocIdxCache
 	if b.docLevelCache != nil && doc.Version > 0 {
+		// attrs validated by AddDocument, normalize again to take a copy owned by cache entry
+		attrs, _ := normalizeDocAttrs(doc.ID, doc.Attrs)
 		cacheEntry = &DocIdxCache{
 			DocID:      doc.ID,
 			Version:    doc.Version,
 			SchemaHash: b.schemaHash,
+			Payload:    append([]byte(nil), doc.Payload...),
+			Attrs:      attrs,
 		}
 	}
 
@@ -313,7 +352,7 @@ ConjLoop:
 		if err != nil {
 			switch b.badConjBehavior {
 			case SkipBadConj:
-				Logger.Errorf("indexing conj:%s fail:%v", conjID.String(), err)
+				b.log().Error("skip bad conjunction", "docID", doc.ID, "conjID", conjID.String(), "err", err)
 				continue ConjLoop
 			case ErrorBadConj:
 				return fmt.Errorf("indexing conj:%s fail:%v", conjID.String(), err)
@@ -338,7 +377,7 @@ ConjLoop:
 
 		// 【增量缓存】捕获 Conjunction 结果
 		if cacheEntry != nil {
-			cacheEntry.ConjIdxCaches = append(cacheEntry.ConjIdxCaches, cd.toCacheResult())
+			cacheEntry.ConjIdxCaches = append(cacheEntry.ConjIdxCaches, cd.toCacheResult(b.log()))
 		}
 	}
 
@@ -346,7 +385,7 @@ ConjLoop:
 	if cacheEntry != nil && len(cacheEntry.ConjIdxCaches) > 0 {
 		cacheKey := NewDocCacheKey(doc.ID, doc.Version)
 		b.docLevelCache.Set(cacheKey, cacheEntry)
-		Logger.Debugf("doc cache saved: docID=%d, version=%d", doc.ID, doc.Version)
+		b.log().Debug("doc cache saved", "docID", doc.ID, "version", doc.Version)
 	}
 
 	return nil
@@ -378,7 +417,7 @@ func (b *IndexerBuilder) indexingConjunction(conjID ConjID, conj *Conjunction) (
 }
 
 // toCacheResult 转换为可缓存的格式
-func (cd *ConjIndexingData) toCacheResult() ConjIdxCache {
+func (cd *ConjIndexingData) toCacheResult(logger StructuredLogger) ConjIdxCache {
 	result := ConjIdxCache{
 		ConjIdx:  cd.idx,
 		ConjSize: cd.incSize,
@@ -402,7 +441,7 @@ func (cd *ConjIndexingData) toCacheResult() ConjIdxCache {
 		// 序列化 TxData
 		dataBytes, err := tx.Data.Encode()
 		if err != nil {
-			Logger.Errorf("encode tx data failed for field %s: %v", field, err)
+			logger.Error("encode tx data fail", "field", field, "conjID", cd.conjID.String(), "err", err)
 			continue
 		}
 
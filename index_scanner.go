@@ -51,12 +51,23 @@ func NewQKey(field BEField, v interface{}) QKey {
 	return key
 }
 
+func (key *QKey) Field() BEField {
+	return key.field
+}
+
+// Value the query value that hit the posting list, holder can attach extra match info here
+func (key *QKey) Value() interface{} {
+	return key.value
+}
+
 func (key *QKey) String() string {
 	switch v := key.value.(type) {
 	case string:
 		return fmt.Sprintf("[%s,%s]", key.field, v)
 	case int8, int16, int, int32, int64, uint8, uint16, uint, uint32, uint64:
 		return fmt.Sprintf("[%s,%d]", key.field, v)
+	case fmt.Stringer:
+		return fmt.Sprintf("[%s,%s]", key.field, v.String())
 	default:
 		fmt.Println("unknown type", reflect.TypeOf(key.value).String())
 	}
@@ -77,6 +88,10 @@ func NewEntriesCursor(key QKey, entries Entries) EntriesCursor {
 	}
 }
 
+func (ec *EntriesCursor) Key() QKey {
+	return ec.key
+}
+
 func (ec *EntriesCursor) GetCurEntryID() EntryID {
 	return ec.curEID
 }
@@ -1,6 +1,9 @@
 package be_indexer
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	DebugLevel = iota
@@ -23,8 +26,25 @@ type (
 	// DefaultLogger a console logger use fmt lib
 	DefaultLogger struct {
 	}
+
+	// StructuredLogger leveled logger with key-value fields, msg is followed by alternating key and value,
+	// eg: Debug("fetch posting list", "field", "age", "k", 2); *slog.Logger(log/slog) satisfies it
+	// and can be injected directly, other logging libraries can be adapted by LogFunc
+	StructuredLogger interface {
+		Debug(msg string, kvs ...interface{})
+		Info(msg string, kvs ...interface{})
+		Error(msg string, kvs ...interface{})
+	}
+
+	// LogFunc adapt a logging function into StructuredLogger, level is one of DebugLevel/InfoLevel/ErrorLevel
+	LogFunc func(level int, msg string, kvs ...interface{})
+
+	// fallbackLogger StructuredLogger write to global Logger, fields formatted as key=value
+	fallbackLogger struct{}
 )
 
+var defaultStructuredLogger StructuredLogger = fallbackLogger{}
+
 func LogDebugIf(condition bool, format string, v ...interface{}) {
 	if condition {
 		Logger.Debugf(format, v...)
@@ -86,3 +106,50 @@ func (l *DefaultLogger) Errorf(format string, v ...interface{}) {
 	fmt.Printf(format, v...)
 	fmt.Println()
 }
+
+func (fn LogFunc) Debug(msg string, kvs ...interface{}) {
+	fn(DebugLevel, msg, kvs...)
+}
+
+func (fn LogFunc) Info(msg string, kvs ...interface{}) {
+	fn(InfoLevel, msg, kvs...)
+}
+
+func (fn LogFunc) Error(msg string, kvs ...interface{}) {
+	fn(ErrorLevel, msg, kvs...)
+}
+
+// formatKVs format msg and fields as: msg key1=value1 key2=value2
+func formatKVs(msg string, kvs ...interface{}) string {
+	sb := &strings.Builder{}
+	sb.WriteString(msg)
+	for i := 0; i < len(kvs); i += 2 {
+		if i+1 < len(kvs) {
+			sb.WriteString(fmt.Sprintf(" %v=%v", kvs[i], kvs[i+1]))
+		} else {
+			sb.WriteString(fmt.Sprintf(" %v=!MISSING", kvs[i]))
+		}
+	}
+	return sb.String()
+}
+
+func (fallbackLogger) Debug(msg string, kvs ...interface{}) {
+	Logger.Debugf("%s", formatKVs(msg, kvs...))
+}
+
+func (fallbackLogger) Info(msg string, kvs ...interface{}) {
+	Logger.Infof("%s", formatKVs(msg, kvs...))
+}
+
+func (fallbackLogger) Error(msg string, kvs ...interface{}) {
+	Logger.Errorf("%s", formatKVs(msg, kvs...))
+}
+
+// LoggerOrDefault return the injected logger, fallback to global Logger when not injected,
+// used by indexes outside this package to honor injected logger the same way
+func LoggerOrDefault(logger StructuredLogger) StructuredLogger {
+	if logger == nil {
+		return defaultStructuredLogger
+	}
+	return logger
+}
@@ -0,0 +1,132 @@
+package be_indexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+type recordLogger struct {
+	lines []string
+}
+
+func (l *recordLogger) Debugf(format string, v ...interface{}) {
+	l.lines = append(l.lines, "D "+fmt.Sprintf(format, v...))
+}
+
+func (l *recordLogger) Infof(format string, v ...interface{}) {
+	l.lines = append(l.lines, "I "+fmt.Sprintf(format, v...))
+}
+
+func (l *recordLogger) Errorf(format string, v ...interface{}) {
+	l.lines = append(l.lines, "E "+fmt.Sprintf(format, v...))
+}
+
+// loggerAwareHolder default holder record the logger injected by index
+type loggerAwareHolder struct {
+	*DefaultEntriesHolder
+	injected *StructuredLogger
+}
+
+func (h *loggerAwareHolder) SetLogger(logger StructuredLogger) {
+	*h.injected = logger
+}
+
+type logRecord struct {
+	level int
+	msg   string
+	kvs   map[string]interface{}
+}
+
+func TestStructuredLogger(t *testing.T) {
+	convey.Convey("test fallback to global logger", t, func() {
+		global := &recordLogger{}
+		origin := Logger
+		Logger = global
+		defer func() { Logger = origin }()
+
+		convey.So(formatKVs("msg", "field", "age", "k", 2, "docID"), convey.ShouldEqual, "msg field=age k=2 docID=!MISSING")
+
+		LoggerOrDefault(nil).Error("fetch fail", "field", "age")
+		convey.So(global.lines, convey.ShouldResemble, []string{"E fetch fail field=age"})
+	})
+
+	convey.Convey("test logger injected into builder and index", t, func() {
+		global := &recordLogger{}
+		origin := Logger
+		Logger = global
+		defer func() { Logger = origin }()
+
+		var records []logRecord
+		logger := LogFunc(func(level int, msg string, kvs ...interface{}) {
+			record := logRecord{level: level, msg: msg, kvs: map[string]interface{}{}}
+			for i := 0; i+1 < len(kvs); i += 2 {
+				record.kvs[kvs[i].(string)] = kvs[i+1]
+			}
+			records = append(records, record)
+		})
+
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(WithLogger(logger)), NewCompactIndexerBuilder(WithLogger(logger))} {
+			records = records[:0]
+			builder.ConfigField("age", FieldOption{})
+			convey.So(records[0].level, convey.ShouldEqual, DebugLevel)
+			convey.So(records[0].kvs["field"], convey.ShouldEqual, BEField("age"))
+
+			convey.So(builder.AddDocument(NewDocument(1).AddConjunction(NewConjunction().In("age", []int{1}))), convey.ShouldBeNil)
+			index := builder.BuildIndex()
+
+			records = records[:0]
+			result, err := index.Retrieve(Assignments{"age": 1}, WithStepDetail())
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(result, convey.ShouldResemble, DocIDList{1})
+			convey.So(len(records), convey.ShouldBeGreaterThan, 0)
+
+			stepWithK := 0
+			for _, record := range records {
+				if _, ok := record.kvs["k"]; ok && record.level == InfoLevel {
+					stepWithK++
+				}
+			}
+			convey.So(stepWithK, convey.ShouldBeGreaterThan, 0)
+		}
+		convey.So(global.lines, convey.ShouldBeEmpty)
+	})
+	convey.Convey("test logger injected into holders and evaluation", t, func() {
+		global := &recordLogger{}
+		origin := Logger
+		Logger = global
+		defer func() { Logger = origin }()
+
+		var injected StructuredLogger
+		RegisterEntriesHolder("logger_aware", func() EntriesHolder {
+			return &loggerAwareHolder{DefaultEntriesHolder: NewDefaultEntriesHolder(), injected: &injected}
+		})
+
+		var errors []string
+		logger := LogFunc(func(level int, msg string, kvs ...interface{}) {
+			if level == ErrorLevel {
+				errors = append(errors, msg)
+			}
+		})
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(WithLogger(logger)), NewCompactIndexerBuilder(WithLogger(logger))} {
+			injected = nil
+			builder.ConfigField("age", FieldOption{Container: "logger_aware"})
+			convey.So(builder.AddDocument(NewDocument(1).AddConjunction(NewConjunction().In("age", []int{1}))), convey.ShouldBeNil)
+			_ = builder.BuildIndex()
+			convey.So(injected, convey.ShouldNotBeNil)
+		}
+
+		injected = nil
+		opt := &EvalOptions{
+			FieldConfig: map[BEField]FieldOption{"age": {Container: "logger_aware"}, "city": {Container: "not_registered"}},
+			Logger:      logger,
+		}
+		convey.So(NewConjunction().In("age", []int{1}).Evaluate(Assignments{"age": 1}, opt), convey.ShouldBeTrue)
+		convey.So(injected, convey.ShouldNotBeNil)
+
+		convey.So(NewConjunction().In("city", []string{"bj"}).Evaluate(Assignments{"city": "bj"}, opt), convey.ShouldBeFalse)
+		convey.So(errors, convey.ShouldResemble, []string{"evaluate conjunction fail"})
+		convey.So(global.lines, convey.ShouldBeEmpty)
+	})
+}
@@ -24,7 +24,7 @@ func NewCommonParser() *CommonStrParser {
 }
 
 func (p *CommonStrParser) Name() string {
-	return "common"
+	return ParserNameCommon
 }
 
 func (p *CommonStrParser) ParseAssign(v interface{}) (values []uint64, e error) {
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -64,7 +65,12 @@ func (p *GeoOption) InitDefault() {
 }
 
 func (p *GeoHashParser) Name() string {
-	return "geohash"
+	return ParserNameGeoHash
+}
+
+// Settings GeoOption in json, see SettingsParser
+func (p *GeoHashParser) Settings() ([]byte, error) {
+	return json.Marshal(p.GeoOption)
 }
 
 // lat:lon:radius
@@ -27,7 +27,7 @@ func NewNumberParser2(f2i bool) *NumberParser {
 }
 
 func (p *NumberParser) Name() string {
-	return "number"
+	return ParserNameNumber
 }
 
 // TokenizeAssign implements ValueTokenizer for query phase
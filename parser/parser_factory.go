@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	ParserNameCommon      = "common"
+	ParserNameNumber      = "number"
+	ParserNameNumberRange = "number_range"
+	ParserNameStrHash     = "str_hash"
+	ParserNameGeoHash     = "geohash"
+)
+
+type (
+	// ParserBuilderFunc create a parser with default setting, used for restoring a
+	// parser from its name(ValueIDGenerator.Name()) when loading a persisted index
+	ParserBuilderFunc func() ValueIDGenerator
+
+	// ParserSettingsBuilderFunc create a parser from settings returned by SettingsParser.Settings
+	ParserSettingsBuilderFunc func(settings []byte) (ValueIDGenerator, error)
+
+	// SettingsParser parser has settings need persisting together with index, eg: GeoHashParser
+	SettingsParser interface {
+		ValueIDGenerator
+
+		// Settings serialized settings, restored by the builder registered with RegisterParserSettings
+		Settings() ([]byte, error)
+	}
+)
+
+var (
+	parserFactory = map[string]ParserBuilderFunc{}
+
+	parserSettingsFactory = map[string]ParserSettingsBuilderFunc{}
+)
+
+func init() {
+	parserFactory[ParserNameCommon] = func() ValueIDGenerator {
+		return NewCommonParser()
+	}
+	parserFactory[ParserNameNumber] = func() ValueIDGenerator {
+		return NewNumberParser()
+	}
+	parserFactory[ParserNameNumberRange] = func() ValueIDGenerator {
+		return NewNumRangeParser()
+	}
+	parserFactory[ParserNameStrHash] = func() ValueIDGenerator {
+		return NewStrHashParser()
+	}
+	parserFactory[ParserNameGeoHash] = func() ValueIDGenerator {
+		return NewGeoHashParser(nil)
+	}
+	parserSettingsFactory[ParserNameGeoHash] = func(settings []byte) (ValueIDGenerator, error) {
+		option := &GeoOption{}
+		if err := json.Unmarshal(settings, option); err != nil {
+			return nil, err
+		}
+		return NewGeoHashParser(option), nil
+	}
+}
+
+// RegisterParser register a parser builder, return false if name already registered
+func RegisterParser(name string, fn ParserBuilderFunc) bool {
+	if _, ok := parserFactory[name]; ok {
+		return false
+	}
+	parserFactory[name] = fn
+	return true
+}
+
+// NewParser create a parser by name, return nil if name not registered
+func NewParser(name string) ValueIDGenerator {
+	if fn, ok := parserFactory[name]; ok {
+		return fn()
+	}
+	return nil
+}
+
+// RegisterParserSettings register a builder restoring parser from its settings, return false if name already registered
+func RegisterParserSettings(name string, fn ParserSettingsBuilderFunc) bool {
+	if _, ok := parserSettingsFactory[name]; ok {
+		return false
+	}
+	parserSettingsFactory[name] = fn
+	return true
+}
+
+// NewParserWithSettings create a parser by name and settings, empty settings same as NewParser;
+// error when parser or its settings builder not registered
+func NewParserWithSettings(name string, settings []byte) (ValueIDGenerator, error) {
+	if len(settings) == 0 {
+		if p := NewParser(name); p != nil {
+			return p, nil
+		}
+		return nil, fmt.Errorf("parser:%s not registered", name)
+	}
+	fn, ok := parserSettingsFactory[name]
+	if !ok {
+		return nil, fmt.Errorf("parser:%s settings builder not registered", name)
+	}
+	return fn(settings)
+}
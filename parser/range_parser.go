@@ -58,7 +58,7 @@ func (rgd *RangeDesc) Values() (start, end, stepLen int64) {
 }
 
 func (p *NumberRangeParser) Name() string {
-	return "number_range"
+	return ParserNameNumberRange
 }
 
 // ParseAssign only single number supported, float will round into integer
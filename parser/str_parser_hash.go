@@ -16,7 +16,7 @@ func NewStrHashParser() ValueIDGenerator {
 }
 
 func (p *StrHashParser) Name() string {
-	return "number_range"
+	return ParserNameStrHash
 }
 
 func (p *StrHashParser) ParseAssign(v interface{}) ([]uint64, error) {
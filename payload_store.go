@@ -0,0 +1,199 @@
+package be_indexer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maxPayloadStoreSize protect from allocating huge memory when loading corrupted data
+const maxPayloadStoreSize = 1 << 30
+
+type (
+	payloadSpan struct {
+		offset int
+		size   int
+	}
+
+	// PayloadStore opaque document payloads(eg: bid, creative id) stored compactly in one arena,
+	// built together with index and read only after index built; replaced payload is not reclaimed
+	PayloadStore struct {
+		arena []byte
+		spans map[DocID]payloadSpan
+	}
+
+	// DocPayload document id with its payload, payload is nil if document has no payload
+	DocPayload struct {
+		ID      DocID
+		Payload []byte
+	}
+
+	// PayloadCollector collect documents into downstream collector(DocIDCollector if nil)
+	// and return payloads alongside document ids, avoid a second lookup for document metadata
+	PayloadCollector struct {
+		collector ResultCollector
+
+		store *PayloadStore
+	}
+)
+
+func NewPayloadStore() *PayloadStore {
+	return &PayloadStore{
+		spans: map[DocID]payloadSpan{},
+	}
+}
+
+// Set copy payload into store, empty payload remove the document from store
+func (s *PayloadStore) Set(id DocID, payload []byte) {
+	if len(payload) == 0 {
+		delete(s.spans, id)
+		return
+	}
+	s.spans[id] = payloadSpan{offset: len(s.arena), size: len(payload)}
+	s.arena = append(s.arena, payload...)
+}
+
+// Get return payload of document, the result is shared with store and should not be modified
+func (s *PayloadStore) Get(id DocID) ([]byte, bool) {
+	if s == nil {
+		return nil, false
+	}
+	span, ok := s.spans[id]
+	if !ok {
+		return nil, false
+	}
+	return s.arena[span.offset : span.offset+span.size : span.offset+span.size], true
+}
+
+// Len count of documents has payload
+func (s *PayloadStore) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.spans)
+}
+
+// WriteTo serialize payloads(little endian): | docCnt | docID | size | payload |...,
+// documents are written in ascending order and replaced payloads are dropped
+func (s *PayloadStore) WriteTo(w io.Writer) (int64, error) {
+	ids := make(DocIDList, 0, s.Len())
+	if s != nil {
+		for id := range s.spans {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(ids)
+
+	var total int64
+	buf := make([]byte, 12)
+	binary.LittleEndian.PutUint32(buf[:4], uint32(len(ids)))
+	n, err := w.Write(buf[:4])
+	if total += int64(n); err != nil {
+		return total, err
+	}
+	for _, id := range ids {
+		payload, _ := s.Get(id)
+		binary.LittleEndian.PutUint64(buf[:8], uint64(id))
+		binary.LittleEndian.PutUint32(buf[8:], uint32(len(payload)))
+		if n, err = w.Write(buf); err == nil {
+			total += int64(n)
+			n, err = w.Write(payload)
+		}
+		if total += int64(n); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom load payloads serialized by WriteTo, existing payloads are kept unless replaced
+func (s *PayloadStore) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	buf := make([]byte, 12)
+	n, err := io.ReadFull(r, buf[:4])
+	if total += int64(n); err != nil {
+		return total, err
+	}
+	cnt := binary.LittleEndian.Uint32(buf[:4])
+	for i := uint32(0); i < cnt; i++ {
+		n, err = io.ReadFull(r, buf)
+		if total += int64(n); err != nil {
+			return total, err
+		}
+		id := DocID(binary.LittleEndian.Uint64(buf[:8]))
+		size := binary.LittleEndian.Uint32(buf[8:])
+		if !ValidDocID(id) || size > maxPayloadStoreSize || len(s.arena)+int(size) > maxPayloadStoreSize {
+			return total, fmt.Errorf("bad serialized payload, doc:%d size:%d", id, size)
+		}
+		offset := len(s.arena)
+		s.arena = append(s.arena, make([]byte, size)...)
+		n, err = io.ReadFull(r, s.arena[offset:])
+		if total += int64(n); err != nil {
+			s.arena = s.arena[:offset]
+			return total, err
+		}
+		s.spans[id] = payloadSpan{offset: offset, size: int(size)}
+	}
+	return total, nil
+}
+
+func NewPayloadCollector(collector ResultCollector, store *PayloadStore) *PayloadCollector {
+	if collector == nil {
+		collector = NewDocIDCollector()
+	}
+	return &PayloadCollector{
+		collector: collector,
+		store:     store,
+	}
+}
+
+func (c *PayloadCollector) Add(docID DocID, conj ConjID) {
+	c.collector.Add(docID, conj)
+}
+
+// TryAdd forward the acceptance of downstream collector
+func (c *PayloadCollector) TryAdd(docID DocID, conj ConjID) bool {
+	return tryAdd(c.collector, docID, conj)
+}
+
+// Done forward the stop signal of downstream collector
+func (c *PayloadCollector) Done() bool {
+	stopper, ok := c.collector.(StoppableCollector)
+	return ok && stopper.Done()
+}
+
+func (c *PayloadCollector) GetDocIDs() (ids DocIDList) {
+	return c.collector.GetDocIDs()
+}
+
+func (c *PayloadCollector) GetDocIDsInto(ids *DocIDList) {
+	c.collector.GetDocIDsInto(ids)
+}
+
+// GetPayloads return collected documents with payloads, in the order of GetDocIDs
+func (c *PayloadCollector) GetPayloads() []DocPayload {
+	ids := c.collector.GetDocIDs()
+	res := make([]DocPayload, 0, len(ids))
+	for _, id := range ids {
+		payload, _ := c.store.Get(id)
+		res = append(res, DocPayload{ID: id, Payload: payload})
+	}
+	return res
+}
+
+// RetrieveWithPayloads retrieve documents with payloads stored in index, index must implement PayloadIndex
+func RetrieveWithPayloads(index BEIndex, queries Assignments, opts ...IndexOpt) ([]DocPayload, error) {
+	payloadIndex, ok := index.(PayloadIndex)
+	if !ok {
+		return nil, fmt.Errorf("index:%T not hold payloads", index)
+	}
+	collector := PickCollector()
+	defer PutCollector(collector)
+
+	payloadCollector := NewPayloadCollector(collector, payloadIndex.Payloads())
+	if err := index.RetrieveWithCollector(queries, payloadCollector, opts...); err != nil {
+		return nil, err
+	}
+	return payloadCollector.GetPayloads(), nil
+}
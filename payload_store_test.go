@@ -0,0 +1,86 @@
+package be_indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestPayloadStore(t *testing.T) {
+	convey.Convey("test payload store", t, func() {
+		store := NewPayloadStore()
+		payload := []byte("bid:12")
+		store.Set(1, payload)
+		store.Set(2, []byte("bid:5"))
+		payload[0] = 'x' // store hold its own copy
+
+		v, ok := store.Get(1)
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(string(v), convey.ShouldEqual, "bid:12")
+		v, _ = store.Get(2)
+		convey.So(string(v), convey.ShouldEqual, "bid:5")
+
+		store.Set(1, []byte("bid:13"))
+		v, _ = store.Get(1)
+		convey.So(string(v), convey.ShouldEqual, "bid:13")
+		convey.So(store.Len(), convey.ShouldEqual, 2)
+
+		store.Set(2, nil)
+		_, ok = store.Get(2)
+		convey.So(ok, convey.ShouldBeFalse)
+		convey.So(store.Len(), convey.ShouldEqual, 1)
+
+		buf := &bytes.Buffer{}
+		n, err := store.WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, buf.Len())
+
+		data := buf.Bytes()
+		loaded := NewPayloadStore()
+		_, err = loaded.ReadFrom(bytes.NewReader(data))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(loaded.Len(), convey.ShouldEqual, 1)
+		v, _ = loaded.Get(1)
+		convey.So(string(v), convey.ShouldEqual, "bid:13")
+		_, err = NewPayloadStore().ReadFrom(bytes.NewReader(data[:len(data)-1]))
+		convey.So(err, convey.ShouldNotBeNil)
+
+		var nilStore *PayloadStore
+		_, ok = nilStore.Get(1)
+		convey.So(ok, convey.ShouldBeFalse)
+		convey.So(nilStore.Len(), convey.ShouldEqual, 0)
+	})
+
+	convey.Convey("test retrieve with payloads", t, func() {
+		doc := &Document{}
+		err := json.Unmarshal([]byte(`{"id":1,"cons":[{"exprs":{"age":[{"value":[1],"inc":true}]}}],"payload":"YmlkOjEy"}`), doc)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(string(doc.Payload), convey.ShouldEqual, "bid:12")
+
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+			convey.So(builder.AddDocument(NewDocument(2).AddConjunction(NewConjunction().In("age", []int64{1, 2}))), convey.ShouldBeNil)
+			convey.So(builder.AddDocument(NewDocument(3).AddConjunction(NewConjunction().In("age", []int64{2}))), convey.ShouldBeNil)
+
+			index := builder.BuildIndex()
+			convey.So(index.(PayloadIndex).Payloads().Len(), convey.ShouldEqual, 1)
+
+			results, err := RetrieveWithPayloads(index, Assignments{"age": []int64{1}})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(results, convey.ShouldResemble, []DocPayload{
+				{ID: 1, Payload: []byte("bid:12")},
+				{ID: 2, Payload: nil},
+			})
+		}
+	})
+
+	convey.Convey("test payload collector forward done", t, func() {
+		collector := NewPayloadCollector(NewLimitCollector(1, nil), nil)
+		convey.So(collector.Done(), convey.ShouldBeFalse)
+		collector.Add(1, NewConjID(1, 0, 1))
+		convey.So(collector.Done(), convey.ShouldBeTrue)
+		convey.So(collector.GetPayloads(), convey.ShouldResemble, []DocPayload{{ID: 1}})
+	})
+}
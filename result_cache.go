@@ -0,0 +1,376 @@
+package be_indexer
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/echoface/be_indexer/parser"
+	"github.com/echoface/be_indexer/util"
+)
+
+const (
+	defaultResultCacheCapacity = 10000
+)
+
+type (
+	// ResultCacheOpt option for NewResultCache
+	ResultCacheOpt func(c *ResultCache)
+
+	// ResultCacheStats statistics of ResultCache, Bypass count queries can't be cached,
+	// eg: retrieve with options or values can't be normalized
+	ResultCacheStats struct {
+		Hits      int64 `json:"hits"`
+		Misses    int64 `json:"misses"`
+		Bypass    int64 `json:"bypass"`
+		Evictions int64 `json:"evictions"`
+		Entries   int   `json:"entries"`
+	}
+
+	resultCacheEntry struct {
+		key      string
+		result   DocIDList
+		expireAt time.Time
+	}
+
+	// ResultCache LRU cache of retrieve results in front of BEIndex, queries are keyed by normalized
+	// assignments: fields sorted, values flattened/sorted/deduplicated, empty fields and fields no
+	// document references are ignored. values of fields held by DefaultEntriesHolder are normalized
+	// into the sorted unique tokens the holder looks up(eg: 5 and "5" share one key); other holders
+	// tokenize queries in their own way and may depend on the order of values, so values of those
+	// fields are normalized by raw form(integers, strings...) in the original order, which may miss
+	// some equivalent queries but never mix up different ones.
+	//
+	// the index retrieved from can be replaced by SwapIndex, all cached results are dropped at the same time,
+	// so replacing index through the cache keep cached results consistent with index
+	ResultCache struct {
+		mu sync.Mutex
+
+		index BEIndex
+
+		// generation increased when index swapped, results retrieved from previous index are not cached
+		generation uint64
+
+		capacity int
+		ttl      time.Duration
+		now      func() time.Time
+
+		lru   *list.List
+		items map[string]*list.Element
+
+		stats ResultCacheStats
+	}
+
+	// fieldRecognizer implemented by indexes of this package, used to ignore fields no document references
+	fieldRecognizer interface {
+		recognizedField(field BEField) bool
+	}
+
+	// fieldTokenizer implemented by indexes of this package, return the tokenizer used to look up
+	// query values of field, nil if values are not looked up by tokens
+	fieldTokenizer interface {
+		queryTokenizer(field BEField) parser.ValueTokenizer
+	}
+)
+
+// WithCacheCapacity max count of cached queries, least recently used query evicted when exceeded
+func WithCacheCapacity(capacity int) ResultCacheOpt {
+	return func(c *ResultCache) {
+		c.capacity = capacity
+	}
+}
+
+// WithCacheTTL cached result expired after ttl, zero means never expired
+func WithCacheTTL(ttl time.Duration) ResultCacheOpt {
+	return func(c *ResultCache) {
+		c.ttl = ttl
+	}
+}
+
+func (bi *indexBase) recognizedField(field BEField) bool {
+	_, ok := bi.fieldsData[field]
+	return ok
+}
+
+func (bi *KGroupsBEIndex) queryTokenizer(field BEField) parser.ValueTokenizer {
+	desc, ok := bi.fieldsData[field]
+	if !ok {
+		return nil
+	}
+	for _, container := range bi.kSizeContainers {
+		if tokenizer := container.queryTokenizer(desc); tokenizer != nil {
+			return tokenizer
+		}
+	}
+	return nil
+}
+
+func (bi *CompactBEIndex) queryTokenizer(field BEField) parser.ValueTokenizer {
+	if desc, ok := bi.fieldsData[field]; ok {
+		return bi.container.queryTokenizer(desc)
+	}
+	return nil
+}
+
+// queryTokenizer only DefaultEntriesHolder is known to look up query values by tokens exactly
+func (c *EntriesContainer) queryTokenizer(desc *FieldDesc) parser.ValueTokenizer {
+	if holder, ok := c.GetHolder(desc).(*DefaultEntriesHolder); ok {
+		return holder.GetTokenizer(desc.Field)
+	}
+	return nil
+}
+
+func NewResultCache(index BEIndex, opts ...ResultCacheOpt) *ResultCache {
+	util.PanicIf(index == nil, "nil index is not allowed")
+	c := &ResultCache{
+		index:    index,
+		capacity: defaultResultCacheCapacity,
+		now:      time.Now,
+		lru:      list.New(),
+		items:    map[string]*list.Element{},
+	}
+	for _, fn := range opts {
+		fn(c)
+	}
+	util.PanicIf(c.capacity <= 0, "cache capacity:%d must be positive", c.capacity)
+	return c
+}
+
+// Index return the index current retrieved from
+func (c *ResultCache) Index() BEIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.index
+}
+
+// SwapIndex replace the index and drop all cached results, return the previous index
+func (c *ResultCache) SwapIndex(index BEIndex) BEIndex {
+	util.PanicIf(index == nil, "nil index is not allowed")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.index
+	c.index = index
+	c.generation++
+	c.clear()
+	return old
+}
+
+// Purge drop all cached results
+func (c *ResultCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+}
+
+func (c *ResultCache) clear() {
+	c.lru.Init()
+	c.items = map[string]*list.Element{}
+}
+
+func (c *ResultCache) Stats() ResultCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// Retrieve same as BEIndex.Retrieve, the result is owned by caller; retrieving with options
+// (eg: WithAllowDocs) is not cached because the result depends on them
+func (c *ResultCache) Retrieve(queries Assignments, opts ...IndexOpt) (DocIDList, error) {
+	c.mu.Lock()
+	index, generation := c.index, c.generation
+	c.mu.Unlock()
+
+	key, ok := "", len(opts) == 0
+	if ok {
+		key, ok = NormalizeAssignments(queries, index)
+	}
+	if !ok {
+		c.mu.Lock()
+		c.stats.Bypass++
+		c.mu.Unlock()
+		return index.Retrieve(queries, opts...)
+	}
+
+	if result, hit := c.get(key); hit {
+		return result, nil
+	}
+
+	result, err := index.Retrieve(queries)
+	if err != nil {
+		return nil, err
+	}
+	c.put(generation, key, result)
+	return result, nil
+}
+
+func (c *ResultCache) get(key string) (DocIDList, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := elem.Value.(*resultCacheEntry)
+	if c.ttl > 0 && !c.now().Before(entry.expireAt) {
+		c.removeElement(elem)
+		c.stats.Misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+	return append(DocIDList(nil), entry.result...), true
+}
+
+func (c *ResultCache) put(generation uint64, key string, result DocIDList) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation { // index swapped while retrieving
+		return
+	}
+	entry := &resultCacheEntry{
+		key:    key,
+		result: append(DocIDList(nil), result...),
+	}
+	if c.ttl > 0 {
+		entry.expireAt = c.now().Add(c.ttl)
+	}
+	if elem, ok := c.items[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.capacity {
+		c.removeElement(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *ResultCache) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*resultCacheEntry).key)
+}
+
+// NormalizeAssignments build a canonical key of assignments, queries with the same key always
+// have the same result; fields not recognized by index(if index can tell) are ignored.
+// return false when some value can't be normalized, eg: struct values of custom holders
+func NormalizeAssignments(queries Assignments, index BEIndex) (string, bool) {
+	recognizer, _ := index.(fieldRecognizer)
+	tokenizer, _ := index.(fieldTokenizer)
+
+	fields := make([]BEField, 0, len(queries))
+	for field, values := range queries {
+		if util.NilInterface(values) {
+			continue
+		}
+		if recognizer != nil && !recognizer.recognizedField(field) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i] < fields[j]
+	})
+
+	sb := &strings.Builder{}
+	for _, field := range fields {
+		var valueTokenizer parser.ValueTokenizer
+		if tokenizer != nil {
+			valueTokenizer = tokenizer.queryTokenizer(field)
+		}
+		tokens, ok := normalizeValues(queries[field], valueTokenizer)
+		if !ok {
+			return "", false
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		sb.WriteString(strconv.Quote(string(field)))
+		sb.WriteString(":[")
+		sb.WriteString(strings.Join(tokens, ","))
+		sb.WriteString("];")
+	}
+	return sb.String(), true
+}
+
+// normalizeValues normalize values into tokens of key. values are tokenized by tokenizer if specified,
+// tokens are sorted and deduplicated because holder look up each token independently; otherwise values
+// are normalized by their raw form in the original order, because holders like ac_matcher(join values
+// into one content) and box_range(a point is a list of coordinates) depend on the order
+func normalizeValues(values Values, tokenizer parser.ValueTokenizer) ([]string, bool) {
+	if tokenizer == nil {
+		return normalizeRawValues(values)
+	}
+	raw, err := tokenizer.TokenizeAssign(values)
+	if err != nil {
+		return nil, false
+	}
+	tokens := make([]string, 0, len(raw))
+	for _, token := range raw {
+		tokens = append(tokens, "t"+strconv.Quote(token))
+	}
+	sort.Strings(tokens)
+
+	unique := tokens[:0]
+	for i, token := range tokens {
+		if i == 0 || token != tokens[i-1] {
+			unique = append(unique, token)
+		}
+	}
+	return unique, true
+}
+
+// normalizeRawValues normalize values by raw form in order, a list is marked with a leading "l"
+// so a scalar is never mixed up with the list contains it only
+func normalizeRawValues(values Values) ([]string, bool) {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		token, ok := normalizeValue(rv)
+		return []string{token}, ok
+	}
+	tokens := make([]string, 0, rv.Len()+1)
+	tokens = append(tokens, "l")
+	for i := 0; i < rv.Len(); i++ {
+		token, ok := normalizeValue(rv.Index(i))
+		if !ok {
+			return nil, false
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, true
+}
+
+func normalizeValue(rv reflect.Value) (string, bool) {
+	if rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "i" + strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v := rv.Uint(); v > math.MaxInt64 {
+			return "u" + strconv.FormatUint(v, 10), true
+		}
+		return "i" + strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return "f" + strconv.FormatFloat(rv.Float(), 'g', -1, 64), true
+	case reflect.String:
+		return strconv.Quote(rv.String()), true
+	case reflect.Bool:
+		return fmt.Sprintf("b%t", rv.Bool()), true
+	}
+	return "", false
+}
@@ -0,0 +1,143 @@
+package be_indexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/echoface/be_indexer/util"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func buildResultCacheTestIndex(docs ...*Document) BEIndex {
+	builder := NewIndexerBuilder()
+	builder.ConfigField("age", FieldOption{Container: HolderNameDefault})
+	builder.ConfigField("os", FieldOption{Container: HolderNameDefault})
+	for _, doc := range docs {
+		util.PanicIfErr(builder.AddDocument(doc), "add document fail")
+	}
+	return builder.BuildIndex()
+}
+
+func TestNormalizeAssignments(t *testing.T) {
+	convey.Convey("test assignments normalized into same key", t, func() {
+		index := buildResultCacheTestIndex(NewDocument(1).AddConjunction(NewConjunction().In("age", []int{1})))
+
+		key, ok := NormalizeAssignments(Assignments{"age": []int{3, 1, 3}, "os": "ios"}, index)
+		convey.So(ok, convey.ShouldBeTrue)
+		for _, assigns := range []Assignments{
+			{"os": []string{"ios"}, "age": []int64{1, 3}},
+			{"age": []interface{}{int8(3), uint(1)}, "os": []string{"ios", "ios"}, "unknown": 1},
+			{"age": []int{1, 3}, "os": "ios", "city": nil},
+		} {
+			other, ok := NormalizeAssignments(assigns, index)
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(other, convey.ShouldEqual, key)
+		}
+
+		// values tokenized by default holder
+		other, _ := NormalizeAssignments(Assignments{"age": []string{"1", "3"}, "os": "ios"}, index)
+		convey.So(other, convey.ShouldEqual, key)
+		other, _ = NormalizeAssignments(Assignments{"age": []int{1, 3}}, index)
+		convey.So(other, convey.ShouldNotEqual, key)
+
+		_, ok = NormalizeAssignments(Assignments{"age": struct{}{}}, index)
+		convey.So(ok, convey.ShouldBeFalse)
+	})
+
+	convey.Convey("test values of not tokenized field normalized by raw form", t, func() {
+		// holder wrapping DefaultEntriesHolder is not known to look up values by tokens
+		RegisterEntriesHolder("result_cache_wrapped", func() EntriesHolder {
+			return struct{ *DefaultEntriesHolder }{NewDefaultEntriesHolder()}
+		})
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			builder.ConfigField("age", FieldOption{Container: HolderNameDefault})
+			builder.ConfigField("label", FieldOption{Container: "result_cache_wrapped"})
+			doc := NewDocument(1).AddConjunction(NewConjunction().In("age", []int{5}).In("label", []int{6}))
+			convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+			index := builder.BuildIndex()
+
+			key, _ := NormalizeAssignments(Assignments{"age": 5, "label": 6}, index)
+			other, _ := NormalizeAssignments(Assignments{"age": "5", "label": 6}, index)
+			convey.So(other, convey.ShouldEqual, key)
+			other, _ = NormalizeAssignments(Assignments{"age": 5, "label": "6"}, index)
+			convey.So(other, convey.ShouldNotEqual, key)
+
+			cache := NewResultCache(index)
+			for _, assigns := range []Assignments{{"age": 5, "label": 6}, {"age": "5", "label": 6}, {"age": 5, "label": "6"}} {
+				result, err := cache.Retrieve(assigns)
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(result, convey.ShouldResemble, DocIDList{1})
+			}
+			convey.So(cache.Stats().Hits, convey.ShouldEqual, 1)
+		}
+	})
+}
+
+func TestResultCache(t *testing.T) {
+	docs := []*Document{
+		NewDocument(1).AddConjunction(NewConjunction().In("age", []int{1, 2})),
+		NewDocument(2).AddConjunction(NewConjunction().In("age", []int{2}).NotIn("os", []string{"ios"})),
+		NewDocument(3).AddConjunction(NewConjunction().In("os", []string{"android"})),
+	}
+
+	convey.Convey("test cache hit and lru eviction", t, func() {
+		cache := NewResultCache(buildResultCacheTestIndex(docs...), WithCacheCapacity(2))
+
+		result, err := cache.Retrieve(Assignments{"age": 2, "os": "android"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(result, convey.ShouldResemble, DocIDList{1, 2, 3})
+		result[0] = 100 // result owned by caller
+
+		result, err = cache.Retrieve(Assignments{"os": []string{"android"}, "age": []int{2, 2}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(result, convey.ShouldResemble, DocIDList{1, 2, 3})
+		convey.So(cache.Stats(), convey.ShouldResemble, ResultCacheStats{Hits: 1, Misses: 1, Entries: 1})
+
+		_, _ = cache.Retrieve(Assignments{"age": 1})
+		_, _ = cache.Retrieve(Assignments{"age": 2, "os": "android"}) // refresh
+		_, _ = cache.Retrieve(Assignments{"os": "ios"})               // evict age:1
+		_, _ = cache.Retrieve(Assignments{"age": 2, "os": "android"})
+		_, _ = cache.Retrieve(Assignments{"age": 1})
+		convey.So(cache.Stats(), convey.ShouldResemble, ResultCacheStats{Hits: 3, Misses: 4, Evictions: 2, Entries: 2})
+
+		result, err = cache.Retrieve(Assignments{"age": 2, "os": "android"}, WithDenyDocs(roaring64.BitmapOf(1)))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(result, convey.ShouldResemble, DocIDList{2, 3})
+		convey.So(cache.Stats().Bypass, convey.ShouldEqual, 1)
+	})
+
+	convey.Convey("test cache ttl", t, func() {
+		now := time.Unix(1000, 0)
+		cache := NewResultCache(buildResultCacheTestIndex(docs...), WithCacheTTL(time.Second))
+		cache.now = func() time.Time { return now }
+
+		_, _ = cache.Retrieve(Assignments{"age": 1})
+		now = now.Add(time.Millisecond * 999)
+		_, _ = cache.Retrieve(Assignments{"age": 1})
+		now = now.Add(time.Millisecond)
+		result, err := cache.Retrieve(Assignments{"age": 1})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(result, convey.ShouldResemble, DocIDList{1})
+		convey.So(cache.Stats(), convey.ShouldResemble, ResultCacheStats{Hits: 1, Misses: 2, Entries: 1})
+	})
+
+	convey.Convey("test cache dropped when index swapped", t, func() {
+		cache := NewResultCache(buildResultCacheTestIndex(docs...))
+		result, _ := cache.Retrieve(Assignments{"age": 1})
+		convey.So(result, convey.ShouldResemble, DocIDList{1})
+
+		newIndex := buildResultCacheTestIndex(NewDocument(4).AddConjunction(NewConjunction().In("age", []int{1})))
+		old := cache.SwapIndex(newIndex)
+		convey.So(old, convey.ShouldNotEqual, newIndex)
+		convey.So(cache.Index(), convey.ShouldEqual, newIndex)
+		convey.So(cache.Stats().Entries, convey.ShouldEqual, 0)
+
+		result, _ = cache.Retrieve(Assignments{"age": 1})
+		convey.So(result, convey.ShouldResemble, DocIDList{4})
+
+		// result retrieved from previous index is not cached
+		cache.put(0, "stale", DocIDList{1})
+		convey.So(cache.Stats().Entries, convey.ShouldEqual, 1)
+	})
+}
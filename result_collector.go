@@ -1,6 +1,13 @@
 package be_indexer
 
-import "github.com/RoaringBitmap/roaring/roaring64"
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/echoface/be_indexer/util"
+)
 
 type (
 	ResultCollector interface {
@@ -11,6 +18,23 @@ type (
 		GetDocIDsInto(ids *DocIDList)
 	}
 
+	// StoppableCollector optional interface for ResultCollector, retrieving end up
+	// as soon as Done return true, so scanning stop when collector has enough results
+	StoppableCollector interface {
+		ResultCollector
+
+		Done() bool
+	}
+
+	// AcceptingCollector optional interface for ResultCollector which may reject matched conjunction,
+	// TryAdd same as Add but report whether it accepted by collector(and its downstream); collectors
+	// not implement it are considered accepting everything, LimitCollector count accepted documents only
+	AcceptingCollector interface {
+		ResultCollector
+
+		TryAdd(id DocID, conj ConjID) bool
+	}
+
 	// DocIDCollector Default Collector with removing duplicated doc
 	DocIDCollector struct {
 		// docBits bitmap hold results docs
@@ -18,6 +42,15 @@ type (
 	}
 )
 
+// tryAdd add into collector and report whether it accepted, see AcceptingCollector
+func tryAdd(collector ResultCollector, docID DocID, conj ConjID) bool {
+	if acceptor, ok := collector.(AcceptingCollector); ok {
+		return acceptor.TryAdd(docID, conj)
+	}
+	collector.Add(docID, conj)
+	return true
+}
+
 func NewDocIDCollector() *DocIDCollector {
 	return &DocIDCollector{
 		docBits: roaring64.New(),
@@ -58,3 +91,376 @@ func (c *DocIDCollector) GetDocIDsInto(ids *DocIDList) {
 	}
 	return
 }
+
+type (
+	// ConjunctionCollector record matched conjunctions of each document,
+	// used to tell which conjunction(targeting package) of a document matched
+	ConjunctionCollector struct {
+		docConjs map[DocID][]ConjID
+	}
+)
+
+var conjCollectorPool = sync.Pool{
+	New: func() interface{} {
+		return NewConjunctionCollector()
+	},
+}
+
+func PickConjunctionCollector() *ConjunctionCollector {
+	return conjCollectorPool.Get().(*ConjunctionCollector)
+}
+
+func PutConjunctionCollector(c *ConjunctionCollector) {
+	if c == nil {
+		return
+	}
+	c.Reset()
+	conjCollectorPool.Put(c)
+}
+
+func NewConjunctionCollector() *ConjunctionCollector {
+	return &ConjunctionCollector{
+		docConjs: map[DocID][]ConjID{},
+	}
+}
+
+func (c *ConjunctionCollector) DocCount() int {
+	return len(c.docConjs)
+}
+
+func (c *ConjunctionCollector) Reset() {
+	for id := range c.docConjs {
+		delete(c.docConjs, id)
+	}
+}
+
+func (c *ConjunctionCollector) Add(docID DocID, conj ConjID) {
+	conjs := c.docConjs[docID]
+	for _, id := range conjs {
+		if id == conj {
+			return
+		}
+	}
+	c.docConjs[docID] = append(conjs, conj)
+}
+
+// GetDocIDs return sorted document ids
+func (c *ConjunctionCollector) GetDocIDs() (ids DocIDList) {
+	if c.DocCount() == 0 {
+		return nil
+	}
+	ids = make(DocIDList, 0, c.DocCount())
+	c.GetDocIDsInto(&ids)
+	return ids
+}
+
+func (c *ConjunctionCollector) GetDocIDsInto(ids *DocIDList) {
+	start := len(*ids)
+	for id := range c.docConjs {
+		*ids = append(*ids, id)
+	}
+	sort.Sort((*ids)[start:])
+}
+
+// GetConjunctions return matched conjunctions of each document, conjunctions sorted by index;
+// the result is owned by collector, it's invalid after collector reset/put back to pool
+func (c *ConjunctionCollector) GetConjunctions() map[DocID][]ConjID {
+	for _, conjs := range c.docConjs {
+		sort.Slice(conjs, func(i, j int) bool {
+			return conjs[i].Index() < conjs[j].Index()
+		})
+	}
+	return c.docConjs
+}
+
+// GetConjIDs return all matched conjunctions sorted by document id then conjunction index
+func (c *ConjunctionCollector) GetConjIDs() []ConjID {
+	res := make([]ConjID, 0, len(c.docConjs))
+	for _, conjs := range c.docConjs {
+		res = append(res, conjs...)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].DocID() != res[j].DocID() {
+			return res[i].DocID() < res[j].DocID()
+		}
+		return res[i].Index() < res[j].Index()
+	})
+	return res
+}
+
+type (
+	// LimitCollector stop retrieving after limit unique documents collected, only documents accepted
+	// by downstream are counted(see AcceptingCollector), conjunctions of collected documents are still
+	// forwarded; which documents collected depend on scanning order
+	LimitCollector struct {
+		limit int
+
+		collector ResultCollector
+
+		docs map[DocID]struct{}
+	}
+)
+
+// NewLimitCollector forward at most limit documents into collector, use a DocIDCollector if nil
+func NewLimitCollector(limit int, collector ResultCollector) *LimitCollector {
+	if collector == nil {
+		collector = NewDocIDCollector()
+	}
+	return &LimitCollector{
+		limit:     limit,
+		collector: collector,
+		docs:      map[DocID]struct{}{},
+	}
+}
+
+func (c *LimitCollector) Add(docID DocID, conj ConjID) {
+	c.TryAdd(docID, conj)
+}
+
+// TryAdd a document counted only when downstream accepted it, see AcceptingCollector
+func (c *LimitCollector) TryAdd(docID DocID, conj ConjID) bool {
+	_, collected := c.docs[docID]
+	if !collected && c.Done() {
+		return false
+	}
+	if !tryAdd(c.collector, docID, conj) {
+		return false
+	}
+	c.docs[docID] = struct{}{}
+	return true
+}
+
+func (c *LimitCollector) Done() bool {
+	return len(c.docs) >= c.limit
+}
+
+func (c *LimitCollector) GetDocIDs() (ids DocIDList) {
+	return c.collector.GetDocIDs()
+}
+
+func (c *LimitCollector) GetDocIDsInto(ids *DocIDList) {
+	c.collector.GetDocIDsInto(ids)
+}
+
+// Reset clear collected documents, the underlying collector need be reset by caller
+func (c *LimitCollector) Reset() {
+	for id := range c.docs {
+		delete(c.docs, id)
+	}
+}
+
+type (
+	// DocFilter a named predicate for FilterCollector, return false to reject the matched conjunction
+	DocFilter struct {
+		Name string
+		Fn   func(DocID, ConjID) bool
+	}
+
+	// FilterStat rejection counter of a filter
+	FilterStat struct {
+		Name     string `json:"name"`
+		Rejected int64  `json:"rejected"`
+	}
+
+	// FilterStats statistics of FilterCollector, count by matched conjunction(Add call)
+	FilterStats struct {
+		Passed  int64        `json:"passed"`
+		Filters []FilterStat `json:"filters"`
+	}
+
+	// FilterCollector apply filters chain on each matched conjunction in order, forward it into
+	// downstream collector only if all filters passed; eg: budget check, frequency capping, blocklist
+	FilterCollector struct {
+		collector ResultCollector
+
+		filters []DocFilter
+
+		// counters can be read concurrently for monitoring
+		passed   int64
+		rejected []int64
+	}
+)
+
+// NewFilterCollector wrap downstream collector, use a DocIDCollector if nil
+func NewFilterCollector(collector ResultCollector, filters ...DocFilter) *FilterCollector {
+	if collector == nil {
+		collector = NewDocIDCollector()
+	}
+	c := &FilterCollector{collector: collector}
+	for _, filter := range filters {
+		c.Use(filter.Name, filter.Fn)
+	}
+	return c
+}
+
+// Use append a named filter into chain
+func (c *FilterCollector) Use(name string, fn func(DocID, ConjID) bool) *FilterCollector {
+	util.PanicIf(fn == nil, "filter:%s need a valid predicate", name)
+	c.filters = append(c.filters, DocFilter{Name: name, Fn: fn})
+	c.rejected = append(c.rejected, 0)
+	return c
+}
+
+func (c *FilterCollector) Add(docID DocID, conj ConjID) {
+	c.TryAdd(docID, conj)
+}
+
+// TryAdd report whether the conjunction passed all filters and accepted by downstream
+func (c *FilterCollector) TryAdd(docID DocID, conj ConjID) bool {
+	for i := range c.filters {
+		if !c.filters[i].Fn(docID, conj) {
+			atomic.AddInt64(&c.rejected[i], 1)
+			return false
+		}
+	}
+	atomic.AddInt64(&c.passed, 1)
+	return tryAdd(c.collector, docID, conj)
+}
+
+// Done forward the stop signal of downstream collector
+func (c *FilterCollector) Done() bool {
+	stopper, ok := c.collector.(StoppableCollector)
+	return ok && stopper.Done()
+}
+
+func (c *FilterCollector) GetDocIDs() (ids DocIDList) {
+	return c.collector.GetDocIDs()
+}
+
+func (c *FilterCollector) GetDocIDsInto(ids *DocIDList) {
+	c.collector.GetDocIDsInto(ids)
+}
+
+// Stats return the passed and per filter rejection counters
+func (c *FilterCollector) Stats() FilterStats {
+	stats := FilterStats{
+		Passed:  atomic.LoadInt64(&c.passed),
+		Filters: make([]FilterStat, 0, len(c.filters)),
+	}
+	for i := range c.filters {
+		stats.Filters = append(stats.Filters, FilterStat{
+			Name:     c.filters[i].Name,
+			Rejected: atomic.LoadInt64(&c.rejected[i]),
+		})
+	}
+	return stats
+}
+
+// Reset clear counters, the downstream collector need be reset by caller
+func (c *FilterCollector) Reset() {
+	atomic.StoreInt64(&c.passed, 0)
+	for i := range c.rejected {
+		atomic.StoreInt64(&c.rejected[i], 0)
+	}
+}
+
+type (
+	// GroupKeyFunc return group key of document, ok=false means document not grouped
+	GroupKeyFunc func(id DocID) (key interface{}, ok bool)
+
+	// GroupingCollector collect at most perGroup documents for each group, eg: at most K ads per advertiser;
+	// documents not grouped are always collected, conjunctions of collected documents are still forwarded
+	GroupingCollector struct {
+		collector ResultCollector
+
+		keyFn GroupKeyFunc
+
+		perGroup int
+
+		groups map[interface{}]int
+
+		docs map[DocID]struct{}
+
+		dropped int
+	}
+)
+
+// GroupByAttr group documents by attribute value in store
+func GroupByAttr(store *AttributeStore, attr string) GroupKeyFunc {
+	return func(id DocID) (interface{}, bool) {
+		return store.Value(id, attr)
+	}
+}
+
+// NewGroupingCollector wrap downstream collector(use a DocIDCollector if nil),
+// perGroup=1 make documents deduplicated by group key
+func NewGroupingCollector(collector ResultCollector, keyFn GroupKeyFunc, perGroup int) *GroupingCollector {
+	util.PanicIf(keyFn == nil, "group key function is required")
+	util.PanicIf(perGroup <= 0, "perGroup must be positive")
+	if collector == nil {
+		collector = NewDocIDCollector()
+	}
+	return &GroupingCollector{
+		collector: collector,
+		keyFn:     keyFn,
+		perGroup:  perGroup,
+		groups:    map[interface{}]int{},
+		docs:      map[DocID]struct{}{},
+	}
+}
+
+func (c *GroupingCollector) Add(docID DocID, conj ConjID) {
+	c.TryAdd(docID, conj)
+}
+
+// TryAdd report whether the document kept in its group and accepted by downstream
+func (c *GroupingCollector) TryAdd(docID DocID, conj ConjID) bool {
+	_, collected := c.docs[docID]
+	var key interface{}
+	grouped := false
+	if !collected {
+		if key, grouped = c.keyFn(docID); grouped && c.groups[key] >= c.perGroup {
+			c.dropped++
+			return false
+		}
+	}
+	if !tryAdd(c.collector, docID, conj) {
+		return false
+	}
+	if !collected {
+		if grouped {
+			c.groups[key]++
+		}
+		c.docs[docID] = struct{}{}
+	}
+	return true
+}
+
+// Done forward the stop signal of downstream collector
+func (c *GroupingCollector) Done() bool {
+	stopper, ok := c.collector.(StoppableCollector)
+	return ok && stopper.Done()
+}
+
+func (c *GroupingCollector) GetDocIDs() (ids DocIDList) {
+	return c.collector.GetDocIDs()
+}
+
+func (c *GroupingCollector) GetDocIDsInto(ids *DocIDList) {
+	c.collector.GetDocIDsInto(ids)
+}
+
+// GroupCounts return collected document count of each group
+func (c *GroupingCollector) GroupCounts() map[interface{}]int {
+	res := make(map[interface{}]int, len(c.groups))
+	for key, cnt := range c.groups {
+		res[key] = cnt
+	}
+	return res
+}
+
+// Dropped count of matched conjunctions dropped because its group is full
+func (c *GroupingCollector) Dropped() int {
+	return c.dropped
+}
+
+// Reset clear groups, the downstream collector need be reset by caller
+func (c *GroupingCollector) Reset() {
+	c.dropped = 0
+	for key := range c.groups {
+		delete(c.groups, key)
+	}
+	for id := range c.docs {
+		delete(c.docs, id)
+	}
+}
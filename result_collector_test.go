@@ -0,0 +1,235 @@
+package be_indexer
+
+import (
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestConjunctionCollector(t *testing.T) {
+	convey.Convey("test collect matched conjunctions", t, func() {
+		docs := []*Document{
+			NewDocument(1).AddConjunction(
+				NewConjunction().In("age", []int64{1, 2}),
+				NewConjunction().In("city", []string{"bj"}),
+				NewConjunction().In("city", []string{"sh"}),
+			),
+			NewDocument(2).AddConjunction(NewConjunction().NotIn("age", []int64{3})),
+			NewDocument(3).AddConjunction(NewConjunction().In("age", []int64{3})),
+		}
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			convey.So(builder.AddDocument(docs...), convey.ShouldBeNil)
+			index := builder.BuildIndex()
+
+			collector := PickConjunctionCollector()
+			err := index.RetrieveWithCollector(Assignments{"age": 1, "city": "bj"}, collector)
+			convey.So(err, convey.ShouldBeNil)
+
+			convey.So(collector.GetDocIDs(), convey.ShouldResemble, DocIDList{1, 2})
+			conjs := collector.GetConjunctions()
+			convey.So(len(conjs), convey.ShouldEqual, 2)
+			convey.So(len(conjs[1]), convey.ShouldEqual, 2)
+			convey.So(conjs[1][0].Index(), convey.ShouldEqual, 0)
+			convey.So(conjs[1][1].Index(), convey.ShouldEqual, 1)
+			convey.So(conjs[2][0].Index(), convey.ShouldEqual, 0)
+
+			flat := collector.GetConjIDs()
+			convey.So(len(flat), convey.ShouldEqual, 3)
+			convey.So(flat[0], convey.ShouldEqual, conjs[1][0])
+			convey.So(flat[2].DocID(), convey.ShouldEqual, 2)
+
+			collector.Add(1, conjs[1][0]) // duplicated conjunction ignored
+			convey.So(len(collector.GetConjIDs()), convey.ShouldEqual, 3)
+
+			PutConjunctionCollector(collector)
+			convey.So(collector.DocCount(), convey.ShouldEqual, 0)
+			convey.So(collector.GetDocIDs(), convey.ShouldBeNil)
+		}
+	})
+}
+
+// countingCollector count Add calls after done to verify scanning stopped early
+type countingCollector struct {
+	*LimitCollector
+	addsAfterDone int
+}
+
+func (c *countingCollector) Add(docID DocID, conj ConjID) {
+	if c.Done() {
+		c.addsAfterDone++
+	}
+	c.LimitCollector.Add(docID, conj)
+}
+
+func TestLimitCollector(t *testing.T) {
+	convey.Convey("test retrieve stop after limit documents", t, func() {
+		docs, queries := BuildTestDocumentAndQueries(1000, 20, true)
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			for _, doc := range docs {
+				convey.So(builder.AddDocument(doc.ToDocument()), convey.ShouldBeNil)
+			}
+			index := builder.BuildIndex()
+
+			for _, q := range queries {
+				all, err := index.Retrieve(q.ToAssigns())
+				convey.So(err, convey.ShouldBeNil)
+
+				collector := &countingCollector{LimitCollector: NewLimitCollector(10, nil)}
+				err = index.RetrieveWithCollector(q.ToAssigns(), collector)
+				convey.So(err, convey.ShouldBeNil)
+
+				result := collector.GetDocIDs()
+				if len(all) <= 10 {
+					convey.So(len(result), convey.ShouldEqual, len(all))
+					continue
+				}
+				convey.So(len(result), convey.ShouldEqual, 10)
+				convey.So(collector.Done(), convey.ShouldBeTrue)
+				// scanning end up as soon as the 10th document collected
+				convey.So(collector.addsAfterDone, convey.ShouldEqual, 0)
+				for _, id := range result {
+					convey.So(all.Contain(id), convey.ShouldBeTrue)
+				}
+			}
+		}
+	})
+
+	convey.Convey("test limit collector forward conjunctions of collected documents", t, func() {
+		inner := NewConjunctionCollector()
+		collector := NewLimitCollector(1, inner)
+		collector.Add(1, NewConjID(1, 0, 1))
+		collector.Add(2, NewConjID(2, 0, 1))
+		collector.Add(1, NewConjID(1, 1, 1))
+		convey.So(collector.GetDocIDs(), convey.ShouldResemble, DocIDList{1})
+		convey.So(len(inner.GetConjIDs()), convey.ShouldEqual, 2)
+
+		collector.Reset()
+		convey.So(collector.Done(), convey.ShouldBeFalse)
+	})
+
+	convey.Convey("test limit collector count documents accepted by downstream only", t, func() {
+		even := DocFilter{Name: "even", Fn: func(id DocID, _ ConjID) bool { return id%2 == 0 }}
+		inner := NewConjunctionCollector()
+		collector := NewLimitCollector(2, NewFilterCollector(inner, even))
+		for id := DocID(1); id <= 6 && !collector.Done(); id++ {
+			collector.Add(id, NewConjID(id, 0, 1))
+		}
+		convey.So(collector.Done(), convey.ShouldBeTrue)
+		convey.So(collector.GetDocIDs(), convey.ShouldResemble, DocIDList{2, 4})
+		convey.So(collector.TryAdd(6, NewConjID(6, 0, 1)), convey.ShouldBeFalse)
+		convey.So(collector.TryAdd(4, NewConjID(4, 1, 1)), convey.ShouldBeTrue)
+		convey.So(len(inner.GetConjIDs()), convey.ShouldEqual, 3)
+
+		// grouping downstream drop documents of full group
+		grouped := NewLimitCollector(2, NewGroupingCollector(nil, func(id DocID) (interface{}, bool) {
+			return id % 2, true
+		}, 1))
+		for id := DocID(1); id <= 6; id++ {
+			grouped.Add(id, NewConjID(id, 0, 1))
+		}
+		convey.So(grouped.GetDocIDs(), convey.ShouldResemble, DocIDList{1, 2})
+
+		// documents rejected by downstream don't take the group quota
+		group := NewGroupingCollector(NewFilterCollector(nil, even), func(id DocID) (interface{}, bool) {
+			return "all", true
+		}, 1)
+		for id := DocID(1); id <= 6; id++ {
+			group.Add(id, NewConjID(id, 0, 1))
+		}
+		convey.So(group.GetDocIDs(), convey.ShouldResemble, DocIDList{2})
+	})
+}
+
+func TestFilterCollector(t *testing.T) {
+	convey.Convey("test filter chain collector", t, func() {
+		docs := []*Document{
+			NewDocument(1).AddConjunction(NewConjunction().In("age", []int64{1})),
+			NewDocument(2).AddConjunction(NewConjunction().In("age", []int64{1}), NewConjunction().In("city", []string{"bj"})),
+			NewDocument(3).AddConjunction(NewConjunction().In("age", []int64{1})),
+			NewDocument(4).AddConjunction(NewConjunction().In("city", []string{"bj"})),
+		}
+		builder := NewIndexerBuilder()
+		convey.So(builder.AddDocument(docs...), convey.ShouldBeNil)
+		index := builder.BuildIndex()
+
+		downstream := NewConjunctionCollector()
+		collector := NewFilterCollector(downstream, DocFilter{Name: "blocklist", Fn: func(id DocID, _ ConjID) bool {
+			return id != 3
+		}}).Use("first_conj_only", func(_ DocID, conj ConjID) bool {
+			return conj.Index() == 0
+		})
+
+		err := index.RetrieveWithCollector(Assignments{"age": 1, "city": "bj"}, collector)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(collector.GetDocIDs(), convey.ShouldResemble, DocIDList{1, 2, 4})
+		convey.So(len(downstream.GetConjunctions()[2]), convey.ShouldEqual, 1)
+
+		stats := collector.Stats()
+		convey.So(stats.Passed, convey.ShouldEqual, 3)
+		convey.So(stats.Filters, convey.ShouldResemble, []FilterStat{
+			{Name: "blocklist", Rejected: 1},
+			{Name: "first_conj_only", Rejected: 1},
+		})
+
+		collector.Reset()
+		convey.So(collector.Stats().Passed, convey.ShouldEqual, 0)
+		convey.So(collector.Stats().Filters[0].Rejected, convey.ShouldEqual, 0)
+	})
+
+	convey.Convey("test filter collector forward stop signal", t, func() {
+		collector := NewFilterCollector(NewLimitCollector(1, nil))
+		convey.So(collector.Done(), convey.ShouldBeFalse)
+		collector.Add(1, NewConjID(1, 0, 1))
+		convey.So(collector.Done(), convey.ShouldBeTrue)
+		convey.So(NewFilterCollector(nil).Done(), convey.ShouldBeFalse)
+	})
+}
+
+func TestGroupingCollector(t *testing.T) {
+	convey.Convey("test at most K documents per group", t, func() {
+		builder := NewIndexerBuilder()
+		for id := 1; id <= 10; id++ {
+			doc := NewDocument(DocID(id)).AddConjunction(
+				NewConjunction().In("age", []int64{1}),
+				NewConjunction().In("city", []string{"bj"}),
+			)
+			if id <= 8 {
+				doc.Attrs = DocAttrs{"advertiser": id % 3}
+			}
+			convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		}
+		index := builder.BuildIndex()
+
+		downstream := NewConjunctionCollector()
+		collector := NewGroupingCollector(downstream, GroupByAttr(index.(AttributeIndex).Attributes(), "advertiser"), 2)
+		err := index.RetrieveWithCollector(Assignments{"age": 1, "city": "bj"}, collector)
+		convey.So(err, convey.ShouldBeNil)
+
+		result := collector.GetDocIDs()
+		// 3 groups * 2 + 2 documents without attribute
+		convey.So(len(result), convey.ShouldEqual, 8)
+		convey.So(result.Contain(9) && result.Contain(10), convey.ShouldBeTrue)
+		convey.So(collector.GroupCounts(), convey.ShouldResemble, map[interface{}]int{int64(0): 2, int64(1): 2, int64(2): 2})
+		convey.So(collector.Dropped(), convey.ShouldEqual, 4)
+		for _, conjs := range downstream.GetConjunctions() {
+			convey.So(len(conjs), convey.ShouldEqual, 2)
+		}
+
+		collector.Reset()
+		convey.So(collector.GroupCounts(), convey.ShouldBeEmpty)
+		convey.So(collector.Dropped(), convey.ShouldEqual, 0)
+	})
+
+	convey.Convey("test dedupe by group key", t, func() {
+		keys := map[DocID]string{1: "a", 2: "a", 3: "b"}
+		collector := NewGroupingCollector(nil, func(id DocID) (interface{}, bool) {
+			key, ok := keys[id]
+			return key, ok
+		}, 1)
+		for id := DocID(1); id <= 3; id++ {
+			collector.Add(id, NewConjID(id, 0, 1))
+		}
+		convey.So(collector.GetDocIDs(), convey.ShouldResemble, DocIDList{1, 3})
+		convey.So(func() { NewGroupingCollector(nil, nil, 1) }, convey.ShouldPanic)
+	})
+}
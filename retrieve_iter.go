@@ -0,0 +1,167 @@
+package be_indexer
+
+import (
+	"github.com/echoface/be_indexer/util"
+)
+
+type (
+	// DocIterator yield matched conjunctions incrementally as the cursor algorithm produces them,
+	// instead of materializing the whole result like Retrieve.
+	//
+	// Ordering: matches of KGroupsBEIndex are yielded group by group from the largest conjunction
+	// size to the smallest(wildcard conjunctions last), ascending by ConjID inside a group;
+	// CompactBEIndex yield matches ascending by ConjID(conjunction size, index, then document).
+	// Duplicates: each matched conjunction is yielded exactly once, so a document with several
+	// matched conjunctions appears several times with different ConjID; dedupe by DocID if needed.
+	//
+	// iterator is not goroutine-safe, Close must be called when caller stop iterating early
+	DocIterator interface {
+		// Next return next matched document and conjunction, false when no more results
+		Next() (DocID, ConjID, bool)
+
+		// Close release resources held by iterator, Next always return false after closed
+		Close()
+	}
+
+	kGroupsCursors struct {
+		cursors      FieldCursors
+		needMatchCnt int
+	}
+
+	kGroupsIterator struct {
+		ctx retrieveContext
+
+		// groups cursors of each k size group, from largest k to zero
+		groups []kGroupsCursors
+	}
+
+	compactIterator struct {
+		ctx retrieveContext
+
+		cursors FieldCursors
+	}
+)
+
+// matchRound process one round of sorted cursors: the conjunction is matched when first needMatchCnt
+// cursors point to the same include entry, exclude entry skip cursors of the conjunction; then move
+// cursors to next conjunction and keep them sorted
+func matchRound(ctx *retrieveContext, fieldCursors FieldCursors, needMatchCnt int) (conjID ConjID, matched bool) {
+	eid := fieldCursors[0].GetCurEntryID()
+	endEID := fieldCursors[needMatchCnt-1].GetCurEntryID()
+
+	conjID = eid.GetConjID()
+	endConjID := endEID.GetConjID()
+
+	nextID := NewEntryID(endConjID, false) // 逻辑按照conjID执行，但是直接使用endEID可能跳过排除逻辑的EID
+
+	if conjID == endConjID {
+
+		// nextID = endEID + 1
+		nextID = NewEntryID(endConjID, true) + 1
+
+		if eid.IsInclude() {
+			matched = true
+		} else { //exclude
+			for i := needMatchCnt; i < len(fieldCursors); i++ {
+				if fieldCursors[i].GetCurEntryID() < nextID {
+					ctx.skipTo(&fieldCursors[i], nextID)
+					ctx.skipFilteredDocs(fieldCursors[i : i+1]...)
+				}
+			}
+		}
+	}
+	for i := 0; i < needMatchCnt; i++ { // 推进游标
+		ctx.skipTo(&fieldCursors[i], nextID)
+	}
+	ctx.skipFilteredDocs(fieldCursors[:needMatchCnt]...)
+
+	fieldCursors.Sort()
+	// sort.Sort(fieldCursors) // slow 12% compare to fieldCursors.Sort()
+	return conjID, matched
+}
+
+func newIterCtx(queries Assignments, opts []IndexOpt) retrieveContext {
+	ctx := newRetrieveCtx(queries, opts...)
+	util.PanicIf(ctx.collector != nil, "collector not supported by iterator")
+	ctx.trace = nil // not supported by iterator
+	return ctx
+}
+
+// RetrieveIter fetch posting lists of all k size groups and return an iterator, the cursors
+// advance only when Next called; see DocIterator for ordering and duplicate semantics
+func (bi *KGroupsBEIndex) RetrieveIter(queries Assignments, opts ...IndexOpt) (DocIterator, error) {
+	iter := &kGroupsIterator{ctx: newIterCtx(queries, opts)}
+
+	for k := util.MinInt(queries.Size(), bi.maxK()); k >= 0; k-- {
+		fCursors, err := bi.initCursors(&iter.ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		needMatchCnt := util.MaxInt(k, 1)
+		if len(fCursors) < needMatchCnt {
+			continue
+		}
+		iter.ctx.skipFilteredDocs(fCursors...)
+		fCursors.Sort()
+		iter.groups = append(iter.groups, kGroupsCursors{cursors: fCursors, needMatchCnt: needMatchCnt})
+	}
+	return iter, nil
+}
+
+func (iter *kGroupsIterator) Next() (DocID, ConjID, bool) {
+	for len(iter.groups) > 0 {
+		group := &iter.groups[0]
+		for !group.cursors[group.needMatchCnt-1].GetCurEntryID().IsNULLEntry() {
+			if conjID, ok := matchRound(&iter.ctx, group.cursors, group.needMatchCnt); ok {
+				return conjID.DocID(), conjID, true
+			}
+		}
+		iter.groups = iter.groups[1:]
+	}
+	return 0, 0, false
+}
+
+func (iter *kGroupsIterator) Close() {
+	iter.groups = nil
+}
+
+// RetrieveIter fetch posting lists and return an iterator, the cursors advance only
+// when Next called; see DocIterator for ordering and duplicate semantics
+func (bi *CompactBEIndex) RetrieveIter(queries Assignments, opts ...IndexOpt) (DocIterator, error) {
+	iter := &compactIterator{ctx: newIterCtx(queries, opts)}
+
+	fCursors, err := bi.initCursors(&iter.ctx)
+	if err != nil {
+		return nil, err
+	}
+	iter.ctx.skipFilteredDocs(fCursors...)
+	fCursors.Sort()
+	iter.cursors = fCursors
+	return iter, nil
+}
+
+func (iter *compactIterator) Next() (DocID, ConjID, bool) {
+	for len(iter.cursors) > 0 {
+		conjID := iter.cursors[0].GetCurEntryID().GetConjID()
+
+		// same as RetrieveWithCollector, no conjunction can be matched when cursors not enough
+		needMatchCnt := util.MaxInt(1, conjID.Size())
+		if needMatchCnt > len(iter.cursors) {
+			break
+		}
+		conjID, matched := matchRound(&iter.ctx, iter.cursors, needMatchCnt)
+
+		for len(iter.cursors) > 0 && iter.cursors[len(iter.cursors)-1].ReachEnd() {
+			iter.cursors = iter.cursors[:len(iter.cursors)-1]
+		}
+		if matched {
+			return conjID.DocID(), conjID, true
+		}
+	}
+	iter.cursors = nil
+	return 0, 0, false
+}
+
+func (iter *compactIterator) Close() {
+	iter.cursors = nil
+}
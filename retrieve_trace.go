@@ -0,0 +1,91 @@
+package be_indexer
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// CompactTraceK k of the only group traced by CompactBEIndex, it doesn't group conjunctions by size
+	CompactTraceK = -1
+)
+
+type (
+	// RetrieveTrace structured trace of one retrieving filled by WithTrace, it can be serialized
+	// into json for sampling; durations are in nanoseconds
+	RetrieveTrace struct {
+		Duration time.Duration `json:"duration_ns"`
+
+		// Groups k size groups evaluated, from largest k to zero; CompactBEIndex has only one group
+		Groups []GroupTrace `json:"groups"`
+
+		// PostingLists posting lists fetched from entries holders per QKey
+		PostingLists []PostingListTrace `json:"posting_lists"`
+
+		// SkipToCalls count of field cursor SkipTo calls
+		SkipToCalls int64 `json:"skip_to_calls"`
+
+		// Matched matched conjunctions in the order they were collected
+		Matched []ConjID `json:"matched"`
+	}
+
+	GroupTrace struct {
+		K        int           `json:"k"`
+		Cursors  int           `json:"cursors"` // count of field cursors initialized
+		Matched  int           `json:"matched"`
+		Duration time.Duration `json:"duration_ns"`
+	}
+
+	PostingListTrace struct {
+		K      int     `json:"k"`
+		Field  BEField `json:"field"`
+		Value  string  `json:"value"`
+		Length int     `json:"length"`
+	}
+
+	// groupTracing state of the group being traced
+	groupTracing struct {
+		start   time.Time
+		matched int
+	}
+)
+
+// WithTrace fill trace when retrieving, the trace is reset before filling; it is
+// supported by KGroupsBEIndex/CompactBEIndex RetrieveWithCollector(and Retrieve) only, roaringidx index
+// return error for it
+func WithTrace(trace *RetrieveTrace) IndexOpt {
+	return func(ctx *retrieveContext) {
+		ctx.trace = trace
+	}
+}
+
+func (t *RetrieveTrace) reset() {
+	*t = RetrieveTrace{}
+}
+
+func (t *RetrieveTrace) finish(start time.Time) {
+	t.Duration = time.Since(start)
+}
+
+func (t *RetrieveTrace) addPostingLists(k int, cursors EntriesCursors) {
+	for i := range cursors {
+		t.PostingLists = append(t.PostingLists, PostingListTrace{
+			K:      k,
+			Field:  cursors[i].key.field,
+			Value:  fmt.Sprint(cursors[i].key.value),
+			Length: len(cursors[i].entries),
+		})
+	}
+}
+
+func (t *RetrieveTrace) beginGroup(k int) groupTracing {
+	t.Groups = append(t.Groups, GroupTrace{K: k})
+	return groupTracing{start: time.Now(), matched: len(t.Matched)}
+}
+
+func (t *RetrieveTrace) endGroup(tracing groupTracing, cursors int) {
+	group := &t.Groups[len(t.Groups)-1]
+	group.Cursors = cursors
+	group.Matched = len(t.Matched) - tracing.matched
+	group.Duration = time.Since(tracing.start)
+}
@@ -0,0 +1,74 @@
+package be_indexer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestBEIndex_RetrieveWithTrace(t *testing.T) {
+	convey.Convey("test retrieve trace filled and serializable", t, func() {
+		docs := []*Document{
+			NewDocument(1).AddConjunction(NewConjunction().In("age", []int{1, 2}).In("os", []string{"ios"})),
+			NewDocument(2).AddConjunction(NewConjunction().In("age", []int{2})),
+			NewDocument(3).AddConjunction(NewConjunction().NotIn("os", []string{"android"})),
+			NewDocument(4).AddConjunction(NewConjunction().In("age", []int{3})),
+		}
+		for _, builder := range []*IndexerBuilder{NewIndexerBuilder(), NewCompactIndexerBuilder()} {
+			builder.ConfigField("age", FieldOption{})
+			builder.ConfigField("os", FieldOption{})
+			for _, doc := range docs {
+				convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+			}
+			index := builder.BuildIndex()
+			_, compacted := index.(*CompactBEIndex)
+
+			trace := &RetrieveTrace{}
+			for i := 0; i < 2; i++ { // trace reset for each retrieving
+				result, err := index.Retrieve(Assignments{"age": []int{2, 5}, "os": "ios"}, WithTrace(trace))
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(result, convey.ShouldResemble, DocIDList{1, 2, 3})
+
+				matched := DocIDList{}
+				for _, conj := range trace.Matched {
+					matched = append(matched, conj.DocID())
+				}
+				convey.So(matched, convey.ShouldHaveLength, 3)
+				convey.So(matched, convey.ShouldContain, DocID(3))
+				convey.So(trace.SkipToCalls, convey.ShouldBeGreaterThan, 0)
+
+				groupMatched := 0
+				for _, group := range trace.Groups {
+					groupMatched += group.Matched
+					if compacted {
+						convey.So(group.K, convey.ShouldEqual, CompactTraceK)
+					}
+				}
+				convey.So(groupMatched, convey.ShouldEqual, 3)
+				if compacted {
+					convey.So(trace.Groups, convey.ShouldHaveLength, 1)
+					convey.So(trace.Groups[0].Cursors, convey.ShouldEqual, 3)
+				} else {
+					convey.So(trace.Groups, convey.ShouldHaveLength, 3) // k: 2, 1, 0
+					convey.So(trace.Groups[0].K, convey.ShouldEqual, 2)
+				}
+
+				ageLists := map[string]int{}
+				for _, pl := range trace.PostingLists {
+					if pl.Field == "age" {
+						ageLists[pl.Value] += pl.Length
+					}
+				}
+				convey.So(ageLists, convey.ShouldResemble, map[string]int{"2": 2})
+			}
+
+			data, err := json.Marshal(trace)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(data), convey.ShouldContainSubstring, `"skip_to_calls"`)
+			decoded := &RetrieveTrace{}
+			convey.So(json.Unmarshal(data, decoded), convey.ShouldBeNil)
+			convey.So(decoded, convey.ShouldResemble, trace)
+		}
+	})
+}
@@ -1,6 +1,10 @@
 package roaringidx
 
 import (
+	"io"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/echoface/be_indexer"
 	"github.com/echoface/be_indexer/util"
 )
@@ -22,6 +26,12 @@ type (
 		BuildBEContainer() (BEContainer, error)
 	}
 
+	// WildcardContainer optional interface of BEContainer, report whether the conjunction has no
+	// include expression on the field, used to restore conjunction size of be_indexer.ConjID
+	WildcardContainer interface {
+		IsWildcard(id ConjunctionID) bool
+	}
+
 	// DefaultBEContainer a common value based inverted index bitmap container
 	DefaultBEContainer struct {
 		meta *FieldMeta
@@ -31,6 +41,11 @@ type (
 		inc map[BEValue]PostingList
 
 		exc map[BEValue]PostingList
+
+		// exprs conjunctions have expressions on this field
+		exprs PostingList
+
+		cow cowState
 	}
 )
 
@@ -38,10 +53,11 @@ func NewDefaultBEContainer(meta *FieldMeta) *DefaultBEContainer {
 	util.PanicIf(meta.Parser == nil, "default container must need parser")
 
 	return &DefaultBEContainer{
-		meta: meta,
-		wc:   NewPostingList(),
-		inc:  map[BEValue]PostingList{},
-		exc:  map[BEValue]PostingList{},
+		meta:  meta,
+		wc:    NewPostingList(),
+		inc:   map[BEValue]PostingList{},
+		exc:   map[BEValue]PostingList{},
+		exprs: NewPostingList(),
 	}
 }
 
@@ -49,25 +65,34 @@ func (c *DefaultBEContainer) Meta() *FieldMeta {
 	return c.meta
 }
 
+func (c *DefaultBEContainer) IsWildcard(id ConjunctionID) bool {
+	return c.wc.Contains(uint64(id))
+}
+
 func (c *DefaultBEContainer) AddWildcard(id ConjunctionID) {
+	c.wc = c.cow.writable(c.wc)
 	c.wc.Add(uint64(id))
 }
 
 func (c *DefaultBEContainer) AddInclude(value BEValue, id ConjunctionID) {
 	pl, ok := c.inc[value]
 	if !ok {
-		pl = NewPostingList()
-		c.inc[value] = pl
+		pl = c.cow.newPostingList()
+	} else {
+		pl = c.cow.writable(pl)
 	}
+	c.inc[value] = pl
 	pl.Add(uint64(id))
 }
 
 func (c *DefaultBEContainer) AddExclude(value BEValue, id ConjunctionID) {
 	pl, ok := c.exc[value]
 	if !ok {
-		pl = NewPostingList()
-		c.exc[value] = pl
+		pl = c.cow.newPostingList()
+	} else {
+		pl = c.cow.writable(pl)
 	}
+	c.exc[value] = pl
 	pl.Add(uint64(id))
 	// c.AddWildcard(id)
 }
@@ -106,6 +131,8 @@ func (c *DefaultBEContainer) EncodeExpr(id ConjunctionID, expr *be_indexer.Boole
 		// c.EncodeWildcard(id)
 	}
 	util.PanicIf(expr.Operator != be_indexer.ValueOptEQ, "default container support EQ operator only")
+	c.exprs = c.cow.writable(c.exprs)
+	c.exprs.Add(uint64(id))
 
 	valueIDs, err := c.meta.Parser.ParseValue(expr.Value)
 	if err != nil {
@@ -129,5 +156,87 @@ func (c *DefaultBEContainer) BuildBEContainer() (BEContainer, error) {
 	//	v.RunOptimize()
 	//}
 	//builder.container.wc.RunOptimize()
+	c.cow.done()
 	return c, nil
 }
+
+// Mutator return a copy-on-write mutator sharing posting lists with c
+func (c *DefaultBEContainer) Mutator() BEContainerMutator {
+	return &DefaultBEContainer{
+		meta:  c.meta,
+		wc:    c.wc,
+		inc:   cloneMap(c.inc),
+		exc:   cloneMap(c.exc),
+		exprs: c.exprs,
+		cow:   newCowState(),
+	}
+}
+
+func (c *DefaultBEContainer) RemoveConjunctions(ids *roaring64.Bitmap) {
+	c.wc = removeFromPostingList(&c.cow, c.wc, ids)
+	removeFromMap(&c.cow, c.inc, ids)
+	removeFromMap(&c.cow, c.exc, ids)
+	c.exprs = removeFromPostingList(&c.cow, c.exprs, ids)
+}
+
+func (c *DefaultBEContainer) HasExpressions(ids *roaring64.Bitmap) bool {
+	return c.exprs.Intersects(ids)
+}
+
+func (c *DefaultBEContainer) WildcardMutator() BEContainerMutator {
+	return newWildcardMutator(c, c.wc, func(wc PostingList) BEContainer {
+		clone := *c
+		clone.wc = wc
+		return &clone
+	})
+}
+
+func writeValuePostingLists(bw *binWriter, pls map[BEValue]PostingList) {
+	values := make([]BEValue, 0, len(pls))
+	for v := range pls {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+	bw.writeUint32(uint32(len(values)))
+	for _, v := range values {
+		bw.writeUint64(uint64(v))
+		bw.writeBitmap(pls[v])
+	}
+}
+
+func readValuePostingLists(br *binReader) map[BEValue]PostingList {
+	cnt := br.readSize()
+	pls := map[BEValue]PostingList{}
+	for i := 0; i < cnt && br.err == nil; i++ {
+		v := BEValue(br.readUint64())
+		pls[v] = br.readBitmap()
+	}
+	return pls
+}
+
+func (c *DefaultBEContainer) Statistics() FieldStatistics {
+	stats := FieldStatistics{Wildcards: c.wc.GetCardinality()}
+	stats.Values, stats.Postings = postingListsStatistics(c.inc)
+	return stats
+}
+
+// WriteTo serialize wildcard/include/exclude posting lists
+func (c *DefaultBEContainer) WriteTo(w io.Writer) (int64, error) {
+	bw := &binWriter{w: w}
+	bw.writeBitmap(c.wc)
+	writeValuePostingLists(bw, c.inc)
+	writeValuePostingLists(bw, c.exc)
+	return bw.n, bw.err
+}
+
+// ReadFrom load posting lists serialized by WriteTo
+func (c *DefaultBEContainer) ReadFrom(r io.Reader) (int64, error) {
+	br := newBinReader(r)
+	c.wc = br.readBitmap()
+	c.inc = readValuePostingLists(br)
+	c.exc = readValuePostingLists(br)
+	c.exprs = unionPostingLists(c.inc, c.exc)
+	return br.n, br.err
+}
@@ -1,11 +1,15 @@
 package roaringidx
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/echoface/be_indexer/holder/ahoholder"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	aho "github.com/anknown/ahocorasick"
 	"github.com/echoface/be_indexer"
 	"github.com/echoface/be_indexer/util"
@@ -13,7 +17,8 @@ import (
 
 type (
 	ACBEContainer struct {
-		querySep string
+		// option share the same query join separator and keywords normalization setting with ACEntriesHolder
+		option ahoholder.ACHolderOption
 
 		meta *FieldMeta
 
@@ -25,6 +30,14 @@ type (
 
 		incValues map[string]PostingList
 		excValues map[string]PostingList
+
+		// exprs conjunctions have expressions on this field
+		exprs PostingList
+
+		// keysChanged keywords added or removed, ac machines need to be rebuilt
+		keysChanged bool
+
+		cow cowState
 	}
 )
 
@@ -33,16 +46,23 @@ const (
 )
 
 func NewACBEContainer(meta *FieldMeta, sep string) *ACBEContainer {
+	return NewACBEContainerWithOption(meta, ahoholder.ACHolderOption{QuerySep: sep})
+}
+
+// NewACBEContainerWithOption create ac container with case folding/NFKC/width folding settings,
+// the normalization will be applied to keywords when encoding and to query content when retrieving
+func NewACBEContainerWithOption(meta *FieldMeta, option ahoholder.ACHolderOption) *ACBEContainer {
 	util.PanicIf(meta == nil, "nil FieldMeta is not allowed")
 
 	return &ACBEContainer{
-		querySep:  sep,
+		option:    option,
 		meta:      meta,
 		wc:        NewPostingList(),
 		inc:       nil,
 		exc:       nil,
 		incValues: map[string]PostingList{},
 		excValues: map[string]PostingList{},
+		exprs:     NewPostingList(),
 	}
 }
 
@@ -50,25 +70,36 @@ func (c *ACBEContainer) Meta() *FieldMeta {
 	return c.meta
 }
 
+func (c *ACBEContainer) IsWildcard(id ConjunctionID) bool {
+	return c.wc.Contains(uint64(id))
+}
+
 func (c *ACBEContainer) AddWildcard(id ConjunctionID) {
+	c.wc = c.cow.writable(c.wc)
 	c.wc.Add(uint64(id))
 }
 
 func (c *ACBEContainer) AddIncludeID(key string, id ConjunctionID) {
 	pl, ok := c.incValues[key]
 	if !ok {
-		pl = NewPostingList()
-		c.incValues[key] = pl
+		pl = c.cow.newPostingList()
+		c.keysChanged = true
+	} else {
+		pl = c.cow.writable(pl)
 	}
+	c.incValues[key] = pl
 	pl.Add(uint64(id))
 }
 
 func (c *ACBEContainer) AddExcludeID(key string, id ConjunctionID) {
 	pl, ok := c.excValues[key]
 	if !ok {
-		pl = NewPostingList()
-		c.excValues[key] = pl
+		pl = c.cow.newPostingList()
+		c.keysChanged = true
+	} else {
+		pl = c.cow.writable(pl)
 	}
+	c.excValues[key] = pl
 	pl.Add(uint64(id))
 }
 
@@ -78,7 +109,7 @@ func (c *ACBEContainer) buildPatternQueryContent(v be_indexer.Values) ([]rune, e
 	case string:
 		data = []rune(tv)
 	case []string:
-		data = []rune(strings.Join(tv, c.querySep))
+		data = []rune(strings.Join(tv, c.option.QuerySep))
 	case []interface{}:
 		for idx, vi := range tv {
 			if str, ok := vi.(string); !ok {
@@ -88,7 +119,7 @@ func (c *ACBEContainer) buildPatternQueryContent(v be_indexer.Values) ([]rune, e
 
 					data = append(data, []rune(str)...)
 				} else {
-					data = append(data, []rune(c.querySep)...)
+					data = append(data, []rune(c.option.QuerySep)...)
 					data = append(data, []rune(str)...)
 				}
 			}
@@ -105,7 +136,7 @@ func (c *ACBEContainer) Retrieve(values be_indexer.Values, inout *PostingList) e
 	if util.NilInterface(values) { // empty assign
 		return nil
 	}
-	data, err := ahoholder.BuildAcMatchContent(values, c.querySep)
+	data, err := ahoholder.BuildNormalizedAcMatchContent(values, &c.option)
 	if err != nil {
 		return err
 	}
@@ -113,7 +144,9 @@ func (c *ACBEContainer) Retrieve(values be_indexer.Values, inout *PostingList) e
 	if c.inc != nil {
 		terms := c.inc.MultiPatternSearch(data, false)
 		for _, term := range terms {
-			// key := c.inc.Key(rawContent, itr)
+			if !c.option.AcceptMatch(data, term.Pos, len(term.Word)) {
+				continue
+			}
 			inout.Or(c.incValues[string(term.Word)].Bitmap)
 		}
 	}
@@ -121,7 +154,9 @@ func (c *ACBEContainer) Retrieve(values be_indexer.Values, inout *PostingList) e
 	if c.exc != nil {
 		terms := c.exc.MultiPatternSearch(data, false)
 		for _, term := range terms {
-			// key := c.inc.Key(rawContent, itr)
+			if !c.option.AcceptMatch(data, term.Pos, len(term.Word)) {
+				continue
+			}
 			inout.AndNot(c.excValues[string(term.Word)].Bitmap)
 		}
 	}
@@ -139,11 +174,14 @@ func (c *ACBEContainer) EncodeExpr(id ConjunctionID, expr *be_indexer.BooleanExp
 		return nil
 	}
 	util.PanicIf(expr.Operator != be_indexer.ValueOptEQ, "ac_match support EQ operator only")
+	c.exprs = c.cow.writable(c.exprs)
+	c.exprs.Add(uint64(id))
 
 	keys, err := ahoholder.ParseAcMatchDict(expr.Value)
 	if err != nil {
 		return fmt.Errorf("ac container need string type values, err:%v", err)
 	}
+	keys = ahoholder.NormalizeAcMatchDict(keys, &c.option)
 	for _, v := range keys {
 		if expr.Incl {
 			c.AddIncludeID(v, id)
@@ -158,7 +196,64 @@ func (c *ACBEContainer) EncodeExpr(id ConjunctionID, expr *be_indexer.BooleanExp
 }
 
 func (c *ACBEContainer) BuildBEContainer() (BEContainer, error) {
-	var err error
+	if c.keysChanged {
+		c.inc, c.exc = nil, nil
+		if err := c.buildMachines(); err != nil {
+			return nil, err
+		}
+		c.keysChanged = false
+	}
+	c.cow.done()
+	return c, nil
+}
+
+// Mutator return a copy-on-write mutator sharing posting lists and ac machines with c,
+// the ac machines will be rebuilt only when keywords added or removed
+func (c *ACBEContainer) Mutator() BEContainerMutator {
+	return &ACBEContainer{
+		option:    c.option,
+		meta:      c.meta,
+		wc:        c.wc,
+		inc:       c.inc,
+		exc:       c.exc,
+		incValues: cloneMap(c.incValues),
+		excValues: cloneMap(c.excValues),
+		exprs:     c.exprs,
+		cow:       newCowState(),
+	}
+}
+
+func (c *ACBEContainer) RemoveConjunctions(ids *roaring64.Bitmap) {
+	c.wc = removeFromPostingList(&c.cow, c.wc, ids)
+	if removeFromMap(&c.cow, c.incValues, ids) {
+		c.keysChanged = true
+	}
+	if removeFromMap(&c.cow, c.excValues, ids) {
+		c.keysChanged = true
+	}
+	c.exprs = removeFromPostingList(&c.cow, c.exprs, ids)
+}
+
+func (c *ACBEContainer) HasExpressions(ids *roaring64.Bitmap) bool {
+	return c.exprs.Intersects(ids)
+}
+
+// WildcardMutator the new container share ac machines with c
+func (c *ACBEContainer) WildcardMutator() BEContainerMutator {
+	return newWildcardMutator(c, c.wc, func(wc PostingList) BEContainer {
+		clone := *c
+		clone.wc = wc
+		return &clone
+	})
+}
+
+func (c *ACBEContainer) Statistics() FieldStatistics {
+	stats := FieldStatistics{Wildcards: c.wc.GetCardinality()}
+	stats.Values, stats.Postings = postingListsStatistics(c.incValues)
+	return stats
+}
+
+func (c *ACBEContainer) buildMachines() (err error) {
 	keys := make([][]rune, 0, len(c.incValues))
 	if len(c.incValues) > 0 {
 		for kw := range c.incValues {
@@ -166,7 +261,7 @@ func (c *ACBEContainer) BuildBEContainer() (BEContainer, error) {
 		}
 		c.inc = &aho.Machine{}
 		if err = c.inc.Build(keys); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
@@ -177,8 +272,66 @@ func (c *ACBEContainer) BuildBEContainer() (BEContainer, error) {
 		}
 		c.exc = &aho.Machine{}
 		if err = c.exc.Build(keys); err != nil {
-			return nil, err
+			return err
 		}
 	}
-	return c, nil
+	return nil
+}
+
+func writeKeywordPostingLists(bw *binWriter, pls map[string]PostingList) {
+	keys := make([]string, 0, len(pls))
+	for key := range pls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	bw.writeUint32(uint32(len(keys)))
+	for _, key := range keys {
+		bw.writeString(key)
+		bw.writeBitmap(pls[key])
+	}
+}
+
+func readKeywordPostingLists(br *binReader) map[string]PostingList {
+	cnt := br.readSize()
+	pls := map[string]PostingList{}
+	for i := 0; i < cnt && br.err == nil; i++ {
+		key := br.readString()
+		pls[key] = br.readBitmap()
+	}
+	return pls
+}
+
+// WriteTo serialize matcher option and keyword posting lists, the ac machines are rebuilt when loading
+func (c *ACBEContainer) WriteTo(w io.Writer) (int64, error) {
+	bw := &binWriter{w: w}
+	option, err := json.Marshal(c.option)
+	if err != nil {
+		return 0, err
+	}
+	bw.writeBytes(option)
+	bw.writeBitmap(c.wc)
+	writeKeywordPostingLists(bw, c.incValues)
+	writeKeywordPostingLists(bw, c.excValues)
+	return bw.n, bw.err
+}
+
+// ReadFrom load data serialized by WriteTo and rebuild the ac machines
+func (c *ACBEContainer) ReadFrom(r io.Reader) (int64, error) {
+	br := newBinReader(r)
+	option := br.readBytes()
+	if br.err != nil {
+		return br.n, br.err
+	}
+	if err := json.Unmarshal(option, &c.option); err != nil {
+		return br.n, err
+	}
+	c.wc = br.readBitmap()
+	c.incValues = readKeywordPostingLists(br)
+	c.excValues = readKeywordPostingLists(br)
+	c.exprs = unionPostingLists(c.incValues, c.excValues)
+	if br.err != nil {
+		return br.n, br.err
+	}
+	c.inc, c.exc = nil, nil
+	return br.n, c.buildMachines()
 }
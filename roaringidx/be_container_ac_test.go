@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/holder/ahoholder"
 	cedar "github.com/iohub/ahocorasick"
 
 	"github.com/echoface/be_indexer/util"
@@ -64,3 +66,24 @@ func TestACBEContainer_AddIncludeID(t *testing.T) {
 		fmt.Println("value:", int(v))
 	}
 }
+
+func TestACBEContainer_Normalize(t *testing.T) {
+	container := NewACBEContainerWithOption(&FieldMeta{field: "keywords"}, ahoholder.ACHolderOption{
+		QuerySep:     DefaultACContainerQueryJoinSep,
+		CaseFolding:  true,
+		WidthFolding: true,
+	})
+	conjID, _ := NewConjunctionID(0, 1)
+	err := container.EncodeExpr(conjID, be_indexer.NewBoolExpr("keywords", true, be_indexer.NewStrValues("Nike")))
+	util.PanicIfErr(err, "encode fail")
+	_, err = container.BuildBEContainer()
+	util.PanicIfErr(err, "build fail")
+
+	pl := NewPostingList()
+	defer ReleasePostingList(pl)
+	err = container.Retrieve([]string{"ＮＩＫＥ", "shoes"}, &pl)
+	util.PanicIfErr(err, "retrieve fail")
+	if !pl.Contains(uint64(conjID)) {
+		t.Fatalf("normalized keyword should be matched")
+	}
+}
@@ -0,0 +1,541 @@
+package roaringidx
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/holder/rangeholder"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/echoface/be_indexer/util"
+)
+
+type (
+	// bitSlices a bit-sliced index: value of id is sum of 1<<i for each slices[i] contain id
+	bitSlices struct {
+		exist  PostingList
+		slices []PostingList
+	}
+
+	// rangeLayer each conjunction has at most one interval [left, right) in a layer,
+	// left and right are encoded as rank of boundary in sorted boundaries
+	rangeLayer struct {
+		left  *bitSlices
+		right *bitSlices
+	}
+
+	// BSIBEContainer a numeric range container support EQ/GT/LT/Between operator,
+	// EQ values are indexed as DefaultBEContainer does, ranges of a conjunction are
+	// merged into disjoint intervals and encoded into bit-sliced layers, so a point
+	// query cost O(log(boundaries)) bitmap operations for each layer
+	BSIBEContainer struct {
+		meta *FieldMeta
+
+		wc PostingList
+
+		incPoints map[int64]PostingList
+		excPoints map[int64]PostingList
+
+		// builder stage ranges, reset after container built
+		incRanges map[ConjunctionID][]rangeholder.Range
+		excRanges map[ConjunctionID][]rangeholder.Range
+
+		boundaries []int64
+		incLayers  []*rangeLayer
+		excLayers  []*rangeLayer
+
+		// exprs conjunctions have expressions on this field
+		exprs PostingList
+
+		cow cowState
+	}
+)
+
+func NewBSIBEContainer(meta *FieldMeta) *BSIBEContainer {
+	util.PanicIf(meta == nil, "nil FieldMeta is not allowed")
+
+	return &BSIBEContainer{
+		meta:      meta,
+		wc:        NewPostingList(),
+		incPoints: map[int64]PostingList{},
+		excPoints: map[int64]PostingList{},
+		incRanges: map[ConjunctionID][]rangeholder.Range{},
+		excRanges: map[ConjunctionID][]rangeholder.Range{},
+		exprs:     NewPostingList(),
+	}
+}
+
+func newBitSlices(bitCnt int) *bitSlices {
+	bs := &bitSlices{exist: NewPostingList(), slices: make([]PostingList, bitCnt)}
+	for i := range bs.slices {
+		bs.slices[i] = NewPostingList()
+	}
+	return bs
+}
+
+// set value of id, posting lists not owned by cow are cloned before modified
+func (bs *bitSlices) set(cow *cowState, id uint64, value uint64) {
+	bs.exist = cow.writable(bs.exist)
+	bs.exist.Add(id)
+	for i := range bs.slices {
+		if value&(1<<uint(i)) > 0 {
+			bs.slices[i] = cow.writable(bs.slices[i])
+			bs.slices[i].Add(id)
+		}
+	}
+}
+
+func (bs *bitSlices) remove(cow *cowState, ids *roaring64.Bitmap) {
+	bs.exist = removeFromPostingList(cow, bs.exist, ids)
+	for i := range bs.slices {
+		bs.slices[i] = removeFromPostingList(cow, bs.slices[i], ids)
+	}
+}
+
+// shallowCopy copy layers, the posting lists are still shared
+func shallowCopy(layers []*rangeLayer) []*rangeLayer {
+	res := make([]*rangeLayer, 0, len(layers))
+	for _, layer := range layers {
+		res = append(res, &rangeLayer{
+			left:  &bitSlices{exist: layer.left.exist, slices: append([]PostingList(nil), layer.left.slices...)},
+			right: &bitSlices{exist: layer.right.exist, slices: append([]PostingList(nil), layer.right.slices...)},
+		})
+	}
+	return res
+}
+
+// le return ids whose value <= v, O'Neil's bit-sliced range algorithm
+func (bs *bitSlices) le(v uint64) *roaring64.Bitmap {
+	lt, eq := roaring64.NewBitmap(), bs.exist.Clone()
+	for i := len(bs.slices) - 1; i >= 0; i-- {
+		if v&(1<<uint(i)) > 0 {
+			lt.Or(roaring64.AndNot(eq, bs.slices[i].Bitmap))
+			eq.And(bs.slices[i].Bitmap)
+		} else {
+			eq.AndNot(bs.slices[i].Bitmap)
+		}
+	}
+	lt.Or(eq)
+	return lt
+}
+
+// gt return ids whose value > v
+func (bs *bitSlices) gt(v uint64) *roaring64.Bitmap {
+	gt, eq := roaring64.NewBitmap(), bs.exist.Clone()
+	for i := len(bs.slices) - 1; i >= 0; i-- {
+		if v&(1<<uint(i)) > 0 {
+			eq.And(bs.slices[i].Bitmap)
+		} else {
+			gt.Or(roaring64.And(eq, bs.slices[i].Bitmap))
+			eq.AndNot(bs.slices[i].Bitmap)
+		}
+	}
+	return gt
+}
+
+// contain return ids whose interval contain the boundary rank
+func (layer *rangeLayer) contain(rank int) *roaring64.Bitmap {
+	res := layer.left.le(uint64(rank))
+	res.And(layer.right.gt(uint64(rank)))
+	return res
+}
+
+func (c *BSIBEContainer) Meta() *FieldMeta {
+	return c.meta
+}
+
+func (c *BSIBEContainer) IsWildcard(id ConjunctionID) bool {
+	return c.wc.Contains(uint64(id))
+}
+
+func (c *BSIBEContainer) AddWildcard(id ConjunctionID) {
+	c.wc = c.cow.writable(c.wc)
+	c.wc.Add(uint64(id))
+}
+
+func (c *BSIBEContainer) addPoint(points map[int64]PostingList, value int64, id ConjunctionID) {
+	pl, ok := points[value]
+	if !ok {
+		pl = c.cow.newPostingList()
+	} else {
+		pl = c.cow.writable(pl)
+	}
+	points[value] = pl
+	pl.Add(uint64(id))
+}
+
+// boundaryRank return the rank of largest boundary <= v, -1 means v less than all boundaries
+func (c *BSIBEContainer) boundaryRank(v int64) int {
+	return sort.Search(len(c.boundaries), func(i int) bool {
+		return c.boundaries[i] > v
+	}) - 1
+}
+
+func (c *BSIBEContainer) Retrieve(values be_indexer.Values, inout *PostingList) error {
+	inout.Or(c.wc.Bitmap)
+
+	if util.NilInterface(values) {
+		return nil
+	}
+	ids, err := parser.ParseIntegers(values, true)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if pl, ok := c.incPoints[id]; ok {
+			inout.Or(pl.Bitmap)
+		}
+		if rank := c.boundaryRank(id); rank >= 0 {
+			for _, layer := range c.incLayers {
+				inout.Or(layer.contain(rank))
+			}
+		}
+	}
+	for _, id := range ids {
+		if pl, ok := c.excPoints[id]; ok {
+			inout.AndNot(pl.Bitmap)
+		}
+		if rank := c.boundaryRank(id); rank >= 0 {
+			for _, layer := range c.excLayers {
+				inout.AndNot(layer.contain(rank))
+			}
+		}
+	}
+	return nil
+}
+
+func (c *BSIBEContainer) EncodeWildcard(id ConjunctionID) {
+	c.AddWildcard(id)
+}
+
+func (c *BSIBEContainer) EncodeExpr(id ConjunctionID, expr *be_indexer.BooleanExpr) error {
+	if expr == nil || util.NilInterface(expr.Value) {
+		return nil
+	}
+	c.exprs = c.cow.writable(c.exprs)
+	c.exprs.Add(uint64(id))
+
+	switch expr.Operator {
+	case be_indexer.ValueOptEQ:
+		values, err := parser.ParseIntegers(expr.Value, true)
+		if err != nil {
+			return fmt.Errorf("bsi container need integer values, err:%v", err)
+		}
+		for _, v := range values {
+			if expr.Incl {
+				c.addPoint(c.incPoints, v, id)
+			} else {
+				c.addPoint(c.excPoints, v, id)
+			}
+		}
+	case be_indexer.ValueOptGT, be_indexer.ValueOptLT, be_indexer.ValueOptBetween:
+		rg, err := rangeholder.ParseRange(expr.Operator, expr.Value, true)
+		if err != nil {
+			return err
+		}
+		if expr.Incl {
+			c.incRanges[id] = append(c.incRanges[id], *rg)
+		} else {
+			c.excRanges[id] = append(c.excRanges[id], *rg)
+		}
+	default:
+		return fmt.Errorf("bsi container not supported operator:%d", expr.Operator)
+	}
+	return nil
+}
+
+// mergeRanges merge overlapped or adjacent ranges into disjoint intervals
+func mergeRanges(rgs []rangeholder.Range) (res []rangeholder.Range) {
+	sort.Slice(rgs, func(i, j int) bool {
+		return rgs[i].Left() < rgs[j].Left()
+	})
+	for _, rg := range rgs {
+		last := len(res) - 1
+		if last >= 0 && rg.Left() <= res[last].Right() {
+			if rg.Right() > res[last].Right() {
+				res[last] = *rangeholder.NewRange(res[last].Left(), rg.Right())
+			}
+			continue
+		}
+		res = append(res, rg)
+	}
+	return res
+}
+
+// encodeLayers encode ranges into layers, boundaries of ranges must exist
+func (c *BSIBEContainer) encodeLayers(layers []*rangeLayer, ranges map[ConjunctionID][]rangeholder.Range) []*rangeLayer {
+	bitCnt := bits.Len(uint(len(c.boundaries)))
+	for id, rgs := range ranges {
+		for idx, rg := range rgs {
+			if idx >= len(layers) {
+				layers = append(layers, &rangeLayer{left: newBitSlices(bitCnt), right: newBitSlices(bitCnt)})
+			}
+			layers[idx].left.set(&c.cow, uint64(id), uint64(c.boundaryRank(rg.Left())))
+			layers[idx].right.set(&c.cow, uint64(id), uint64(c.boundaryRank(rg.Right())))
+		}
+	}
+	return layers
+}
+
+// removeFromLayers remove ids from layers, empty layers will be dropped
+func (c *BSIBEContainer) removeFromLayers(layers []*rangeLayer, ids *roaring64.Bitmap) []*rangeLayer {
+	res := layers[:0]
+	for _, layer := range layers {
+		if layer.left.exist.Intersects(ids) {
+			layer.left.remove(&c.cow, ids)
+			layer.right.remove(&c.cow, ids)
+		}
+		if !layer.left.exist.IsEmpty() {
+			res = append(res, layer)
+		}
+	}
+	return res
+}
+
+// boundariesExist whether all boundaries of ranges exist, ranges can be encoded into current layers
+func (c *BSIBEContainer) boundariesExist(ranges map[ConjunctionID][]rangeholder.Range) bool {
+	for _, rgs := range ranges {
+		for _, rg := range rgs {
+			if !c.boundaryExist(rg.Left()) || !c.boundaryExist(rg.Right()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *BSIBEContainer) boundaryExist(v int64) bool {
+	rank := c.boundaryRank(v)
+	return rank >= 0 && c.boundaries[rank] == v
+}
+
+// BuildBEContainer ranges added by mutator are encoded into current layers if their boundaries
+// exist; otherwise the ranks of boundaries changed, all layers are rebuilt
+func (c *BSIBEContainer) BuildBEContainer() (BEContainer, error) {
+	for _, ranges := range []map[ConjunctionID][]rangeholder.Range{c.incRanges, c.excRanges} {
+		for id, rgs := range ranges {
+			ranges[id] = mergeRanges(rgs)
+		}
+	}
+	if !c.boundariesExist(c.incRanges) || !c.boundariesExist(c.excRanges) {
+		c.rebuildLayers()
+	}
+	c.incLayers = c.encodeLayers(c.incLayers, c.incRanges)
+	c.excLayers = c.encodeLayers(c.excLayers, c.excRanges)
+	c.incRanges, c.excRanges = nil, nil
+	c.cow.done()
+	return c, nil
+}
+
+// rebuildLayers restore ranges from layers, and rebuild boundaries with all ranges; layers are reset
+func (c *BSIBEContainer) rebuildLayers() {
+	restore := func(ranges map[ConjunctionID][]rangeholder.Range, layers []*rangeLayer) {
+		for id, rgs := range c.decodeRanges(layers) {
+			ranges[id] = append(ranges[id], rgs...)
+		}
+	}
+	restore(c.incRanges, c.incLayers)
+	restore(c.excRanges, c.excLayers)
+
+	boundaries := make([]int64, 0, (len(c.incRanges)+len(c.excRanges))*2)
+	for _, ranges := range []map[ConjunctionID][]rangeholder.Range{c.incRanges, c.excRanges} {
+		for _, rgs := range ranges {
+			for _, rg := range rgs {
+				boundaries = append(boundaries, rg.Left(), rg.Right())
+			}
+		}
+	}
+	c.boundaries = util.DistinctInteger(boundaries)
+	sort.Slice(c.boundaries, func(i, j int) bool {
+		return c.boundaries[i] < c.boundaries[j]
+	})
+	c.incLayers, c.excLayers = nil, nil
+}
+
+// Statistics each range layer counted as an indexed value, a point query may hit all layers
+// so the estimation of range field is optimistic
+func (c *BSIBEContainer) Statistics() FieldStatistics {
+	stats := FieldStatistics{Wildcards: c.wc.GetCardinality()}
+	stats.Values, stats.Postings = postingListsStatistics(c.incPoints)
+	for _, layer := range c.incLayers {
+		stats.Values++
+		stats.Postings += layer.left.exist.GetCardinality()
+	}
+	return stats
+}
+
+// values decode value of all ids
+func (bs *bitSlices) values() map[ConjunctionID]int {
+	res := map[ConjunctionID]int{}
+	iter := bs.exist.Iterator()
+	for iter.HasNext() {
+		res[ConjunctionID(iter.Next())] = 0
+	}
+	for i, pl := range bs.slices {
+		iter = pl.Iterator()
+		for iter.HasNext() {
+			res[ConjunctionID(iter.Next())] |= 1 << uint(i)
+		}
+	}
+	return res
+}
+
+// decodeRanges restore the merged ranges of conjunctions from layers
+func (c *BSIBEContainer) decodeRanges(layers []*rangeLayer) map[ConjunctionID][]rangeholder.Range {
+	ranges := map[ConjunctionID][]rangeholder.Range{}
+	for _, layer := range layers {
+		rights := layer.right.values()
+		for id, left := range layer.left.values() {
+			rg := rangeholder.NewRange(c.boundaries[left], c.boundaries[rights[id]])
+			ranges[id] = append(ranges[id], *rg)
+		}
+	}
+	return ranges
+}
+
+// Mutator return a copy-on-write mutator sharing point posting lists and range layers with c,
+// range layers will be rebuilt only when ranges added need new boundaries
+func (c *BSIBEContainer) Mutator() BEContainerMutator {
+	return &BSIBEContainer{
+		meta:       c.meta,
+		wc:         c.wc,
+		incPoints:  cloneMap(c.incPoints),
+		excPoints:  cloneMap(c.excPoints),
+		incRanges:  map[ConjunctionID][]rangeholder.Range{},
+		excRanges:  map[ConjunctionID][]rangeholder.Range{},
+		boundaries: c.boundaries,
+		incLayers:  shallowCopy(c.incLayers),
+		excLayers:  shallowCopy(c.excLayers),
+		exprs:      c.exprs,
+		cow:        newCowState(),
+	}
+}
+
+func (c *BSIBEContainer) RemoveConjunctions(ids *roaring64.Bitmap) {
+	c.wc = removeFromPostingList(&c.cow, c.wc, ids)
+	removeFromMap(&c.cow, c.incPoints, ids)
+	removeFromMap(&c.cow, c.excPoints, ids)
+	c.exprs = removeFromPostingList(&c.cow, c.exprs, ids)
+	c.incLayers = c.removeFromLayers(c.incLayers, ids)
+	c.excLayers = c.removeFromLayers(c.excLayers, ids)
+	iter := ids.Iterator()
+	for iter.HasNext() {
+		id := ConjunctionID(iter.Next())
+		delete(c.incRanges, id)
+		delete(c.excRanges, id)
+	}
+}
+
+func (c *BSIBEContainer) HasExpressions(ids *roaring64.Bitmap) bool {
+	return c.exprs.Intersects(ids)
+}
+
+// WildcardMutator the new container share points and range layers with c
+func (c *BSIBEContainer) WildcardMutator() BEContainerMutator {
+	return newWildcardMutator(c, c.wc, func(wc PostingList) BEContainer {
+		clone := *c
+		clone.wc = wc
+		return &clone
+	})
+}
+
+func writeBitSlices(bw *binWriter, bs *bitSlices) {
+	bw.writeBitmap(bs.exist)
+	bw.writeUint32(uint32(len(bs.slices)))
+	for _, pl := range bs.slices {
+		bw.writeBitmap(pl)
+	}
+}
+
+func readBitSlices(br *binReader) *bitSlices {
+	bs := &bitSlices{exist: br.readBitmap()}
+	cnt := br.readSize()
+	for i := 0; i < cnt && br.err == nil; i++ {
+		bs.slices = append(bs.slices, br.readBitmap())
+	}
+	return bs
+}
+
+func writePointPostingLists(bw *binWriter, pls map[int64]PostingList) {
+	values := make([]int64, 0, len(pls))
+	for v := range pls {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+	bw.writeUint32(uint32(len(values)))
+	for _, v := range values {
+		bw.writeUint64(uint64(v))
+		bw.writeBitmap(pls[v])
+	}
+}
+
+func readPointPostingLists(br *binReader) map[int64]PostingList {
+	cnt := br.readSize()
+	pls := map[int64]PostingList{}
+	for i := 0; i < cnt && br.err == nil; i++ {
+		v := int64(br.readUint64())
+		pls[v] = br.readBitmap()
+	}
+	return pls
+}
+
+func writeRangeLayers(bw *binWriter, layers []*rangeLayer) {
+	bw.writeUint32(uint32(len(layers)))
+	for _, layer := range layers {
+		writeBitSlices(bw, layer.left)
+		writeBitSlices(bw, layer.right)
+	}
+}
+
+func readRangeLayers(br *binReader) (layers []*rangeLayer) {
+	cnt := br.readSize()
+	for i := 0; i < cnt && br.err == nil; i++ {
+		layer := &rangeLayer{left: readBitSlices(br)}
+		layer.right = readBitSlices(br)
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+// WriteTo serialize a built container
+func (c *BSIBEContainer) WriteTo(w io.Writer) (int64, error) {
+	bw := &binWriter{w: w}
+	bw.writeBitmap(c.wc)
+	writePointPostingLists(bw, c.incPoints)
+	writePointPostingLists(bw, c.excPoints)
+	bw.writeUint32(uint32(len(c.boundaries)))
+	for _, v := range c.boundaries {
+		bw.writeUint64(uint64(v))
+	}
+	writeRangeLayers(bw, c.incLayers)
+	writeRangeLayers(bw, c.excLayers)
+	return bw.n, bw.err
+}
+
+// ReadFrom load container serialized by WriteTo
+func (c *BSIBEContainer) ReadFrom(r io.Reader) (int64, error) {
+	br := newBinReader(r)
+	c.wc = br.readBitmap()
+	c.incPoints = readPointPostingLists(br)
+	c.excPoints = readPointPostingLists(br)
+	cnt := br.readSize()
+	c.boundaries = nil
+	for i := 0; i < cnt && br.err == nil; i++ {
+		c.boundaries = append(c.boundaries, int64(br.readUint64()))
+	}
+	c.incLayers = readRangeLayers(br)
+	c.excLayers = readRangeLayers(br)
+	c.incRanges, c.excRanges = nil, nil
+	c.exprs = unionPostingLists(c.incPoints, c.excPoints)
+	for _, layers := range [][]*rangeLayer{c.incLayers, c.excLayers} {
+		for _, layer := range layers {
+			c.exprs.Or(layer.left.exist.Bitmap)
+		}
+	}
+	return br.n, br.err
+}
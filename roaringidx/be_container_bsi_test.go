@@ -0,0 +1,131 @@
+package roaringidx
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestBitSlices_Compare(t *testing.T) {
+	convey.Convey("test bit sliced index compare", t, func() {
+		bs := newBitSlices(4)
+		for id := uint64(0); id < 16; id++ {
+			bs.set(&cowState{}, id, id)
+		}
+		for v := uint64(0); v < 16; v++ {
+			le, gt := bs.le(v), bs.gt(v)
+			convey.So(le.GetCardinality(), convey.ShouldEqual, v+1)
+			convey.So(gt.GetCardinality(), convey.ShouldEqual, 15-v)
+			convey.So(le.Contains(v), convey.ShouldBeTrue)
+			convey.So(gt.Contains(v), convey.ShouldBeFalse)
+		}
+	})
+}
+
+type testBSIExpr struct {
+	incl   bool
+	points []int64
+	left   int64 // [left, right) when points is empty
+	right  int64
+}
+
+func (e *testBSIExpr) match(v int64) bool {
+	if len(e.points) == 0 {
+		return v >= e.left && v < e.right
+	}
+	for _, p := range e.points {
+		if p == v {
+			return true
+		}
+	}
+	return false
+}
+
+func randBSIExpr(r *rand.Rand) (*be_indexer.BooleanExpr, *testBSIExpr) {
+	incl := r.Intn(3) > 0
+	switch r.Intn(4) {
+	case 0:
+		points := []int64{r.Int63n(200) - 100, r.Int63n(200) - 100}
+		return be_indexer.NewBoolExpr("age", incl, points), &testBSIExpr{incl: incl, points: points}
+	case 1:
+		l := r.Int63n(200) - 100
+		h := l + 1 + r.Int63n(60)
+		value := be_indexer.NewBoolValue(be_indexer.ValueOptBetween, []int64{l, h}, incl)
+		return be_indexer.NewBoolExpr2("age", value), &testBSIExpr{incl: incl, left: l, right: h}
+	case 2:
+		v := r.Int63n(200) - 100
+		value := be_indexer.NewBoolValue(be_indexer.ValueOptGT, v, incl)
+		return be_indexer.NewBoolExpr2("age", value), &testBSIExpr{incl: incl, left: v + 1, right: math.MaxInt64}
+	default:
+		v := r.Int63n(200) - 100
+		value := be_indexer.NewBoolValue(be_indexer.ValueOptLT, v, incl)
+		return be_indexer.NewBoolExpr2("age", value), &testBSIExpr{incl: incl, left: math.MinInt64, right: v}
+	}
+}
+
+func TestBSIBEContainer_Retrieve(t *testing.T) {
+	convey.Convey("test bsi container retrieve same as brute force", t, func() {
+		builder := NewIndexerBuilder()
+		convey.So(builder.ConfigureField("age", FieldSetting{Container: ContainerNameBSI}), convey.ShouldBeNil)
+		convey.So(builder.ConfigureField("tag", FieldSetting{
+			Container: ContainerNameDefault,
+			Parser:    parser.NewNumberParser(),
+		}), convey.ShouldBeNil)
+
+		r := rand.New(rand.NewSource(33))
+		docExprs := map[int64][][]*testBSIExpr{}
+		for id := int64(1); id <= 300; id++ {
+			doc := be_indexer.NewDocument(be_indexer.DocID(id))
+			for i := 0; i < 1+r.Intn(2); i++ {
+				conj := be_indexer.NewConjunction()
+				var exprs []*testBSIExpr
+				for j := 0; j < r.Intn(4); j++ {
+					expr, test := randBSIExpr(r)
+					conj.AddBoolExprs(expr)
+					exprs = append(exprs, test)
+				}
+				doc.AddConjunction(conj)
+				docExprs[id] = append(docExprs[id], exprs)
+			}
+			convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		}
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		for v := int64(-120); v <= 120; v++ {
+			var expect []uint64
+			for id, conjs := range docExprs {
+				for _, exprs := range conjs {
+					hasIncl, inclMatch, exclMatch := false, false, false
+					for _, e := range exprs {
+						if e.incl {
+							hasIncl = true
+							inclMatch = inclMatch || e.match(v)
+						} else {
+							exclMatch = exclMatch || e.match(v)
+						}
+					}
+					if (!hasIncl || inclMatch) && !exclMatch {
+						expect = append(expect, uint64(id))
+						break
+					}
+				}
+			}
+			sort.Slice(expect, func(i, j int) bool { return expect[i] < expect[j] })
+
+			scanner := NewScanner(indexer)
+			docs, err := scanner.Retrieve(be_indexer.Assignments{"age": v})
+			convey.So(err, convey.ShouldBeNil)
+			if len(expect) == 0 {
+				convey.So(len(docs), convey.ShouldEqual, 0)
+			} else {
+				convey.So(docs, convey.ShouldResemble, expect)
+			}
+		}
+	})
+}
@@ -9,6 +9,7 @@ type (
 const (
 	ContainerNameDefault = "default"
 	ContainerNameAcMatch = "ac_matcher"
+	ContainerNameBSI     = "bsi"
 )
 
 func init() {
@@ -18,11 +19,14 @@ func init() {
 		return NewDefaultBEContainer(meta)
 	}
 	containerFactory[ContainerNameAcMatch] = func(meta *FieldMeta) BEContainerBuilder {
+		if meta.ACOption != nil {
+			return NewACBEContainerWithOption(meta, *meta.ACOption)
+		}
 		return NewACBEContainer(meta, DefaultACContainerQueryJoinSep)
 	}
-	//containerFactory["bsi"] = func(setting FieldSetting) BEContainerBuilder {
-	//	return NewBSIBEContainerBuilder(setting)
-	//}
+	containerFactory[ContainerNameBSI] = func(meta *FieldMeta) BEContainerBuilder {
+		return NewBSIBEContainer(meta)
+	}
 }
 
 func RegisterContainerBuilder(name string, builderFunc ContainerBuilderFunc) bool {
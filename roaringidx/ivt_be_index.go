@@ -0,0 +1,385 @@
+package roaringidx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/util"
+)
+
+type (
+	// IvtBEIndex adapt IvtBEIndexer into be_indexer.BEIndex, so code written against BEIndex
+	// and ResultCollector can switch to roaring bitmap based index without changing;
+	// be_indexer.WithTrace is not supported, retrieving with it return error
+	IvtBEIndex struct {
+		indexer *IvtBEIndexer
+
+		// logger injected logger for step detail output, global be_indexer.Logger used when nil
+		logger be_indexer.StructuredLogger
+
+		attributes *be_indexer.AttributeStore
+
+		payloads *be_indexer.PayloadStore
+	}
+
+	// ivtDocIterator iterate conjunction ids result of a pooled scanner, the scanner
+	// is released when iterator exhausted or closed
+	ivtDocIterator struct {
+		scanner *IvtScanner
+
+		iter roaring64.IntPeekable64
+
+		options be_indexer.RetrieveOptions
+	}
+
+	// IvtBEIndexBuilder build IvtBEIndex from the same FieldOption configuration as be_indexer.IndexerBuilder,
+	// documents are buffered until BuildIndex, fields not configured will use default container
+	IvtBEIndexBuilder struct {
+		settings map[be_indexer.BEField]FieldSetting
+
+		logger be_indexer.StructuredLogger
+
+		docs []*be_indexer.Document
+	}
+)
+
+func NewIvtBEIndex(indexer *IvtBEIndexer) *IvtBEIndex {
+	util.PanicIf(indexer == nil, "nil indexer is not allowed")
+	return &IvtBEIndex{
+		indexer:    indexer,
+		attributes: be_indexer.NewAttributeStore(),
+		payloads:   be_indexer.NewPayloadStore(),
+	}
+}
+
+func (idx *IvtBEIndex) Attributes() *be_indexer.AttributeStore {
+	return idx.attributes
+}
+
+func (idx *IvtBEIndex) Payloads() *be_indexer.PayloadStore {
+	return idx.payloads
+}
+
+// WriteTo serialize the index as a snapshot: the indexer(see IvtBEIndexer.WriteTo) followed by
+// document payloads and attributes
+func (idx *IvtBEIndex) WriteTo(w io.Writer) (int64, error) {
+	n, err := idx.indexer.WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	m, err := idx.payloads.WriteTo(w)
+	if n += m; err != nil {
+		return n, err
+	}
+	m, err = idx.attributes.WriteTo(w)
+	return n + m, err
+}
+
+// LoadIvtBEIndex load a index snapshot serialized by IvtBEIndex.WriteTo
+func LoadIvtBEIndex(r io.Reader, opts ...LoadOption) (*IvtBEIndex, error) {
+	indexer, err := LoadIvtBEIndexer(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return loadIvtBEIndexStores(NewIvtBEIndex(indexer), r)
+}
+
+// LoadFrozenIvtBEIndex load a index snapshot serialized by IvtBEIndex.WriteTo from data without copying
+// bitmaps, see LoadFrozenIvtBEIndexer for the lifetime requirement of data; payloads and attributes are copied
+func LoadFrozenIvtBEIndex(data []byte, opts ...LoadOption) (*IvtBEIndex, error) {
+	indexer, n, err := LoadFrozenIvtBEIndexer(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return loadIvtBEIndexStores(NewIvtBEIndex(indexer), bytes.NewReader(data[n:]))
+}
+
+func loadIvtBEIndexStores(index *IvtBEIndex, r io.Reader) (*IvtBEIndex, error) {
+	if _, err := index.payloads.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("load payloads fail:%v", err)
+	}
+	if _, err := index.attributes.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("load attributes fail:%v", err)
+	}
+	return index, nil
+}
+
+// SetLogger inject logger for this index like be_indexer.WithLogger, it should be set before retrieving;
+// nil means fallback to global be_indexer.Logger
+func (idx *IvtBEIndex) SetLogger(logger be_indexer.StructuredLogger) {
+	idx.logger = logger
+}
+
+// acquireScanner acquire a scanner configured by retrieve options, error when option not supported
+func (idx *IvtBEIndex) acquireScanner(options *be_indexer.RetrieveOptions) (*IvtScanner, error) {
+	if options.Trace != nil {
+		return nil, fmt.Errorf("WithTrace not supported by roaringidx index")
+	}
+	scanner := AcquireScanner(idx.indexer)
+	scanner.SetDebug(options.StepDetail)
+	scanner.SetLogger(idx.logger)
+	return scanner, nil
+}
+
+// Indexer return the underlying IvtBEIndexer, used for updating documents
+func (idx *IvtBEIndex) Indexer() *IvtBEIndexer {
+	return idx.indexer
+}
+
+func (idx *IvtBEIndex) Retrieve(queries be_indexer.Assignments, opts ...be_indexer.IndexOpt) (be_indexer.DocIDList, error) {
+	collector := be_indexer.PickCollector()
+	defer be_indexer.PutCollector(collector)
+
+	if err := idx.RetrieveWithCollector(queries, collector, opts...); err != nil {
+		return nil, err
+	}
+	return collector.GetDocIDs(), nil
+}
+
+// conjunctionSize count fields the conjunction has include expression on, same as the size of
+// be_indexer.ConjID(zero means wildcard conjunction); containers not implementing WildcardContainer
+// are counted as included
+func conjunctionSize(data *ivtIndexData, id ConjunctionID) int {
+	size := 0
+	for _, container := range data.data {
+		if wc, ok := container.(WildcardContainer); ok && wc.IsWildcard(id) {
+			continue
+		}
+		size++
+	}
+	return size
+}
+
+// newConjID convert roaringidx conjunction id into be_indexer.ConjID with real conjunction size
+func newConjID(data *ivtIndexData, docID be_indexer.DocID, id ConjunctionID) be_indexer.ConjID {
+	return be_indexer.NewConjID(docID, int(id.Idx()), util.MinInt(conjunctionSize(data, id), 255))
+}
+
+// RetrieveWithCollector feed each matched conjunction into collector, the ConjID carry
+// document id, conjunction index and conjunction size(count of fields has include expression)
+func (idx *IvtBEIndex) RetrieveWithCollector(
+	queries be_indexer.Assignments, collector be_indexer.ResultCollector, opts ...be_indexer.IndexOpt) error {
+
+	options := be_indexer.NewRetrieveOptions(opts...)
+	util.PanicIf(options.Collector != nil, "can't specify collector twice")
+
+	scanner, err := idx.acquireScanner(&options)
+	if err != nil {
+		return err
+	}
+	defer ReleaseScanner(scanner)
+
+	if err = scanner.retrieve(queries); err != nil {
+		return err
+	}
+
+	iter := scanner.conjIDResults.Iterator()
+	for iter.HasNext() {
+		conjID := ConjunctionID(iter.Next())
+		docID := be_indexer.DocID(conjID.DocID())
+		if !be_indexer.ValidDocID(docID) {
+			return fmt.Errorf("doc:%d overflow, BEIndex support max doc id:%d", docID, be_indexer.MaxDocID)
+		}
+		if !options.DocAllowed(docID) {
+			continue
+		}
+		collector.Add(docID, newConjID(scanner.indexData(), docID, conjID))
+		if stopper, ok := collector.(be_indexer.StoppableCollector); ok && stopper.Done() {
+			break
+		}
+	}
+	return nil
+}
+
+// RetrieveIter scanner merge bitmaps of all fields at once, so only the conversion from conjunction
+// ids is lazy; result is ascending by document then conjunction index, each conjunction yielded once;
+// documents overflow be_indexer.MaxDocID are skipped, RetrieveWithCollector report them as error
+func (idx *IvtBEIndex) RetrieveIter(queries be_indexer.Assignments, opts ...be_indexer.IndexOpt) (be_indexer.DocIterator, error) {
+	options := be_indexer.NewRetrieveOptions(opts...)
+	util.PanicIf(options.Collector != nil, "collector not supported by iterator")
+
+	scanner, err := idx.acquireScanner(&options)
+	if err != nil {
+		return nil, err
+	}
+	if err = scanner.retrieve(queries); err != nil {
+		ReleaseScanner(scanner)
+		return nil, err
+	}
+	return &ivtDocIterator{
+		scanner: scanner,
+		iter:    scanner.conjIDResults.Iterator(),
+		options: options,
+	}, nil
+}
+
+func (it *ivtDocIterator) Next() (be_indexer.DocID, be_indexer.ConjID, bool) {
+	for it.scanner != nil && it.iter.HasNext() {
+		conjID := ConjunctionID(it.iter.Next())
+		docID := be_indexer.DocID(conjID.DocID())
+		if !be_indexer.ValidDocID(docID) || !it.options.DocAllowed(docID) {
+			continue
+		}
+		return docID, newConjID(it.scanner.indexData(), docID, conjID), true
+	}
+	it.Close()
+	return 0, 0, false
+}
+
+func (it *ivtDocIterator) Close() {
+	if it.scanner == nil {
+		return
+	}
+	ReleaseScanner(it.scanner)
+	it.scanner, it.iter = nil, nil
+}
+
+func (idx *IvtBEIndex) DumpEntries(sb *strings.Builder) {
+	sb.WriteString("\n+++++++ roaringidx boolean indexing entries +++++++++++\n")
+	data := idx.indexer.load()
+	for _, field := range data.sortedFields() {
+		sb.WriteString(fmt.Sprintf("field:%s container:%s", field, data.data[field].Meta().Container))
+		if stats, ok := data.stats[field]; ok {
+			sb.WriteString(fmt.Sprintf(" wildcards:%d values:%d postings:%d", stats.Wildcards, stats.Values, stats.Postings))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("+++++++++++++ dump entries end ++++++++++++++++++++++\n")
+}
+
+func (idx *IvtBEIndex) DumpIndexInfo(sb *strings.Builder) {
+	sb.WriteString("\n+++++++ roaringidx boolean indexing info +++++++++++\n")
+	data := idx.indexer.load()
+	summary := map[string]interface{}{
+		"docMaxConjSize": data.docMaxConjSize,
+	}
+	for _, field := range data.sortedFields() {
+		meta := data.data[field].Meta()
+		info := map[string]interface{}{
+			"container": meta.Container,
+		}
+		if meta.Parser != nil {
+			info["parser"] = meta.Parser.Name()
+		}
+		if stats, ok := data.stats[field]; ok {
+			info["statistics"] = stats
+		}
+		summary[fmt.Sprintf("field#%s", field)] = info
+	}
+	sb.WriteString(util.JSONPretty(summary))
+	sb.WriteString("\n++++++++++++++dump index info end ++++++++++++++++\n")
+}
+
+func (d *ivtIndexData) sortedFields() []be_indexer.BEField {
+	fields := make([]be_indexer.BEField, 0, len(d.data))
+	for field := range d.data {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i] < fields[j]
+	})
+	return fields
+}
+
+func NewIvtBEIndexBuilder() *IvtBEIndexBuilder {
+	return &IvtBEIndexBuilder{
+		settings: map[be_indexer.BEField]FieldSetting{},
+	}
+}
+
+// HolderSetting map be_indexer holder name into roaringidx container setting:
+// default => default container, ac_matcher => ac_matcher container, ext_range/adaptive_range => bsi container
+func HolderSetting(option be_indexer.FieldOption) (FieldSetting, error) {
+	switch option.Container {
+	case "", be_indexer.HolderNameDefault:
+		return FieldSetting{Container: ContainerNameDefault}, nil
+	case be_indexer.HolderNameACMatcher:
+		return FieldSetting{Container: ContainerNameAcMatch}, nil
+	case be_indexer.HolderNameExtendRange, be_indexer.HolderNameAdaptiveRange:
+		return FieldSetting{Container: ContainerNameBSI}, nil
+	}
+	return FieldSetting{}, fmt.Errorf("holder:%s has no equivalent roaringidx container", option.Container)
+}
+
+// SetLogger inject logger for the index built by this builder, same as be_indexer.WithLogger
+func (b *IvtBEIndexBuilder) SetLogger(logger be_indexer.StructuredLogger) {
+	b.logger = logger
+}
+
+// ConfigField same as be_indexer.IndexerBuilder.ConfigField, panic when holder not supported
+func (b *IvtBEIndexBuilder) ConfigField(field be_indexer.BEField, option be_indexer.FieldOption) {
+	setting, err := HolderSetting(option)
+	util.PanicIfErr(err, "config field:%s with option fail:%+v", field, option)
+	b.ConfigFieldSetting(field, setting)
+}
+
+// ConfigFieldSetting configure field with roaringidx setting directly, eg: specify a custom parser
+func (b *IvtBEIndexBuilder) ConfigFieldSetting(field be_indexer.BEField, setting FieldSetting) {
+	_, ok := b.settings[field]
+	util.PanicIf(ok, "can't configure field:%s twice", field)
+	b.settings[field] = setting
+}
+
+func (b *IvtBEIndexBuilder) AddDocument(docs ...*be_indexer.Document) error {
+	for _, doc := range docs {
+		util.PanicIf(doc == nil, "nil document not be allowed")
+		if len(doc.Cons) == 0 {
+			return fmt.Errorf("no conjunctions in this document")
+		}
+		b.docs = append(b.docs, doc)
+	}
+	return nil
+}
+
+// BuildIndex configure all fields(not configured fields use default container) then encode
+// buffered documents, panic when fail like be_indexer.IndexerBuilder
+func (b *IvtBEIndexBuilder) BuildIndex() be_indexer.BEIndex {
+	index, err := b.Build()
+	util.PanicIfErr(err, "fail build roaringidx index, err:%+v", err)
+	return index
+}
+
+// Build same as BuildIndex but return error instead of panic
+func (b *IvtBEIndexBuilder) Build() (*IvtBEIndex, error) {
+	settings := make(map[be_indexer.BEField]FieldSetting, len(b.settings))
+	for field, setting := range b.settings {
+		settings[field] = setting
+	}
+	for _, doc := range b.docs {
+		for _, conj := range doc.Cons {
+			for field := range conj.Expressions {
+				if _, ok := settings[field]; !ok {
+					settings[field] = FieldSetting{Container: ContainerNameDefault}
+				}
+			}
+		}
+	}
+
+	builder := NewIndexerBuilder()
+	for field, setting := range settings {
+		if err := builder.ConfigureField(string(field), setting); err != nil {
+			return nil, err
+		}
+	}
+	if err := builder.AddDocuments(b.docs...); err != nil {
+		return nil, err
+	}
+	indexer, err := builder.BuildIndexer()
+	if err != nil {
+		return nil, err
+	}
+	index := NewIvtBEIndex(indexer)
+	index.SetLogger(b.logger)
+	for _, doc := range b.docs {
+		if err = index.attributes.Set(doc.ID, doc.Attrs); err != nil {
+			return nil, err
+		}
+		index.payloads.Set(doc.ID, doc.Payload)
+	}
+	return index, nil
+}
@@ -0,0 +1,186 @@
+package roaringidx
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+type conjCollector struct {
+	be_indexer.DocIDCollector
+	conjs []be_indexer.ConjID
+}
+
+func (c *conjCollector) Add(id be_indexer.DocID, conj be_indexer.ConjID) {
+	c.DocIDCollector.Add(id, conj)
+	c.conjs = append(c.conjs, conj)
+}
+
+func sortConjIDs(ids []be_indexer.ConjID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+func TestIvtBEIndex_Retrieve(t *testing.T) {
+	convey.Convey("test roaringidx adapter agree with be_indexer", t, func() {
+		docs := []*be_indexer.Document{
+			be_indexer.NewDocument(1).AddConjunction(
+				be_indexer.NewConjunction().In("age", []int64{1, 2}).NotIn("city", []string{"sh"}),
+				be_indexer.NewConjunction().In("city", []string{"bj"}).GreaterThan("score", 60),
+			),
+			be_indexer.NewDocument(2).AddConjunction(be_indexer.NewConjunction().NotIn("age", []int64{3})),
+			be_indexer.NewDocument(3).AddConjunction(
+				be_indexer.NewConjunction().In("age", []int64{3}).In("city", []string{"sh", "gz"}),
+				be_indexer.NewConjunction().Between("score", 10, 20),
+			),
+		}
+		docs[0].Attrs = be_indexer.DocAttrs{"advertiser": 7}
+		docs[0].Payload = []byte("bid:7")
+		defer func() { docs[0].Attrs, docs[0].Payload = nil, nil }()
+		option := be_indexer.FieldOption{Container: be_indexer.HolderNameExtendRange}
+
+		builder := be_indexer.NewIndexerBuilder()
+		builder.ConfigField("score", option)
+		convey.So(builder.AddDocument(docs...), convey.ShouldBeNil)
+		expectIndex := builder.BuildIndex()
+
+		ivtBuilder := NewIvtBEIndexBuilder()
+		ivtBuilder.ConfigField("score", option)
+		convey.So(ivtBuilder.AddDocument(docs...), convey.ShouldBeNil)
+		var index be_indexer.BEIndex = ivtBuilder.BuildIndex()
+
+		queries := []be_indexer.Assignments{
+			{},
+			{"age": 1},
+			{"age": 1, "city": "sh"},
+			{"age": 3, "city": "gz"},
+			{"city": "bj", "score": 61},
+			{"score": 10},
+			{"age": []int64{2, 3}, "city": []string{"bj", "sh"}, "score": 20},
+		}
+		for _, assigns := range queries {
+			expect, err := expectIndex.Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			result, err := index.Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			sort.Sort(expect)
+			sort.Sort(result)
+			convey.So(result, convey.ShouldResemble, expect)
+
+			// conjunction size agree with be_indexer, zero only for wildcard conjunction
+			expectConjs := &conjCollector{DocIDCollector: *be_indexer.NewDocIDCollector()}
+			convey.So(expectIndex.RetrieveWithCollector(assigns, expectConjs), convey.ShouldBeNil)
+			resultConjs := &conjCollector{DocIDCollector: *be_indexer.NewDocIDCollector()}
+			convey.So(index.RetrieveWithCollector(assigns, resultConjs), convey.ShouldBeNil)
+			sortConjIDs(expectConjs.conjs)
+			sortConjIDs(resultConjs.conjs)
+			convey.So(resultConjs.conjs, convey.ShouldResemble, expectConjs.conjs)
+		}
+
+		collector := &conjCollector{DocIDCollector: *be_indexer.NewDocIDCollector()}
+		err := index.RetrieveWithCollector(be_indexer.Assignments{"city": "bj", "score": []int64{15, 61}, "age": 3}, collector)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(collector.GetDocIDs(), convey.ShouldResemble, be_indexer.DocIDList{1, 3})
+		convey.So(len(collector.conjs), convey.ShouldEqual, 2)
+		for _, conj := range collector.conjs {
+			switch conj.DocID() {
+			case 1:
+				convey.So(conj.Index(), convey.ShouldEqual, 1)
+			case 3:
+				convey.So(conj.Index(), convey.ShouldEqual, 1)
+			}
+		}
+
+		iter, err := index.(be_indexer.IterableIndex).RetrieveIter(be_indexer.Assignments{"city": "bj", "score": []int64{15, 61}, "age": 3},
+			be_indexer.WithDenyDocs(roaring64.BitmapOf(1)))
+		convey.So(err, convey.ShouldBeNil)
+		id, conj, ok := iter.Next()
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(id, convey.ShouldEqual, 3)
+		convey.So(conj, convey.ShouldEqual, be_indexer.NewConjID(3, 1, 1))
+		_, _, ok = iter.Next()
+		convey.So(ok, convey.ShouldBeFalse)
+		iter.Close()
+
+		result, err := index.Retrieve(be_indexer.Assignments{"city": "bj", "score": []int64{15, 61}},
+			be_indexer.WithDenyDocs(roaring64.BitmapOf(3)))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(result, convey.ShouldResemble, be_indexer.DocIDList{1, 2})
+		result, err = index.Retrieve(be_indexer.Assignments{"city": "bj", "score": []int64{15, 61}},
+			be_indexer.WithAllowDocs(roaring64.BitmapOf(2, 3)))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(result, convey.ShouldResemble, be_indexer.DocIDList{2, 3})
+
+		advertiser, ok := index.(be_indexer.AttributeIndex).Attributes().Int(1, "advertiser")
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(advertiser, convey.ShouldEqual, 7)
+
+		payloads, err := be_indexer.RetrieveWithPayloads(index, be_indexer.Assignments{"age": []int64{1}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(payloads[0], convey.ShouldResemble, be_indexer.DocPayload{ID: 1, Payload: []byte("bid:7")})
+
+		buf := &bytes.Buffer{}
+		_, err = index.(*IvtBEIndex).WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		loaded, err := LoadIvtBEIndex(buf)
+		convey.So(err, convey.ShouldBeNil)
+		loadedPayloads, err := be_indexer.RetrieveWithPayloads(loaded, be_indexer.Assignments{"age": []int64{1}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(loadedPayloads, convey.ShouldResemble, payloads)
+		convey.So(loaded.Attributes().Get(1), convey.ShouldResemble, be_indexer.DocAttrs{"advertiser": int64(7)})
+
+		buf.Reset()
+		_, err = index.(*IvtBEIndex).WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		frozen, err := LoadFrozenIvtBEIndex(buf.Bytes())
+		convey.So(err, convey.ShouldBeNil)
+		frozenPayloads, err := be_indexer.RetrieveWithPayloads(frozen, be_indexer.Assignments{"age": []int64{1}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(frozenPayloads, convey.ShouldResemble, payloads)
+		convey.So(frozen.Attributes().Get(1), convey.ShouldResemble, be_indexer.DocAttrs{"advertiser": int64(7)})
+
+		sb := &strings.Builder{}
+		index.DumpIndexInfo(sb)
+		convey.So(sb.String(), convey.ShouldContainSubstring, "field#score")
+		index.DumpEntries(sb)
+		convey.So(sb.String(), convey.ShouldContainSubstring, "container:bsi")
+	})
+
+	convey.Convey("test unsupported holder", t, func() {
+		_, err := HolderSetting(be_indexer.FieldOption{Container: be_indexer.HolderNameFuzzyMatch})
+		convey.So(err, convey.ShouldNotBeNil)
+		convey.So(func() {
+			NewIvtBEIndexBuilder().ConfigField("kw", be_indexer.FieldOption{Container: be_indexer.HolderNameKeywordExpr})
+		}, convey.ShouldPanic)
+	})
+}
+
+func TestIvtBEIndex_RetrieveOptions(t *testing.T) {
+	convey.Convey("test roaringidx adapter honor logger and reject trace", t, func() {
+		var msgs []string
+		logger := be_indexer.LogFunc(func(level int, msg string, kvs ...interface{}) {
+			msgs = append(msgs, msg)
+		})
+
+		builder := NewIvtBEIndexBuilder()
+		builder.SetLogger(logger)
+		doc := be_indexer.NewDocument(1).AddConjunction(be_indexer.NewConjunction().In("age", []int64{1}))
+		convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		index := builder.BuildIndex()
+
+		ids, err := index.Retrieve(be_indexer.Assignments{"age": 1}, be_indexer.WithStepDetail())
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ids, convey.ShouldResemble, be_indexer.DocIDList{1})
+		convey.So(msgs, convey.ShouldContain, "merger result")
+
+		trace := &be_indexer.RetrieveTrace{}
+		_, err = index.Retrieve(be_indexer.Assignments{"age": 1}, be_indexer.WithTrace(trace))
+		convey.So(err, convey.ShouldNotBeNil)
+		_, err = index.(be_indexer.IterableIndex).RetrieveIter(be_indexer.Assignments{"age": 1}, be_indexer.WithTrace(trace))
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
@@ -1,7 +1,11 @@
 package roaringidx
 
 import (
+	"sync"
+	"sync/atomic"
+
 	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/holder/ahoholder"
 	"github.com/echoface/be_indexer/parser"
 )
 
@@ -10,6 +14,9 @@ type (
 	FieldSetting struct {
 		Parser    parser.ValueIDGenerator
 		Container string
+		// ACOption normalization option for ac_matcher container(eg: CaseFolding),
+		// nil means only DefaultACContainerQueryJoinSep applied
+		ACOption *ahoholder.ACHolderOption
 	}
 
 	FieldMeta struct {
@@ -18,16 +25,34 @@ type (
 		field be_indexer.BEField
 	}
 
-	IvtBEIndexer struct {
+	// ivtIndexData an immutable version of index data, updating will create a new version
+	ivtIndexData struct {
 		docMaxConjSize int
 		data           map[be_indexer.BEField]BEContainer
+		stats          map[be_indexer.BEField]FieldStatistics
+	}
+
+	IvtBEIndexer struct {
+		// mu serialize the updating of index data
+		mu sync.Mutex
+
+		// current hold *ivtIndexData, replaced atomically when document updated
+		current atomic.Value
 	}
 )
 
 func NewIvtBEIndexer() *IvtBEIndexer {
-	return &IvtBEIndexer{
-		data: make(map[be_indexer.BEField]BEContainer),
-	}
+	indexer := &IvtBEIndexer{}
+	indexer.store(newIvtIndexData(0, make(map[be_indexer.BEField]BEContainer)))
+	return indexer
+}
+
+func (indexer *IvtBEIndexer) load() *ivtIndexData {
+	return indexer.current.Load().(*ivtIndexData)
+}
+
+func (indexer *IvtBEIndexer) store(data *ivtIndexData) {
+	indexer.current.Store(data)
 }
 
 func (meta *FieldMeta) FieldName() string {
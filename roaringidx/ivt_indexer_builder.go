@@ -73,26 +73,52 @@ func (builder *IvtBEIndexerBuilder) AddDocument(doc *be_indexer.Document) (err e
 		return fmt.Errorf("empty document(zero conjunctions) is not allowed")
 	}
 
+	if err = encodeDocument(doc, builder.containerBuilder); err != nil {
+		util.PanicIf(builder.panicOnError, "encode document:%d fail, err:%v", doc.ID, err)
+		return err
+	}
+
+	builder.docMaxConjSize = util.MaxInt(len(doc.Cons), builder.docMaxConjSize)
+	return nil
+}
+
+func (builder *IvtBEIndexerBuilder) BuildIndexer() (*IvtBEIndexer, error) {
+
+	data := make(map[be_indexer.BEField]BEContainer, len(builder.containerBuilder))
+	for field, fieldBuilder := range builder.containerBuilder {
+		container, err := fieldBuilder.BuildBEContainer()
+		if err != nil {
+			return nil, err
+		}
+		data[field] = container
+	}
+
+	indexer := NewIvtBEIndexer()
+	indexer.store(newIvtIndexData(builder.docMaxConjSize, data))
+	return indexer, nil
+}
+
+// encodeDocument encode all conjunctions of document into containers, a field not
+// presented in conjunction or has exclude expressions only will be encoded as wildcard
+func encodeDocument(doc *be_indexer.Document, containers map[be_indexer.BEField]BEContainerBuilder) (err error) {
 	for idx, conj := range doc.Cons {
 
 		var conjID ConjunctionID
 		if conjID, err = NewConjunctionID(idx, int64(doc.ID)); err != nil {
 			be_indexer.Logger.Errorf("gen conjunction id for doc:%d fail, err:%s", doc.ID, err.Error())
-			util.PanicIf(builder.panicOnError, "gen conj id fail,%v", err)
 			return err
 		}
 
 		// NOTE: check conjunction contains none-configured field expression
 		// this may case logic error if we omit those boolean-expression
 		for field := range conj.Expressions {
-			if _, ok := builder.containerBuilder[field]; !ok {
-				util.PanicIf(builder.panicOnError, "document contains none-configured field:%s", field)
+			if _, ok := containers[field]; !ok {
 				be_indexer.LogErrIf(true, "document contains none-configured field:%", field)
 				return fmt.Errorf("document contains none-configured field:%s", field)
 			}
 		}
 
-		for field, containerBuilder := range builder.containerBuilder {
+		for field, containerBuilder := range containers {
 			exprs, ok := conj.Expressions[field]
 			if !ok || len(exprs) == 0 {
 				containerBuilder.EncodeWildcard(conjID)
@@ -101,8 +127,7 @@ func (builder *IvtBEIndexerBuilder) AddDocument(doc *be_indexer.Document) (err e
 			addWildcard := true
 			for _, expr := range exprs {
 				if err = containerBuilder.EncodeExpr(conjID, be_indexer.NewBoolExpr2(field, *expr)); err != nil {
-					util.PanicIf(builder.panicOnError, "failed evaluate boolean expression:%+v", expr)
-					return err
+					return fmt.Errorf("failed evaluate boolean expression:%+v, err:%v", expr, err)
 				}
 				addWildcard = addWildcard && (!expr.Incl)
 			}
@@ -112,24 +137,5 @@ func (builder *IvtBEIndexerBuilder) AddDocument(doc *be_indexer.Document) (err e
 			}
 		}
 	}
-
-	builder.docMaxConjSize = util.MaxInt(len(doc.Cons), builder.docMaxConjSize)
 	return nil
 }
-
-func (builder *IvtBEIndexerBuilder) BuildIndexer() (*IvtBEIndexer, error) {
-
-	indexer := NewIvtBEIndexer()
-
-	for field, fieldBuilder := range builder.containerBuilder {
-		container, err := fieldBuilder.BuildBEContainer()
-		if err != nil {
-			return nil, err
-		}
-		indexer.data[field] = container
-	}
-
-	indexer.docMaxConjSize = builder.docMaxConjSize
-
-	return indexer, nil
-}
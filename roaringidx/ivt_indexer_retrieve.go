@@ -0,0 +1,88 @@
+package roaringidx
+
+import (
+	"sync"
+
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/util"
+)
+
+type (
+	retrieveOption struct {
+		debug bool
+
+		hints []int64
+	}
+
+	// RetrieveOpt per call option for IvtBEIndexer.Retrieve
+	RetrieveOpt func(opt *retrieveOption)
+)
+
+var scannerPool = sync.Pool{
+	New: func() interface{} {
+		return NewScanner(nil)
+	},
+}
+
+// WithHints restrict result into candidate documents, see IvtScanner.WithHint
+func WithHints(docs ...int64) RetrieveOpt {
+	return func(opt *retrieveOption) {
+		opt.hints = append(opt.hints, docs...)
+	}
+}
+
+// WithDebug log the merging result of each field for this call
+func WithDebug() RetrieveOpt {
+	return func(opt *retrieveOption) {
+		opt.debug = true
+	}
+}
+
+// AcquireScanner pick a reset scanner from pool, it must be released by ReleaseScanner
+// and can't be used by goroutines concurrently
+func AcquireScanner(indexer *IvtBEIndexer) *IvtScanner {
+	util.PanicIf(indexer == nil, "nil indexer is not allowed")
+	scanner := scannerPool.Get().(*IvtScanner)
+	scanner.indexer = indexer
+	return scanner
+}
+
+// ReleaseScanner reset scanner and put it back to pool, results of scanner
+// (eg: GetRawResult) can't be accessed after released
+func ReleaseScanner(scanner *IvtScanner) {
+	if scanner == nil {
+		return
+	}
+	scanner.Reset()
+	scanner.indexer = nil
+	scannerPool.Put(scanner)
+}
+
+func (indexer *IvtBEIndexer) acquireScanner(opts []RetrieveOpt) *IvtScanner {
+	option := &retrieveOption{}
+	for _, fn := range opts {
+		fn(option)
+	}
+	scanner := AcquireScanner(indexer)
+	scanner.SetDebug(option.debug)
+	if len(option.hints) > 0 {
+		scanner.WithHint(option.hints...)
+	}
+	return scanner
+}
+
+// Retrieve goroutine-safe retrieving with pooled scanner, return document id list
+func (indexer *IvtBEIndexer) Retrieve(assigns be_indexer.Assignments, opts ...RetrieveOpt) ([]uint64, error) {
+	scanner := indexer.acquireScanner(opts)
+	defer ReleaseScanner(scanner)
+
+	return scanner.Retrieve(assigns)
+}
+
+// RetrieveDocs goroutine-safe retrieving with pooled scanner, return document id as map
+func (indexer *IvtBEIndexer) RetrieveDocs(assigns be_indexer.Assignments, opts ...RetrieveOpt) (map[int64]struct{}, error) {
+	scanner := indexer.acquireScanner(opts)
+	defer ReleaseScanner(scanner)
+
+	return scanner.RetrieveDocs(assigns)
+}
@@ -0,0 +1,113 @@
+package roaringidx
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/echoface/be_indexer"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func randRetrieveTestAssigns(r *rand.Rand) be_indexer.Assignments {
+	return be_indexer.Assignments{
+		"ad_id": []int{r.Intn(20), r.Intn(20)},
+		"title": "word1 word3 word5",
+		"age":   int64(r.Intn(120)),
+	}
+}
+
+func TestIvtBEIndexer_Retrieve(t *testing.T) {
+	convey.Convey("test pooled retrieve concurrently", t, func() {
+		r := rand.New(rand.NewSource(7))
+		builder := NewIndexerBuilder()
+		configureUpdateTestFields(builder)
+		for id := int64(1); id <= 200; id++ {
+			convey.So(builder.AddDocument(randUpdateTestDoc(r, id)), convey.ShouldBeNil)
+		}
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		queries := make([]be_indexer.Assignments, 50)
+		expects := make([][]uint64, 50)
+		hintExpects := make([][]uint64, 50)
+		hints := []int64{1, 3, 5, 7, 11, 13, 17, 19, 23, 29}
+		for i := range queries {
+			queries[i] = randRetrieveTestAssigns(r)
+			expects[i], err = NewScanner(indexer).Retrieve(queries[i])
+			convey.So(err, convey.ShouldBeNil)
+
+			scanner := NewScanner(indexer)
+			scanner.WithHint(hints...)
+			hintExpects[i], err = scanner.Retrieve(queries[i])
+			convey.So(err, convey.ShouldBeNil)
+		}
+
+		hitCnt := 0
+		for i := range expects {
+			hitCnt += len(expects[i]) + len(hintExpects[i])
+		}
+		convey.So(hitCnt, convey.ShouldBeGreaterThan, 0)
+
+		var wg sync.WaitGroup
+		failed := make(chan int, 16*len(queries))
+		for g := 0; g < 16; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := range queries {
+					idx := (i + g) % len(queries)
+					var opts []RetrieveOpt
+					expect := expects[idx]
+					if g%2 == 0 {
+						opts = append(opts, WithHints(hints...))
+						expect = hintExpects[idx]
+					}
+					docs, err := indexer.Retrieve(queries[idx], opts...)
+					if err != nil || !equalUint64s(docs, expect) {
+						failed <- idx
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		close(failed)
+		convey.So(len(failed), convey.ShouldEqual, 0)
+
+		docs, err := indexer.RetrieveDocs(queries[0], WithDebug())
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(docs), convey.ShouldEqual, len(expects[0]))
+	})
+
+	convey.Convey("test hints restrict result", t, func() {
+		builder := NewIndexerBuilder()
+		configureUpdateTestFields(builder)
+		for id := int64(1); id <= 5; id++ {
+			doc := be_indexer.NewDocument(be_indexer.DocID(id))
+			doc.AddConjunction(be_indexer.NewConjunction().Include("ad_id", be_indexer.NewIntValues(1)))
+			convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		}
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		docs, err := indexer.Retrieve(be_indexer.Assignments{"ad_id": 1}, WithHints(2, 4, 8))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldResemble, []uint64{2, 4})
+
+		docs, err = indexer.Retrieve(be_indexer.Assignments{"ad_id": 1})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldResemble, []uint64{1, 2, 3, 4, 5})
+	})
+}
+
+func equalUint64s(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
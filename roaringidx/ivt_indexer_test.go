@@ -3,6 +3,7 @@ package roaringidx
 import (
 	"testing"
 
+	"github.com/echoface/be_indexer/holder/ahoholder"
 	"github.com/echoface/be_indexer/parser"
 
 	"github.com/echoface/be_indexer"
@@ -80,3 +81,47 @@ func TestIvtBEIndexer_AddDocument(t *testing.T) {
 		convey.So(indexer, convey.ShouldNotBeNil)
 	})
 }
+
+func TestIvtBEIndexer_ACOption(t *testing.T) {
+	convey.Convey("test ac_matcher container configured with folding option", t, func() {
+		builder := NewIndexerBuilder()
+		_ = builder.ConfigureField("title", FieldSetting{
+			Container: ContainerNameAcMatch,
+			ACOption: &ahoholder.ACHolderOption{
+				QuerySep:      DefaultACContainerQueryJoinSep,
+				CaseFolding:   true,
+				NFKCNormalize: true,
+				WidthFolding:  true,
+			},
+		})
+		_ = builder.ConfigureField("desc", FieldSetting{Container: ContainerNameAcMatch})
+
+		doc1 := be_indexer.NewDocument(1)
+		doc1.AddConjunction(be_indexer.NewConjunction().Include("title", be_indexer.NewStrValues("Nike")))
+		doc2 := be_indexer.NewDocument(2)
+		doc2.AddConjunction(be_indexer.NewConjunction().Include("desc", be_indexer.NewStrValues("Nike")))
+		convey.So(builder.AddDocuments(doc1, doc2), convey.ShouldBeNil)
+
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		scanner := NewScanner(indexer)
+		defer scanner.Reset()
+		for _, title := range []string{"nike", "ＮＩＫＥ", "NIKE shoes"} {
+			docs, err := scanner.Retrieve(be_indexer.Assignments{"title": []string{title}})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(docs, convey.ShouldResemble, []uint64{1})
+			scanner.Reset()
+		}
+
+		// field without ACOption keep exact match
+		docs, err := scanner.Retrieve(be_indexer.Assignments{"desc": []string{"ＮＩＫＥ"}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldBeEmpty)
+		scanner.Reset()
+
+		docs, err = scanner.Retrieve(be_indexer.Assignments{"desc": []string{"Nike"}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldResemble, []uint64{2})
+	})
+}
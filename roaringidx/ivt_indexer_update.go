@@ -0,0 +1,256 @@
+package roaringidx
+
+import (
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/util"
+)
+
+type (
+	// BEContainerMutator a copy-on-write builder derived from a built container,
+	// BuildBEContainer return the updated new container, the origin one never changed
+	BEContainerMutator interface {
+		BEContainerBuilder
+
+		// RemoveConjunctions remove ids from all wildcard/include/exclude posting lists
+		RemoveConjunctions(ids *roaring64.Bitmap)
+	}
+
+	// MutableBEContainer container support incremental updating
+	MutableBEContainer interface {
+		BEContainer
+
+		Mutator() BEContainerMutator
+
+		// HasExpressions whether any of ids has expressions(include/exclude values) on this field
+		HasExpressions(ids *roaring64.Bitmap) bool
+
+		// WildcardMutator return a mutator update wildcards only, the new container share all
+		// other data with the origin one; used for fields the document has no expression on
+		WildcardMutator() BEContainerMutator
+	}
+
+	wildcardMutator struct {
+		origin BEContainer
+
+		wc PostingList
+
+		// build the new container with updated wildcards
+		build func(wc PostingList) BEContainer
+
+		cow cowState
+	}
+
+	// cowState track the posting lists cloned(owned) by a mutator, all posting lists not
+	// owned are shared with the origin container which may be reading by other scanners
+	cowState struct {
+		enabled bool
+		owned   map[*roaring64.Bitmap]struct{}
+	}
+)
+
+func newCowState() cowState {
+	return cowState{enabled: true, owned: map[*roaring64.Bitmap]struct{}{}}
+}
+
+// writable return a posting list that can be modified safely
+func (cow *cowState) writable(pl PostingList) PostingList {
+	if !cow.enabled {
+		return pl
+	}
+	if _, ok := cow.owned[pl.Bitmap]; ok {
+		return pl
+	}
+	clone := PostingList{Bitmap: pl.Clone()}
+	cow.owned[clone.Bitmap] = struct{}{}
+	return clone
+}
+
+func (cow *cowState) newPostingList() PostingList {
+	pl := NewPostingList()
+	if cow.enabled {
+		cow.owned[pl.Bitmap] = struct{}{}
+	}
+	return pl
+}
+
+func (cow *cowState) done() {
+	cow.enabled, cow.owned = false, nil
+}
+
+// unionPostingLists all ids in posting lists
+func unionPostingLists[K comparable](plss ...map[K]PostingList) PostingList {
+	res := NewPostingList()
+	for _, pls := range plss {
+		for _, pl := range pls {
+			res.Or(pl.Bitmap)
+		}
+	}
+	return res
+}
+
+// cloneMap shallow copy posting list map, the posting lists are still shared
+func cloneMap[K comparable](pls map[K]PostingList) map[K]PostingList {
+	res := make(map[K]PostingList, len(pls))
+	for k, pl := range pls {
+		res[k] = pl
+	}
+	return res
+}
+
+// removeFromMap remove ids from all posting lists, empty posting list will be dropped;
+// return true if any posting list dropped
+func removeFromMap[K comparable](cow *cowState, pls map[K]PostingList, ids *roaring64.Bitmap) (dropped bool) {
+	for k, pl := range pls {
+		if !pl.Intersects(ids) {
+			continue
+		}
+		pl = cow.writable(pl)
+		pl.AndNot(ids)
+		if pl.IsEmpty() {
+			delete(pls, k)
+			dropped = true
+			continue
+		}
+		pls[k] = pl
+	}
+	return dropped
+}
+
+func removeFromPostingList(cow *cowState, pl PostingList, ids *roaring64.Bitmap) PostingList {
+	if !pl.Intersects(ids) {
+		return pl
+	}
+	pl = cow.writable(pl)
+	pl.AndNot(ids)
+	return pl
+}
+
+func newWildcardMutator(origin BEContainer, wc PostingList, build func(wc PostingList) BEContainer) *wildcardMutator {
+	return &wildcardMutator{origin: origin, wc: wc, build: build, cow: newCowState()}
+}
+
+func (m *wildcardMutator) EncodeWildcard(id ConjunctionID) {
+	m.wc = m.cow.writable(m.wc)
+	m.wc.Add(uint64(id))
+}
+
+func (m *wildcardMutator) EncodeExpr(id ConjunctionID, expr *be_indexer.BooleanExpr) error {
+	return fmt.Errorf("field:%s only wildcards can be updated", m.origin.Meta().field)
+}
+
+func (m *wildcardMutator) RemoveConjunctions(ids *roaring64.Bitmap) {
+	m.wc = removeFromPostingList(&m.cow, m.wc, ids)
+}
+
+// BuildBEContainer return the origin container if wildcards not changed
+func (m *wildcardMutator) BuildBEContainer() (BEContainer, error) {
+	changed := len(m.cow.owned) > 0
+	m.cow.done()
+	if !changed {
+		return m.origin, nil
+	}
+	return m.build(m.wc), nil
+}
+
+// docConjunctionIDs all possible conjunction ids of document
+func docConjunctionIDs(docID int64, docMaxConjSize int) (*roaring64.Bitmap, error) {
+	ids := roaring64.NewBitmap()
+	for idx := 0; idx < docMaxConjSize; idx++ {
+		id, err := NewConjunctionID(idx, docID)
+		if err != nil {
+			return nil, err
+		}
+		ids.Add(uint64(id))
+	}
+	return ids, nil
+}
+
+// RemoveDocument remove all conjunctions of the document, scanners already
+// retrieving keep reading the previous version of index data
+func (indexer *IvtBEIndexer) RemoveDocument(docID int64) error {
+	return indexer.update(docID, nil)
+}
+
+// UpsertDocument replace the document's conjunctions with new version(add if not exist),
+// it's copy-on-write, only the posting lists touched by the document are cloned.
+// NOTE: cost of a update(see BenchmarkIvtBEIndexer_UpsertDocument):
+//   - fields the document(old or new version) has no expression on: clone the wildcard posting list
+//   - other fields: shallow copy the posting list maps, clone the posting lists touched;
+//     ac machines are rebuilt when keywords added/removed, BSI range layers are rebuilt
+//     when ranges added need new boundaries
+//   - statistics are recomputed for the fields not in the first case only
+//
+// rebuilding the indexer is cheaper when many documents changed
+func (indexer *IvtBEIndexer) UpsertDocument(doc *be_indexer.Document) error {
+	if doc == nil || len(doc.Cons) == 0 {
+		return fmt.Errorf("empty document(zero conjunctions) is not allowed")
+	}
+	return indexer.update(int64(doc.ID), doc)
+}
+
+func (indexer *IvtBEIndexer) update(docID int64, doc *be_indexer.Document) error {
+	indexer.mu.Lock()
+	defer indexer.mu.Unlock()
+
+	current := indexer.load()
+	ids, err := docConjunctionIDs(docID, current.docMaxConjSize)
+	if err != nil {
+		return err
+	}
+
+	docFields := map[be_indexer.BEField]struct{}{}
+	if doc != nil {
+		for _, conj := range doc.Cons {
+			for field, exprs := range conj.Expressions {
+				if len(exprs) > 0 {
+					docFields[field] = struct{}{}
+				}
+			}
+		}
+	}
+
+	mutators := make(map[be_indexer.BEField]BEContainerBuilder, len(current.data))
+	for field, container := range current.data {
+		mutable, ok := container.(MutableBEContainer)
+		if !ok {
+			return fmt.Errorf("field:%s container:%s not support updating", field, container.Meta().Container)
+		}
+		var mutator BEContainerMutator
+		if _, ok = docFields[field]; ok || mutable.HasExpressions(ids) {
+			mutator = mutable.Mutator()
+		} else {
+			mutator = mutable.WildcardMutator()
+		}
+		mutator.RemoveConjunctions(ids)
+		mutators[field] = mutator
+	}
+
+	docMaxConjSize := current.docMaxConjSize
+	if doc != nil {
+		if err = encodeDocument(doc, mutators); err != nil {
+			return err
+		}
+		docMaxConjSize = util.MaxInt(len(doc.Cons), docMaxConjSize)
+	}
+	data := make(map[be_indexer.BEField]BEContainer, len(mutators))
+	stats := make(map[be_indexer.BEField]FieldStatistics, len(current.stats))
+	for field, mutator := range mutators {
+		if data[field], err = mutator.BuildBEContainer(); err != nil {
+			return err
+		}
+		// statistics recomputed for rebuilt fields only
+		if wm, ok := mutator.(*wildcardMutator); ok {
+			if s, ok := current.stats[field]; ok {
+				s.Wildcards = wm.wc.GetCardinality()
+				stats[field] = s
+			}
+		} else if provider, ok := data[field].(BEContainerStatistics); ok {
+			stats[field] = provider.Statistics()
+		}
+	}
+	indexer.store(&ivtIndexData{docMaxConjSize: docMaxConjSize, data: data, stats: stats})
+	return nil
+}
@@ -0,0 +1,192 @@
+package roaringidx
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func configureUpdateTestFields(builder *IvtBEIndexerBuilder) {
+	_ = builder.ConfigureField("ad_id", FieldSetting{Container: ContainerNameDefault, Parser: parser.NewNumberParser()})
+	_ = builder.ConfigureField("title", FieldSetting{Container: ContainerNameAcMatch})
+	_ = builder.ConfigureField("age", FieldSetting{Container: ContainerNameBSI})
+}
+
+func randUpdateTestDoc(r *rand.Rand, id int64) *be_indexer.Document {
+	doc := be_indexer.NewDocument(be_indexer.DocID(id))
+	for i := 0; i < 1+r.Intn(3); i++ {
+		conj := be_indexer.NewConjunction()
+		if r.Intn(2) == 0 {
+			conj.AddExpression3("ad_id", r.Intn(3) > 0, []int{r.Intn(20), r.Intn(20)})
+		}
+		if r.Intn(2) == 0 {
+			conj.AddExpression3("title", r.Intn(3) > 0, fmt.Sprintf("word%d", r.Intn(10)))
+		}
+		if r.Intn(2) == 0 {
+			l := int64(r.Intn(100))
+			conj.Between("age", l, l+int64(r.Intn(30)))
+		}
+		if r.Intn(3) == 0 {
+			conj.AddExpression3("age", false, []int{r.Intn(100)})
+		}
+		doc.AddConjunction(conj)
+	}
+	return doc
+}
+
+func randUpdateTestAssigns(r *rand.Rand) be_indexer.Assignments {
+	return be_indexer.Assignments{
+		"ad_id": []int{r.Intn(20), r.Intn(20)},
+		"title": fmt.Sprintf("word%d word%d", r.Intn(10), r.Intn(10)),
+		"age":   r.Intn(120),
+	}
+}
+
+func TestIvtBEIndexer_UpsertDocument(t *testing.T) {
+	convey.Convey("test update document same as rebuild", t, func() {
+		r := rand.New(rand.NewSource(35))
+		docs := map[int64]*be_indexer.Document{}
+
+		builder := NewIndexerBuilder()
+		configureUpdateTestFields(builder)
+		for id := int64(1); id <= 100; id++ {
+			docs[id] = randUpdateTestDoc(r, id)
+			convey.So(builder.AddDocument(docs[id]), convey.ShouldBeNil)
+		}
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		for i := 0; i < 100; i++ {
+			id := int64(1 + r.Intn(120))
+			if r.Intn(3) == 0 {
+				delete(docs, id)
+				convey.So(indexer.RemoveDocument(id), convey.ShouldBeNil)
+				continue
+			}
+			docs[id] = randUpdateTestDoc(r, id)
+			convey.So(indexer.UpsertDocument(docs[id]), convey.ShouldBeNil)
+		}
+
+		rebuilder := NewIndexerBuilder()
+		configureUpdateTestFields(rebuilder)
+		for _, doc := range docs {
+			convey.So(rebuilder.AddDocument(doc), convey.ShouldBeNil)
+		}
+		expectIndexer, err := rebuilder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		for i := 0; i < 300; i++ {
+			assigns := randUpdateTestAssigns(r)
+			expect, err := NewScanner(expectIndexer).Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			result, err := NewScanner(indexer).Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(result, convey.ShouldResemble, expect)
+		}
+
+		convey.So(indexer.FieldStatistics(), convey.ShouldResemble, expectIndexer.FieldStatistics())
+
+		convey.So(indexer.UpsertDocument(be_indexer.NewDocument(1)), convey.ShouldNotBeNil)
+		badDoc := be_indexer.NewDocument(1).AddConjunction(be_indexer.NewConjunction().In("unknown", 1))
+		convey.So(indexer.UpsertDocument(badDoc), convey.ShouldNotBeNil)
+	})
+}
+
+func TestIvtBEIndexer_UpdateWildcardsOnly(t *testing.T) {
+	convey.Convey("test fields document has no expression on share posting lists", t, func() {
+		builder := NewIndexerBuilder()
+		configureUpdateTestFields(builder)
+		doc := be_indexer.NewDocument(1).AddConjunction(be_indexer.NewConjunction().
+			In("ad_id", []int{1}).Include("title", be_indexer.NewStrValues("hello")))
+		convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		before := indexer.load()
+		doc = be_indexer.NewDocument(2).AddConjunction(be_indexer.NewConjunction().In("ad_id", []int{2}))
+		convey.So(indexer.UpsertDocument(doc), convey.ShouldBeNil)
+		after := indexer.load()
+
+		title, prevTitle := after.data["title"].(*ACBEContainer), before.data["title"].(*ACBEContainer)
+		id, _ := NewConjunctionID(0, 2)
+		convey.So(title, convey.ShouldNotPointTo, prevTitle)
+		convey.So(title.inc, convey.ShouldPointTo, prevTitle.inc)
+		convey.So(title.wc.Contains(uint64(id)), convey.ShouldBeTrue)
+		convey.So(after.stats["title"].Wildcards, convey.ShouldEqual, 1)
+
+		age, prevAge := after.data["age"].(*BSIBEContainer), before.data["age"].(*BSIBEContainer)
+		convey.So(age.exprs.Bitmap, convey.ShouldPointTo, prevAge.exprs.Bitmap)
+
+		// nothing changed when removing a document not indexed
+		convey.So(indexer.RemoveDocument(3), convey.ShouldBeNil)
+		convey.So(indexer.load().data["title"], convey.ShouldPointTo, title)
+	})
+}
+
+func TestIvtBEIndexer_UpdateConcurrently(t *testing.T) {
+	convey.Convey("test scanner keep reading pinned version when updating", t, func() {
+		builder := NewIndexerBuilder()
+		configureUpdateTestFields(builder)
+		doc := be_indexer.NewDocument(1).AddConjunction(be_indexer.NewConjunction().In("ad_id", []int{1}))
+		convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		pinned := NewScanner(indexer)
+		pinned.WithHint(1, 2)
+		convey.So(indexer.RemoveDocument(1), convey.ShouldBeNil)
+		docs, err := pinned.Retrieve(be_indexer.Assignments{"ad_id": 1})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldResemble, []uint64{1})
+
+		docs, err = NewScanner(indexer).Retrieve(be_indexer.Assignments{"ad_id": 1})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(docs), convey.ShouldEqual, 0)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(seed))
+				for j := 0; j < 200; j++ {
+					_, _ = NewScanner(indexer).Retrieve(randUpdateTestAssigns(r))
+				}
+			}(int64(i))
+		}
+		r := rand.New(rand.NewSource(36))
+		for i := 0; i < 100; i++ {
+			_ = indexer.UpsertDocument(randUpdateTestDoc(r, int64(1+r.Intn(20))))
+		}
+		wg.Wait()
+	})
+}
+
+func BenchmarkIvtBEIndexer_UpsertDocument(b *testing.B) {
+	r := rand.New(rand.NewSource(35))
+	builder := NewIndexerBuilder()
+	configureUpdateTestFields(builder)
+	for id := int64(1); id <= 100000; id++ {
+		_ = builder.AddDocument(randUpdateTestDoc(r, id))
+	}
+	indexer, err := builder.BuildIndexer()
+	if err != nil {
+		b.Fatal(err)
+	}
+	docs := make([]*be_indexer.Document, 1000)
+	for i := range docs {
+		docs[i] = randUpdateTestDoc(r, int64(1+r.Intn(100000)))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = indexer.UpsertDocument(docs[i%len(docs)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package roaringidx
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/echoface/be_indexer/util"
 
@@ -11,14 +12,24 @@ import (
 
 type (
 	IvtScanner struct {
+		// busy detect misuse of scanner across goroutines
+		busy int32
+
 		debug bool
 
+		// logger injected logger for debug output, global be_indexer.Logger used when nil
+		logger be_indexer.StructuredLogger
+
 		inited bool
 
 		ended bool
 
 		indexer *IvtBEIndexer
 
+		// snapshot the index data version used by this scanner, pinned at first access
+		// so document updating in progress will not affect the result
+		snapshot *ivtIndexData
+
 		// conjIDResults this hold temp result
 		// NOTE: it's conjunction id, not document id
 		conjIDResults PostingList
@@ -32,6 +43,13 @@ func NewScanner(indexer *IvtBEIndexer) *IvtScanner {
 	}
 }
 
+func (scanner *IvtScanner) indexData() *ivtIndexData {
+	if scanner.snapshot == nil {
+		scanner.snapshot = scanner.indexer.load()
+	}
+	return scanner.snapshot
+}
+
 func FormatBitMapResult(ids []uint64) string {
 	var vs []string
 	for _, id := range ids {
@@ -44,9 +62,10 @@ func FormatBitMapResult(ids []uint64) string {
 func (scanner *IvtScanner) WithHint(hints ...int64) {
 	util.PanicIf(scanner.inited, "can't attach hint result in progress")
 
-	hintConjIDs := make([]uint64, 0, len(hints)*scanner.indexer.docMaxConjSize)
+	docMaxConjSize := scanner.indexData().docMaxConjSize
+	hintConjIDs := make([]uint64, 0, len(hints)*docMaxConjSize)
 	for _, hintID := range hints {
-		for conjIdx := 0; conjIdx < scanner.indexer.docMaxConjSize; conjIdx++ {
+		for conjIdx := 0; conjIdx < docMaxConjSize; conjIdx++ {
 			conjID, err := NewConjunctionID(conjIdx, hintID)
 			if err != nil {
 				continue
@@ -56,7 +75,7 @@ func (scanner *IvtScanner) WithHint(hints ...int64) {
 	}
 	scanner.conjIDResults.AddMany(hintConjIDs)
 	if scanner.debug {
-		be_indexer.Logger.Infof("init with hints:%s", FormatBitMapResult(scanner.conjIDResults.ToArray()))
+		scanner.logInfo("init with hints", "result", FormatBitMapResult(scanner.conjIDResults.ToArray()))
 	}
 	scanner.inited = true
 }
@@ -65,10 +84,21 @@ func (scanner *IvtScanner) SetDebug(debugOn bool) {
 	scanner.debug = debugOn
 }
 
+// SetLogger inject logger for debug output, nil means fallback to global be_indexer.Logger
+func (scanner *IvtScanner) SetLogger(logger be_indexer.StructuredLogger) {
+	scanner.logger = logger
+}
+
+func (scanner *IvtScanner) logInfo(msg string, kvs ...interface{}) {
+	be_indexer.LoggerOrDefault(scanner.logger).Info(msg, kvs...)
+}
+
 func (scanner *IvtScanner) Reset() {
 	scanner.inited = false
 	scanner.ended = false
 	scanner.debug = false
+	scanner.logger = nil
+	scanner.snapshot = nil
 
 	scanner.conjIDResults.Clear()
 }
@@ -80,11 +110,29 @@ func (scanner *IvtScanner) GetRawResult() *PostingList {
 	return &scanner.conjIDResults
 }
 
-func (scanner *IvtScanner) mergeFieldResult(field be_indexer.BEField, pl PostingList) {
+// GetDocConjunctions decoded view of raw result: matched conjunction ids of each document,
+// conjunctions of a document are sorted by index
+func (scanner *IvtScanner) GetDocConjunctions() map[int64][]ConjunctionID {
+	res := map[int64][]ConjunctionID{}
+	iter := scanner.conjIDResults.Iterator()
+	for iter.HasNext() {
+		conjID := ConjunctionID(iter.Next())
+		res[conjID.DocID()] = append(res[conjID.DocID()], conjID)
+	}
+	return res
+}
+
+// mergeFieldResult intersect field result into scanner result, the intersection is applied in place
+// on the smaller bitmap, pl may be swapped with scanner result after merged
+func (scanner *IvtScanner) mergeFieldResult(field be_indexer.BEField, pl *PostingList) {
+	var fieldResult string
+	if scanner.debug {
+		fieldResult = FormatBitMapResult(pl.ToArray())
+	}
 	defer func() {
 		if scanner.debug {
-			be_indexer.Logger.Infof("merger result from field:%s pl:%s \n after:%s",
-				field, FormatBitMapResult(pl.ToArray()), FormatBitMapResult(scanner.conjIDResults.ToArray()))
+			scanner.logInfo("merger result", "field", field, "pl", fieldResult,
+				"after", FormatBitMapResult(scanner.conjIDResults.ToArray()))
 		}
 		scanner.ended = scanner.conjIDResults.IsEmpty()
 	}()
@@ -93,30 +141,39 @@ func (scanner *IvtScanner) mergeFieldResult(field be_indexer.BEField, pl Posting
 	}
 	if !scanner.inited {
 		scanner.inited = true
-		scanner.conjIDResults.Or(pl.Bitmap)
+		*pl, scanner.conjIDResults = scanner.conjIDResults, *pl
 		return
 	}
+	if pl.GetCardinality() < scanner.conjIDResults.GetCardinality() {
+		*pl, scanner.conjIDResults = scanner.conjIDResults, *pl
+	}
 	scanner.conjIDResults.And(pl.Bitmap)
 }
 
 func (scanner *IvtScanner) retrieve(assigns be_indexer.Assignments) (err error) {
+	util.PanicIf(!atomic.CompareAndSwapInt32(&scanner.busy, 0, 1),
+		"scanner can't be used by goroutines concurrently, use IvtBEIndexer.Retrieve instead")
+	defer atomic.StoreInt32(&scanner.busy, 0)
+
 	tmpPl := NewPostingList()
+	defer func() {
+		ReleasePostingList(tmpPl) // tmpPl may be swapped with result when merging
+	}()
 
-	for field, can := range scanner.indexer.data {
+	data := scanner.indexData()
+	for _, field := range data.evaluateOrder(assigns) {
 		if scanner.ended {
 			break
 		}
 		values := assigns[field]
 
-		if err = can.Retrieve(values, &tmpPl); err != nil {
+		if err = data.data[field].Retrieve(values, &tmpPl); err != nil {
 			return err
 		}
 
-		scanner.mergeFieldResult(field, tmpPl)
+		scanner.mergeFieldResult(field, &tmpPl)
 		tmpPl.Clear()
 	}
-
-	ReleasePostingList(tmpPl)
 	return nil
 }
 
@@ -212,3 +212,81 @@ func TestIvtScanner_Retrieve4(t *testing.T) {
 		convey.So(scanner.GetRawResult().GetCardinality(), convey.ShouldEqual, 2)
 	})
 }
+
+func TestIvtScanner_SelectivityOrder(t *testing.T) {
+	convey.Convey("test fields evaluated by selectivity", t, func() {
+		builder := NewIndexerBuilder()
+		_ = builder.ConfigureField("age", FieldSetting{Container: ContainerNameDefault, Parser: parser.NewNumberParser()})
+		_ = builder.ConfigureField("city", FieldSetting{Container: ContainerNameDefault, Parser: parser.NewStrHashParser()})
+		_ = builder.ConfigureField("vip", FieldSetting{Container: ContainerNameDefault, Parser: parser.NewNumberParser()})
+
+		for id := 1; id <= 100; id++ {
+			conj := be_indexer.NewConjunction().
+				Include("age", be_indexer.NewInt64Values(int64(id%5))).
+				Include("city", be_indexer.NewStrValues("bj", "sh"))
+			if id%50 == 0 {
+				conj.Include("vip", be_indexer.NewInt64Values(1))
+			}
+			doc := be_indexer.NewDocument(be_indexer.DocID(id))
+			doc.AddConjunction(conj)
+			convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		}
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		stats := indexer.FieldStatistics()
+		convey.So(stats["age"], convey.ShouldResemble, FieldStatistics{Wildcards: 0, Values: 5, Postings: 100})
+		convey.So(stats["city"], convey.ShouldResemble, FieldStatistics{Wildcards: 0, Values: 2, Postings: 200})
+		convey.So(stats["vip"], convey.ShouldResemble, FieldStatistics{Wildcards: 98, Values: 1, Postings: 2})
+
+		data := indexer.load()
+		order := data.evaluateOrder(be_indexer.Assignments{"age": 1, "city": "bj", "vip": 1})
+		convey.So(order, convey.ShouldResemble, []be_indexer.BEField{"age", "city", "vip"})
+		// field not assigned only wildcard conjunctions can pass
+		order = data.evaluateOrder(be_indexer.Assignments{"city": "bj"})
+		convey.So(order, convey.ShouldResemble, []be_indexer.BEField{"age", "vip", "city"})
+
+		scanner := NewScanner(indexer)
+		docs, err := scanner.Retrieve(be_indexer.Assignments{"age": 0, "city": "sh", "vip": 1})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldResemble, []uint64{5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55, 60, 65, 70, 75, 80, 85, 90, 95, 100})
+
+		scanner.Reset()
+		docs, err = scanner.Retrieve(be_indexer.Assignments{"age": 0, "city": "sh"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(docs), convey.ShouldEqual, 18)
+
+		scanner.Reset()
+		docs, err = scanner.Retrieve(be_indexer.Assignments{"city": "gz", "age": 0})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldBeEmpty)
+	})
+}
+
+func TestIvtScanner_GetDocConjunctions(t *testing.T) {
+	convey.Convey("test decoded view of raw result", t, func() {
+		builder := NewIndexerBuilder()
+		_ = builder.ConfigureField("city", FieldSetting{Container: ContainerNameDefault, Parser: parser.NewStrHashParser()})
+		convey.So(builder.AddDocuments(
+			be_indexer.NewDocument(1).AddConjunction(
+				be_indexer.NewConjunction().Include("city", be_indexer.NewStrValues("bj")),
+				be_indexer.NewConjunction().Include("city", be_indexer.NewStrValues("sh")),
+				be_indexer.NewConjunction().Include("city", be_indexer.NewStrValues("bj", "gz")),
+			),
+			be_indexer.NewDocument(2).AddConjunction(be_indexer.NewConjunction().Exclude("city", be_indexer.NewStrValues("sh"))),
+		), convey.ShouldBeNil)
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		scanner := NewScanner(indexer)
+		_, err = scanner.Retrieve(be_indexer.Assignments{"city": "bj"})
+		convey.So(err, convey.ShouldBeNil)
+
+		conjs := scanner.GetDocConjunctions()
+		convey.So(len(conjs), convey.ShouldEqual, 2)
+		convey.So(len(conjs[1]), convey.ShouldEqual, 2)
+		convey.So(conjs[1][0].Idx(), convey.ShouldEqual, 0)
+		convey.So(conjs[1][1].Idx(), convey.ShouldEqual, 2)
+		convey.So(conjs[2][0].Idx(), convey.ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,92 @@
+package roaringidx
+
+import (
+	"math"
+	"sort"
+
+	"github.com/echoface/be_indexer"
+)
+
+type (
+	// FieldStatistics cardinality statistics of a field container collected at building time,
+	// used by scanner to evaluate the most selective fields first
+	FieldStatistics struct {
+		// Wildcards count of conjunctions not restricted by this field, they always pass the field
+		Wildcards uint64 `json:"wildcards"`
+
+		// Values count of distinct indexed include values(keywords/points/range layers)
+		Values int `json:"values"`
+
+		// Postings total count of conjunction ids in include posting lists
+		Postings uint64 `json:"postings"`
+	}
+
+	// BEContainerStatistics optional interface for BEContainer, container without statistics
+	// will be evaluated after all other fields
+	BEContainerStatistics interface {
+		Statistics() FieldStatistics
+	}
+)
+
+// Estimate expected count of conjunctions passing this field; when field not assigned
+// only wildcard conjunctions can pass, otherwise assume an average include posting list hit
+func (s FieldStatistics) Estimate(assigned bool) uint64 {
+	if !assigned || s.Values == 0 {
+		return s.Wildcards
+	}
+	return s.Wildcards + s.Postings/uint64(s.Values)
+}
+
+func postingListsStatistics[K comparable](pls map[K]PostingList) (values int, postings uint64) {
+	for _, pl := range pls {
+		postings += pl.GetCardinality()
+	}
+	return len(pls), postings
+}
+
+func newIvtIndexData(docMaxConjSize int, data map[be_indexer.BEField]BEContainer) *ivtIndexData {
+	stats := make(map[be_indexer.BEField]FieldStatistics, len(data))
+	for field, container := range data {
+		if provider, ok := container.(BEContainerStatistics); ok {
+			stats[field] = provider.Statistics()
+		}
+	}
+	return &ivtIndexData{
+		docMaxConjSize: docMaxConjSize,
+		data:           data,
+		stats:          stats,
+	}
+}
+
+// evaluateOrder sort fields by estimated passing conjunctions ascending,
+// so the intersection start with the smallest result and end early when nothing left
+func (d *ivtIndexData) evaluateOrder(assigns be_indexer.Assignments) []be_indexer.BEField {
+	fields := make([]be_indexer.BEField, 0, len(d.data))
+	estimates := make(map[be_indexer.BEField]uint64, len(d.data))
+	for field := range d.data {
+		fields = append(fields, field)
+
+		estimates[field] = math.MaxUint64
+		if stats, ok := d.stats[field]; ok {
+			_, assigned := assigns[field]
+			estimates[field] = stats.Estimate(assigned)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if estimates[fields[i]] != estimates[fields[j]] {
+			return estimates[fields[i]] < estimates[fields[j]]
+		}
+		return fields[i] < fields[j]
+	})
+	return fields
+}
+
+// FieldStatistics return statistics of fields whose container provide it
+func (indexer *IvtBEIndexer) FieldStatistics() map[be_indexer.BEField]FieldStatistics {
+	stats := indexer.load().stats
+	res := make(map[be_indexer.BEField]FieldStatistics, len(stats))
+	for field, s := range stats {
+		res[field] = s
+	}
+	return res
+}
@@ -0,0 +1,350 @@
+package roaringidx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/parser"
+)
+
+// serialized index layout(little endian):
+// | magic | version | docMaxConjSize | fieldCnt | field...
+// field: | name | container name | parser name | parser settings | container payload |
+// parser settings is written by parser.SettingsParser(empty if not implemented, absent in version 1),
+// container payload is written by container's io.WriterTo implementation
+const (
+	ivtIndexerMagic   uint32 = 0x42545649 // "IVTB"
+	ivtIndexerVersion uint32 = 2
+
+	// maxSerializedBlockSize protect from allocating huge memory for corrupted data
+	maxSerializedBlockSize = 1 << 30
+)
+
+type (
+	// LoadOption option for loading a serialized IvtBEIndexer
+	LoadOption func(opt *loadOption)
+
+	loadOption struct {
+		parsers map[string]parser.ValueIDGenerator
+	}
+
+	// binWriter sticky error writer, the first error will be kept and stop all subsequent writes
+	binWriter struct {
+		w   io.Writer
+		n   int64
+		err error
+		buf [8]byte
+	}
+
+	// binReader sticky error reader
+	binReader struct {
+		r   io.Reader
+		n   int64
+		err error
+		buf [8]byte
+
+		// frozen not nil when reading from a frozen buffer, bytes and bitmaps are views of the buffer
+		frozen *frozenReader
+	}
+
+	// frozenReader reader over a serialized buffer, see LoadFrozenIvtBEIndexer
+	frozenReader struct {
+		data []byte
+		off  int
+	}
+)
+
+func newBinReader(r io.Reader) *binReader {
+	frozen, _ := r.(*frozenReader)
+	return &binReader{r: r, frozen: frozen}
+}
+
+func (r *frozenReader) Read(p []byte) (int, error) {
+	if r.off >= len(r.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[r.off:])
+	r.off += n
+	return n, nil
+}
+
+// next return the next n bytes without copying
+func (r *frozenReader) next(n int) ([]byte, error) {
+	if n > len(r.data)-r.off {
+		r.off = len(r.data)
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := r.data[r.off : r.off+n : r.off+n]
+	r.off += n
+	return data, nil
+}
+
+// WithFieldParser use the parser for the field instead of creating from parser name,
+// it's required when the parser holds state(eg: CommonStrParser with IDAllocatorImpl)
+// or has non-default settings
+func WithFieldParser(field string, p parser.ValueIDGenerator) LoadOption {
+	return func(opt *loadOption) {
+		opt.parsers[field] = p
+	}
+}
+
+func (w *binWriter) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	var n int
+	n, w.err = w.w.Write(data)
+	w.n += int64(n)
+}
+
+func (w *binWriter) writeUint32(v uint32) {
+	binary.LittleEndian.PutUint32(w.buf[:4], v)
+	w.write(w.buf[:4])
+}
+
+func (w *binWriter) writeUint64(v uint64) {
+	binary.LittleEndian.PutUint64(w.buf[:8], v)
+	w.write(w.buf[:8])
+}
+
+func (w *binWriter) writeBytes(data []byte) {
+	w.writeUint32(uint32(len(data)))
+	w.write(data)
+}
+
+func (w *binWriter) writeString(s string) {
+	w.writeBytes([]byte(s))
+}
+
+func (w *binWriter) writeBitmap(pl PostingList) {
+	if w.err != nil {
+		return
+	}
+	var data []byte
+	if data, w.err = pl.ToBytes(); w.err != nil {
+		return
+	}
+	w.writeBytes(data)
+}
+
+func (r *binReader) read(data []byte) {
+	if r.err != nil {
+		return
+	}
+	var n int
+	n, r.err = io.ReadFull(r.r, data)
+	r.n += int64(n)
+}
+
+func (r *binReader) readUint32() uint32 {
+	r.read(r.buf[:4])
+	if r.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(r.buf[:4])
+}
+
+func (r *binReader) readUint64() uint64 {
+	r.read(r.buf[:8])
+	if r.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(r.buf[:8])
+}
+
+// readSize read a uint32 size and check it not exceed limit
+func (r *binReader) readSize() int {
+	size := r.readUint32()
+	if r.err == nil && size > maxSerializedBlockSize {
+		r.err = fmt.Errorf("bad serialized data, size:%d too large", size)
+	}
+	return int(size)
+}
+
+// readBytes read a size prefixed block, the block is a view of buffer when reading frozen buffer
+func (r *binReader) readBytes() []byte {
+	size := r.readSize()
+	if r.err != nil {
+		return nil
+	}
+	if r.frozen != nil {
+		var data []byte
+		data, r.err = r.frozen.next(size)
+		r.n += int64(len(data))
+		return data
+	}
+	data := make([]byte, size)
+	r.read(data)
+	return data
+}
+
+func (r *binReader) readString() string {
+	return string(r.readBytes())
+}
+
+func (r *binReader) readBitmap() PostingList {
+	pl := NewPostingList()
+	data := r.readBytes()
+	if r.err != nil {
+		return pl
+	}
+	if r.frozen != nil {
+		_, r.err = pl.FromUnsafeBytes(data)
+		return pl
+	}
+	r.err = pl.UnmarshalBinary(data)
+	return pl
+}
+
+// WriteTo serialize the indexer, all containers must implement io.WriterTo
+func (indexer *IvtBEIndexer) WriteTo(w io.Writer) (int64, error) {
+	snapshot := indexer.load()
+	fields := make([]string, 0, len(snapshot.data))
+	for field := range snapshot.data {
+		fields = append(fields, string(field))
+	}
+	sort.Strings(fields)
+
+	bw := &binWriter{w: w}
+	bw.writeUint32(ivtIndexerMagic)
+	bw.writeUint32(ivtIndexerVersion)
+	bw.writeUint32(uint32(snapshot.docMaxConjSize))
+	bw.writeUint32(uint32(len(fields)))
+	for _, field := range fields {
+		container := snapshot.data[be_indexer.BEField(field)]
+		meta := container.Meta()
+		writer, ok := container.(io.WriterTo)
+		if !ok {
+			return bw.n, fmt.Errorf("field:%s container:%s not support serialization", field, meta.Container)
+		}
+		parserName, settings := "", []byte(nil)
+		if meta.Parser != nil {
+			parserName = meta.Parser.Name()
+		}
+		if settingsParser, ok := meta.Parser.(parser.SettingsParser); ok {
+			var err error
+			if settings, err = settingsParser.Settings(); err != nil {
+				return bw.n, fmt.Errorf("field:%s parser:%s settings fail:%v", field, parserName, err)
+			}
+		}
+		bw.writeString(field)
+		bw.writeString(meta.Container)
+		bw.writeString(parserName)
+		bw.writeBytes(settings)
+		if bw.err != nil {
+			return bw.n, bw.err
+		}
+		n, err := writer.WriteTo(w)
+		bw.n += n
+		if err != nil {
+			return bw.n, fmt.Errorf("field:%s container serialize fail:%v", field, err)
+		}
+	}
+	return bw.n, bw.err
+}
+
+// ReadFrom load the indexer serialized by WriteTo, parsers are created by their names and
+// settings(see parser.SettingsParser), use LoadIvtBEIndexer with WithFieldParser for parsers holding state
+func (indexer *IvtBEIndexer) ReadFrom(r io.Reader) (int64, error) {
+	return indexer.readFrom(r, newLoadOption(nil))
+}
+
+// LoadIvtBEIndexer load a indexer serialized by IvtBEIndexer.WriteTo, bitmaps are copied into heap
+func LoadIvtBEIndexer(r io.Reader, opts ...LoadOption) (*IvtBEIndexer, error) {
+	indexer := NewIvtBEIndexer()
+	if _, err := indexer.readFrom(r, newLoadOption(opts)); err != nil {
+		return nil, err
+	}
+	return indexer, nil
+}
+
+// LoadFrozenIvtBEIndexer load a indexer serialized by IvtBEIndexer.WriteTo from data(eg: a mmap-ed file)
+// without copying bitmaps, posting lists are views of data: data must not be modified or released while
+// the indexer(and indexers updated from it) in use; updating documents is supported, modified bitmaps
+// are copied on write. return the count of bytes consumed
+func LoadFrozenIvtBEIndexer(data []byte, opts ...LoadOption) (*IvtBEIndexer, int64, error) {
+	indexer := NewIvtBEIndexer()
+	n, err := indexer.readFrom(&frozenReader{data: data}, newLoadOption(opts))
+	if err != nil {
+		return nil, n, err
+	}
+	return indexer, n, nil
+}
+
+func newLoadOption(opts []LoadOption) *loadOption {
+	option := &loadOption{parsers: map[string]parser.ValueIDGenerator{}}
+	for _, fn := range opts {
+		fn(option)
+	}
+	return option
+}
+
+func (indexer *IvtBEIndexer) readFrom(r io.Reader, option *loadOption) (int64, error) {
+	br := newBinReader(r)
+	if magic := br.readUint32(); br.err == nil && magic != ivtIndexerMagic {
+		return br.n, fmt.Errorf("bad magic number:%x, not a serialized ivt indexer", magic)
+	}
+	version := br.readUint32()
+	if br.err == nil && (version == 0 || version > ivtIndexerVersion) {
+		return br.n, fmt.Errorf("serialized version:%d not supported", version)
+	}
+	docMaxConjSize := br.readUint32()
+	fieldCnt := br.readSize()
+	if br.err != nil {
+		return br.n, br.err
+	}
+	if docMaxConjSize > math.MaxUint8+1 {
+		return br.n, fmt.Errorf("bad docMaxConjSize:%d", docMaxConjSize)
+	}
+
+	data := map[be_indexer.BEField]BEContainer{}
+	for i := 0; i < fieldCnt; i++ {
+		field, containerName, parserName := br.readString(), br.readString(), br.readString()
+		var settings []byte
+		if version > 1 {
+			settings = br.readBytes()
+		}
+		if br.err != nil {
+			return br.n, br.err
+		}
+		meta := &FieldMeta{
+			FieldSetting: FieldSetting{Container: containerName},
+			field:        be_indexer.BEField(field),
+		}
+		if p, ok := option.parsers[field]; ok {
+			if p.Name() != parserName {
+				return br.n, fmt.Errorf("field:%s parser:%s not match serialized:%s", field, p.Name(), parserName)
+			}
+			meta.Parser = p
+		} else if parserName != "" {
+			var err error
+			if meta.Parser, err = parser.NewParserWithSettings(parserName, settings); err != nil {
+				return br.n, fmt.Errorf("field:%s restore parser fail:%v, specify it by WithFieldParser", field, err)
+			}
+		}
+
+		builder := NewContainerBuilder(meta)
+		if builder == nil {
+			return br.n, fmt.Errorf("field:%s container:%s not registered", field, containerName)
+		}
+		container, ok := builder.(BEContainer)
+		reader, readable := builder.(io.ReaderFrom)
+		if !ok || !readable {
+			return br.n, fmt.Errorf("field:%s container:%s not support serialization", field, containerName)
+		}
+		n, err := reader.ReadFrom(r)
+		br.n += n
+		if err != nil {
+			return br.n, fmt.Errorf("field:%s container load fail:%v", field, err)
+		}
+		data[meta.field] = container
+	}
+	indexer.mu.Lock()
+	indexer.store(newIvtIndexData(int(docMaxConjSize), data))
+	indexer.mu.Unlock()
+	return br.n, nil
+}
@@ -0,0 +1,201 @@
+package roaringidx
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/echoface/be_indexer"
+	"github.com/echoface/be_indexer/holder/ahoholder"
+	"github.com/echoface/be_indexer/parser"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func buildSerializationTestIndexer(r *rand.Rand) (*IvtBEIndexer, error) {
+	builder := NewIndexerBuilder()
+	_ = builder.ConfigureField("ad_id", FieldSetting{Container: ContainerNameDefault, Parser: parser.NewNumberParser()})
+	_ = builder.ConfigureField("pkg", FieldSetting{Container: ContainerNameDefault, Parser: parser.NewStrHashParser()})
+	_ = builder.ConfigureField("tag", FieldSetting{Container: ContainerNameDefault})
+	_ = builder.ConfigureField("title", FieldSetting{
+		Container: ContainerNameAcMatch,
+		ACOption:  &ahoholder.ACHolderOption{QuerySep: " ", CaseFolding: true},
+	})
+	_ = builder.ConfigureField("age", FieldSetting{Container: ContainerNameBSI})
+
+	for id := 1; id <= 200; id++ {
+		doc := be_indexer.NewDocument(be_indexer.DocID(id))
+		for i := 0; i < 1+r.Intn(3); i++ {
+			conj := be_indexer.NewConjunction()
+			if r.Intn(2) == 0 {
+				conj.AddExpression3("ad_id", r.Intn(3) > 0, []int{r.Intn(20), r.Intn(20)})
+			}
+			if r.Intn(2) == 0 {
+				conj.AddExpression3("pkg", r.Intn(3) > 0, fmt.Sprintf("pkg.%d", r.Intn(10)))
+			}
+			if r.Intn(2) == 0 {
+				conj.AddExpression3("tag", r.Intn(3) > 0, fmt.Sprintf("tag%d", r.Intn(10)))
+			}
+			if r.Intn(2) == 0 {
+				conj.AddExpression3("title", r.Intn(3) > 0, fmt.Sprintf("Word%d", r.Intn(10)))
+			}
+			if r.Intn(2) == 0 {
+				l := int64(r.Intn(100))
+				conj.Between("age", l, l+int64(r.Intn(30)))
+			}
+			doc.AddConjunction(conj)
+		}
+		if err := builder.AddDocument(doc); err != nil {
+			return nil, err
+		}
+	}
+	return builder.BuildIndexer()
+}
+
+func TestIvtBEIndexer_WriteTo(t *testing.T) {
+	convey.Convey("test indexer serialization", t, func() {
+		r := rand.New(rand.NewSource(34))
+		indexer, err := buildSerializationTestIndexer(r)
+		convey.So(err, convey.ShouldBeNil)
+
+		buf := &bytes.Buffer{}
+		n, err := indexer.WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, buf.Len())
+		data := buf.Bytes()
+
+		loaded := NewIvtBEIndexer()
+		n, err = loaded.ReadFrom(bytes.NewReader(data))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, len(data))
+		convey.So(loaded.load().docMaxConjSize, convey.ShouldEqual, indexer.load().docMaxConjSize)
+		convey.So(loaded.load().data["pkg"].Meta().Parser.Name(), convey.ShouldEqual, parser.ParserNameStrHash)
+
+		again := &bytes.Buffer{}
+		_, err = loaded.WriteTo(again)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(again.Bytes(), convey.ShouldResemble, data)
+
+		for i := 0; i < 300; i++ {
+			assigns := be_indexer.Assignments{
+				"ad_id": []int{r.Intn(20), r.Intn(20)},
+				"pkg":   fmt.Sprintf("pkg.%d", r.Intn(10)),
+				"tag":   fmt.Sprintf("tag%d", r.Intn(10)),
+				"title": fmt.Sprintf("WORD%d word%d", r.Intn(10), r.Intn(10)),
+				"age":   r.Intn(120),
+			}
+			expect, err := NewScanner(indexer).Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			docs, err := NewScanner(loaded).Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(docs, convey.ShouldResemble, expect)
+		}
+	})
+
+	convey.Convey("test load with field parser and bad data", t, func() {
+		indexer, err := buildSerializationTestIndexer(rand.New(rand.NewSource(34)))
+		convey.So(err, convey.ShouldBeNil)
+		buf := &bytes.Buffer{}
+		_, err = indexer.WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		data := buf.Bytes()
+
+		numParser := parser.NewNumberParser()
+		loaded, err := LoadIvtBEIndexer(bytes.NewReader(data), WithFieldParser("ad_id", numParser))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(loaded.load().data["ad_id"].Meta().Parser, convey.ShouldEqual, numParser)
+
+		_, err = LoadIvtBEIndexer(bytes.NewReader(data), WithFieldParser("ad_id", parser.NewStrHashParser()))
+		convey.So(err, convey.ShouldNotBeNil)
+
+		_, err = LoadIvtBEIndexer(bytes.NewReader(data[:len(data)/2]))
+		convey.So(err, convey.ShouldNotBeNil)
+
+		_, err = LoadIvtBEIndexer(bytes.NewReader([]byte("not a indexer data")))
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+func TestLoadFrozenIvtBEIndexer(t *testing.T) {
+	convey.Convey("test frozen load share bitmaps with buffer", t, func() {
+		r := rand.New(rand.NewSource(34))
+		indexer, err := buildSerializationTestIndexer(r)
+		convey.So(err, convey.ShouldBeNil)
+		buf := &bytes.Buffer{}
+		_, err = indexer.WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		data := buf.Bytes()
+		origin := append([]byte(nil), data...)
+
+		frozen, n, err := LoadFrozenIvtBEIndexer(data)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, len(data))
+
+		again := &bytes.Buffer{}
+		_, err = frozen.WriteTo(again)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(again.Bytes(), convey.ShouldResemble, data)
+
+		// updating copy modified bitmaps instead of writing into buffer
+		for id := int64(1); id <= 50; id++ {
+			convey.So(frozen.RemoveDocument(id), convey.ShouldBeNil)
+			convey.So(indexer.RemoveDocument(id), convey.ShouldBeNil)
+		}
+		doc := be_indexer.NewDocument(7).AddConjunction(be_indexer.NewConjunction().
+			In("tag", []string{"tag1"}).In("title", []string{"word1"}).Between("age", 1, 200))
+		convey.So(frozen.UpsertDocument(doc), convey.ShouldBeNil)
+		convey.So(indexer.UpsertDocument(doc), convey.ShouldBeNil)
+		convey.So(data, convey.ShouldResemble, origin)
+
+		for i := 0; i < 300; i++ {
+			assigns := be_indexer.Assignments{
+				"ad_id": []int{r.Intn(20), r.Intn(20)},
+				"pkg":   fmt.Sprintf("pkg.%d", r.Intn(10)),
+				"tag":   fmt.Sprintf("tag%d", r.Intn(10)),
+				"title": fmt.Sprintf("WORD%d word%d", r.Intn(10), r.Intn(10)),
+				"age":   r.Intn(120),
+			}
+			expect, err := NewScanner(indexer).Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			docs, err := NewScanner(frozen).Retrieve(assigns)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(docs, convey.ShouldResemble, expect)
+		}
+
+		_, _, err = LoadFrozenIvtBEIndexer(data[:len(data)/2])
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+func TestIvtBEIndexer_ParserSettings(t *testing.T) {
+	convey.Convey("test parser settings persisted with indexer", t, func() {
+		option := &parser.GeoOption{Precision: 5, CompressPrecisionMin: 3, CompressPrecisionCutoff: 5}
+		builder := NewIndexerBuilder()
+		convey.So(builder.ConfigureField("geo", FieldSetting{
+			Container: ContainerNameDefault,
+			Parser:    parser.NewGeoHashParser(option),
+		}), convey.ShouldBeNil)
+		doc := be_indexer.NewDocument(1).AddConjunction(be_indexer.NewConjunction().In("geo", "31.21275902:121.53779984:1000"))
+		convey.So(builder.AddDocument(doc), convey.ShouldBeNil)
+		indexer, err := builder.BuildIndexer()
+		convey.So(err, convey.ShouldBeNil)
+
+		buf := &bytes.Buffer{}
+		_, err = indexer.WriteTo(buf)
+		convey.So(err, convey.ShouldBeNil)
+		loaded, err := LoadIvtBEIndexer(bytes.NewReader(buf.Bytes()))
+		convey.So(err, convey.ShouldBeNil)
+
+		restored, ok := loaded.load().data["geo"].Meta().Parser.(*parser.GeoHashParser)
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(restored.GeoOption, convey.ShouldResemble, *option)
+
+		assigns := be_indexer.Assignments{"geo": [2]float64{31.21275902, 121.53779984}}
+		expect, err := NewScanner(indexer).Retrieve(assigns)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(expect), convey.ShouldEqual, 1)
+		docs, err := NewScanner(loaded).Retrieve(assigns)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(docs, convey.ShouldResemble, expect)
+	})
+}
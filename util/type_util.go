@@ -9,7 +9,7 @@ func NilInterface(v interface{}) bool {
 		return true
 	}
 	switch reflect.TypeOf(v).Kind() {
-	case reflect.Ptr, reflect.Map, reflect.Array, reflect.Chan, reflect.Slice:
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Slice:
 		return reflect.ValueOf(v).IsNil()
 	}
 	return false